@@ -0,0 +1,30 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package ipc
+
+import (
+	"io"
+
+	"github.com/nxgtw/go-ipc/internal/common"
+)
+
+// SetAutoCleanup enables or disables automatic registration of new objects for CloseAll.
+// When it is enabled, memory regions, and the queues and mutexes created by mq.New, mq.Open,
+// and sync.NewMutex, are registered, and they are unregistered, when they are closed.
+// Registered objects are not garbage collected until they are closed.
+// It is disabled by default.
+func SetAutoCleanup(enabled bool) {
+	common.SetAutoCleanup(enabled)
+}
+
+// RegisterForCleanup registers a custom object, so that it is closed by CloseAll.
+func RegisterForCleanup(c io.Closer) {
+	common.RegisterForCleanup(c)
+}
+
+// CloseAll closes, but does not destroy, all the registered objects of the current process
+// in the reverse order of their registration. The registry is empty after the call.
+// It tries to close all the objects, even if some of them fail to close, and the returned error lists all the failures.
+func CloseAll() error {
+	return common.CloseAll()
+}
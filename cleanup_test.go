@@ -0,0 +1,94 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package ipc
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"bitbucket.org/avd/go-ipc/mmf"
+	"bitbucket.org/avd/go-ipc/mq"
+	ipc_sync "bitbucket.org/avd/go-ipc/sync"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testCleanupName = "go-ipc-cleanup"
+)
+
+type testCloser struct {
+	closed bool
+	err    error
+}
+
+func (c *testCloser) Close() error {
+	c.closed = true
+	return c.err
+}
+
+func TestCloseAll(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(mq.Destroy(testCleanupName)) || !a.NoError(ipc_sync.DestroyMutex(testCleanupName)) {
+		return
+	}
+	defer mq.Destroy(testCleanupName)
+	defer ipc_sync.DestroyMutex(testCleanupName)
+	file, err := ioutil.TempFile("", "go-ipc-cleanup")
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+	if !a.NoError(file.Truncate(4096)) {
+		return
+	}
+	SetAutoCleanup(true)
+	defer SetAutoCleanup(false)
+	queue, err := mq.New(testCleanupName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	region, err := mmf.NewMemoryRegion(file, mmf.MEM_READWRITE, 0, 4096)
+	if !a.NoError(err) {
+		return
+	}
+	m, err := ipc_sync.NewMutex(testCleanupName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	m.Lock()
+	m.Unlock()
+	custom := &testCloser{}
+	RegisterForCleanup(custom)
+	if !a.NoError(CloseAll()) {
+		return
+	}
+	a.True(custom.closed)
+	a.Error(queue.Send([]byte{1}))
+	a.Equal(0, region.Size())
+	a.Error(region.Remap(8192))
+	// the registry is empty now.
+	custom.closed = false
+	a.NoError(CloseAll())
+	a.False(custom.closed)
+}
+
+func TestCloseAllErrors(t *testing.T) {
+	a := assert.New(t)
+	c1, c2, c3 := &testCloser{err: errors.New("first")}, &testCloser{}, &testCloser{err: errors.New("third")}
+	RegisterForCleanup(c1)
+	RegisterForCleanup(c2)
+	RegisterForCleanup(c3)
+	err := CloseAll()
+	if a.Error(err) {
+		a.Contains(err.Error(), "first")
+		a.Contains(err.Error(), "third")
+	}
+	a.True(c1.closed && c2.closed && c3.closed)
+	a.NoError(CloseAll())
+}
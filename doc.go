@@ -12,4 +12,5 @@
 //	semaphores
 //	events
 //	conditional variables
+//	peer credentials of unix-domain sockets (Linux)
 package ipc
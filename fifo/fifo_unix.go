@@ -86,5 +86,5 @@ func DestroyUnixFIFO(name string) error {
 }
 
 func fifoPath(name string) string {
-	return "/tmp/" + name
+	return "/tmp/" + common.PrefixedName(name)
 }
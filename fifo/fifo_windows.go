@@ -79,7 +79,7 @@ func DestroyNamedPipe(name string) error {
 
 func namedPipePath(name string) string {
 	const prefix = `\\.\pipe\`
-	return prefix + name
+	return prefix + common.PrefixedName(name)
 }
 
 func createFifoClient(path string, flag int) (windows.Handle, error) {
@@ -97,6 +97,36 @@ func Alloc(memory []byte, object interface{}) error {
 	return nil
 }
 
+// SliceFromBytes makes a slice, pointed by slicePtr, use memory as its backing array.
+// It is the reverse of Alloc for slices: slicePtr must be a pointer to a slice of plain objects,
+// and length is the number of elements, which was saved by the calling site.
+// The data is not copied, so the slice is valid only while the memory is valid, for example,
+// until the memory region it belongs to is closed. The capacity of the slice is set to its length,
+// so that append allocates a new backing array instead of writing past the end of the memory.
+func SliceFromBytes(memory []byte, slicePtr interface{}, length int) error {
+	value := reflect.ValueOf(slicePtr)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("a pointer to a slice expected, got %v", value.Kind())
+	}
+	if value.IsNil() {
+		return fmt.Errorf("nil slice pointer")
+	}
+	if err := checkType(value.Elem().Type(), 0); err != nil {
+		return err
+	}
+	if length < 0 {
+		return fmt.Errorf("invalid slice length %d", length)
+	}
+	if length*int(value.Elem().Type().Elem().Size()) > len(memory) {
+		return fmt.Errorf("the slice is too large for the buffer")
+	}
+	header := (*reflect.SliceHeader)(unsafe.Pointer(value.Pointer()))
+	header.Data = uintptr(ByteSliceData(memory))
+	header.Len = length
+	header.Cap = length
+	return nil
+}
+
 // ByteSliceTointSlice returns an int slice, which uses the same memory, that the byte slice uses.
 func ByteSliceTointSlice(memory []byte, length, capacity int) []int {
 	return IntSliceFromUnsafePointer(unsafe.Pointer((*reflect.SliceHeader)((unsafe.Pointer)(&memory)).Data), length, capacity)
@@ -138,14 +168,18 @@ func ObjectData(object interface{}) ([]byte, error) {
 	if err := checkType(value.Type(), 0); err != nil {
 		return nil, err
 	}
-	var data []byte
+	return ValueData(value)
+}
+
+// ValueData returns objects data as ObjectData does, but it doesn't check the type of the value.
+// It allows to check the type once with CheckObjectReferences and then to skip the checks.
+func ValueData(value reflect.Value) ([]byte, error) {
 	objSize := ObjectSize(value)
 	addr := ObjectAddress(value)
 	if uintptr(addr) == 0 {
 		return nil, fmt.Errorf("nil object")
 	}
-	data = ByteSliceFromUnsafePointer(addr, objSize, objSize)
-	return data, nil
+	return ByteSliceFromUnsafePointer(addr, objSize, objSize), nil
 }
 
 // UseValue is an ugly hack used to ensure, that the value is alive at some point.
@@ -173,6 +207,59 @@ func CheckObjectReferences(object interface{}) error {
 	return checkType(reflect.ValueOf(object).Type(), 0)
 }
 
+// CheckObjectStrict performs the same checks, as CheckObjectReferences does,
+// and also ensures, that the object has the same memory layout on 32 and 64-bit platforms, so
+// it can be shared between processes built for different architectures. The object must not contain:
+//	- int, uint, uintptr and unsafe.Pointer values, as their size depends on the architecture.
+//	- padding and fields, which are not aligned to their natural alignment,
+//	  as 64-bit values are aligned differently on different platforms.
+func CheckObjectStrict(object interface{}) error {
+	t := reflect.ValueOf(object).Type()
+	if err := checkType(t, 0); err != nil {
+		return err
+	}
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	_, err := checkTypeStrict(t)
+	return err
+}
+
+// checkTypeStrict checks the type for portability and returns its natural alignment,
+// which is the alignment it would have, if all values were aligned to their size.
+func checkTypeStrict(t reflect.Type) (int, error) {
+	switch t.Kind() {
+	case reflect.Int, reflect.Uint, reflect.Uintptr, reflect.UnsafePointer:
+		return 0, fmt.Errorf("type %q has architecture-dependent size", t.Kind().String())
+	case reflect.Complex64, reflect.Complex128:
+		return int(t.Size() / 2), nil
+	case reflect.Array:
+		return checkTypeStrict(t.Elem())
+	case reflect.Struct:
+		align, offset := 1, uintptr(0)
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			fieldAlign, err := checkTypeStrict(field.Type)
+			if err != nil {
+				return 0, fmt.Errorf("field %s: %v", field.Name, err)
+			}
+			if field.Offset != offset || int(offset)%fieldAlign != 0 {
+				return 0, fmt.Errorf("field %s is not aligned to %d bytes without padding", field.Name, fieldAlign)
+			}
+			offset += field.Type.Size()
+			if fieldAlign > align {
+				align = fieldAlign
+			}
+		}
+		if t.Size() != offset || int(offset)%align != 0 {
+			return 0, fmt.Errorf("%s has trailing padding", t.String())
+		}
+		return align, nil
+	default:
+		return int(t.Size()), nil
+	}
+}
+
 func checkType(t reflect.Type, depth int) error {
 	kind := t.Kind()
 	if kind == reflect.Array {
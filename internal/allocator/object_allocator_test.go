@@ -50,6 +50,42 @@ func TestCheckObjectType(t *testing.T) {
 	assert.Error(t, CheckObjectReferences(slsl))
 }
 
+func TestCheckObjectStrict(t *testing.T) {
+	type intStruct struct {
+		a int64
+		b int
+	}
+	type int64Struct struct {
+		a int64
+		b [2]int32
+		c struct {
+			f float64
+		}
+	}
+	type unalignedStruct struct {
+		a int32
+		b int64
+	}
+	type paddedStruct struct {
+		a int64
+		b int32
+	}
+	a := assert.New(t)
+	a.NoError(CheckObjectStrict(int64Struct{}))
+	a.NoError(CheckObjectStrict(&int64Struct{}))
+	a.NoError(CheckObjectStrict([]int64Struct{}))
+	a.NoError(CheckObjectStrict([4]uint16{}))
+	a.NoError(CheckObjectStrict(complex128(0)))
+
+	a.NoError(CheckObjectReferences(intStruct{}))
+	a.Error(CheckObjectStrict(intStruct{}))
+	a.Error(CheckObjectStrict(uintptr(0)))
+	a.Error(CheckObjectStrict([]uint{}))
+	a.Error(CheckObjectStrict(unalignedStruct{}))
+	a.Error(CheckObjectStrict(paddedStruct{}))
+	a.Error(CheckObjectStrict(""))
+}
+
 func TestAllocInt(t *testing.T) {
 	var i = 0x01027FFF
 	data := make([]byte, unsafe.Sizeof(i))
@@ -119,6 +155,34 @@ func TestAllocSlice(t *testing.T) {
 	assert.Equal(t, obj, sl)
 }
 
+func TestAllocSliceRoundTrip(t *testing.T) {
+	a := assert.New(t)
+	obj := make([]uint64, 10)
+	for i := range obj {
+		obj[i] = uint64(i) << 40
+	}
+	data := make([]byte, unsafe.Sizeof(uint64(0))*10)
+	if !a.NoError(Alloc(data, obj)) {
+		return
+	}
+	var sl []uint64
+	if !a.NoError(SliceFromBytes(data, &sl, len(obj))) {
+		return
+	}
+	a.Equal(obj, sl)
+	a.Equal(len(obj), cap(sl))
+	// the slice must use the memory, not a copy of it.
+	sl[0] = 0xFF
+	a.Equal(byte(0xFF), data[0])
+	var empty []uint64
+	a.NoError(SliceFromBytes(data, &empty, 0))
+	a.Len(empty, 0)
+	a.Error(SliceFromBytes(data, &sl, len(obj)+1))
+	a.Error(SliceFromBytes(data, sl, len(obj)))
+	var strs []string
+	a.Error(SliceFromBytes(data, &strs, 1))
+}
+
 func TestAllocSliceReadAsArray(t *testing.T) {
 	obj := make([]int, 10)
 	for i := range obj {
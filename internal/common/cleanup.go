@@ -0,0 +1,98 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package common
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	autoCleanup int32
+	cleanup     = struct {
+		sync.Mutex
+		seq     uint64
+		closers map[io.Closer]uint64
+	}{closers: make(map[io.Closer]uint64)}
+)
+
+// CloseErrors is returned by CloseAll, if some of the objects failed to close.
+type CloseErrors []error
+
+// Error is to implement error interface.
+func (errs CloseErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return "failed to close objects: " + strings.Join(msgs, "; ")
+}
+
+// SetAutoCleanup enables or disables automatic registration of new objects for CloseAll.
+func SetAutoCleanup(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+	atomic.StoreInt32(&autoCleanup, value)
+}
+
+// AutoRegisterForCleanup registers an object for CloseAll, if the automatic registration is enabled.
+func AutoRegisterForCleanup(c io.Closer) {
+	if atomic.LoadInt32(&autoCleanup) != 0 {
+		RegisterForCleanup(c)
+	}
+}
+
+// RegisterForCleanup registers an object for CloseAll.
+func RegisterForCleanup(c io.Closer) {
+	cleanup.Lock()
+	cleanup.seq++
+	cleanup.closers[c] = cleanup.seq
+	cleanup.Unlock()
+}
+
+// UnregisterFromCleanup removes an object from the registry. It is called, when an object is closed.
+func UnregisterFromCleanup(c io.Closer) {
+	cleanup.Lock()
+	delete(cleanup.closers, c)
+	cleanup.Unlock()
+}
+
+// CloseAll closes all the registered objects in the reverse order of their registration.
+// The objects are closed one by one, so that an object, which was closed by another one, is not closed twice.
+func CloseAll() error {
+	var errs CloseErrors
+	for {
+		c := popLatestCloser()
+		if c == nil {
+			break
+		}
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// popLatestCloser removes the most recently registered object from the registry and returns it.
+func popLatestCloser() io.Closer {
+	cleanup.Lock()
+	defer cleanup.Unlock()
+	var result io.Closer
+	var latest uint64
+	for c, seq := range cleanup.closers {
+		if seq > latest {
+			result, latest = c, seq
+		}
+	}
+	if result != nil {
+		delete(cleanup.closers, result)
+	}
+	return result
+}
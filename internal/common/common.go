@@ -97,3 +97,48 @@ func CallTimeout(f func(time.Duration) bool, timeout time.Duration) {
 		}
 	}
 }
+
+// PollTimeout calls f in a loop until it returns true or an error, the timeout expires, or cancel is closed.
+// Between the calls it sleeps, starting with a millisecond and doubling the interval up to maxInterval.
+// Passing negative value as a timeout makes the timeout infinite. cancel may be nil.
+// It returns true, if f succeeded.
+func PollTimeout(f func() (bool, error), timeout, maxInterval time.Duration, cancel <-chan struct{}) (bool, error) {
+	return PollTimeoutWait(f, timeout, maxInterval, func(interval time.Duration) bool {
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+			return true
+		case <-cancel:
+			timer.Stop()
+			return false
+		}
+	})
+}
+
+// PollTimeoutWait is the same as PollTimeout, but it calls wait to pause between the calls of f.
+// wait must return not later, than the given interval expires, and it may return earlier.
+// If wait returns false, polling is stopped.
+func PollTimeoutWait(f func() (bool, error), timeout, maxInterval time.Duration, wait func(interval time.Duration) bool) (bool, error) {
+	start := time.Now()
+	interval := time.Millisecond
+	for {
+		if done, err := f(); done || err != nil {
+			return done, err
+		}
+		if timeout >= 0 {
+			left := timeout - time.Since(start)
+			if left <= 0 {
+				return false, nil
+			}
+			if interval > left {
+				interval = left
+			}
+		}
+		if !wait(interval) {
+			return false, nil
+		}
+		if interval *= 2; interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+}
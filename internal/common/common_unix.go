@@ -52,7 +52,7 @@ func KeyForName(name string) (Key, error) {
 
 // TmpFilename returns a full path for a temporary file with the given name.
 func TmpFilename(name string) string {
-	return os.TempDir() + "/" + name
+	return os.TempDir() + "/" + PrefixedName(name)
 }
 
 // AbsTimeoutToTimeSpec converts given timeout value to absulute value of unix.Timespec.
@@ -0,0 +1,65 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package common
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Epoll waits for a set of file descriptors to become readable.
+type Epoll struct {
+	fd int
+}
+
+// NewEpoll creates an epoll instance, which watches given file descriptors for readability.
+func NewEpoll(fds []int) (*Epoll, error) {
+	epfd, err := unix.EpollCreate1(unix.EPOLL_CLOEXEC)
+	if err != nil {
+		return nil, os.NewSyscallError("EPOLL_CREATE1", err)
+	}
+	for _, fd := range fds {
+		ev := unix.EpollEvent{Events: unix.EPOLLIN, Fd: int32(fd)}
+		if err = unix.EpollCtl(epfd, unix.EPOLL_CTL_ADD, fd, &ev); err != nil {
+			unix.Close(epfd)
+			return nil, os.NewSyscallError("EPOLL_CTL", err)
+		}
+	}
+	return &Epoll{fd: epfd}, nil
+}
+
+// WaitReadable waits until any of the file descriptors becomes readable, or the timeout expires.
+// Passing negative value as a timeout makes the timeout infinite.
+// Returns true, if there is a readable file descriptor.
+func (e *Epoll) WaitReadable(timeout time.Duration) (bool, error) {
+	var events [1]unix.EpollEvent
+	var deadline time.Time
+	if timeout >= 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	for {
+		msec := -1
+		if timeout >= 0 {
+			left := deadline.Sub(time.Now())
+			if left < 0 {
+				left = 0
+			}
+			// round up, so that we do not spin with a zero timeout.
+			msec = int((left + time.Millisecond - 1) / time.Millisecond)
+		}
+		n, err := unix.EpollWait(e.fd, events[:], msec)
+		if err == nil {
+			return n > 0, nil
+		}
+		if err != unix.EINTR {
+			return false, os.NewSyscallError("EPOLL_WAIT", err)
+		}
+	}
+}
+
+// Close closes the epoll instance.
+func (e *Epoll) Close() error {
+	return unix.Close(e.fd)
+}
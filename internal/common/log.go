@@ -0,0 +1,25 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package common
+
+import "sync/atomic"
+
+// Logger receives diagnostic events. fields hold event-specific values, like object names and sizes.
+type Logger func(event string, fields map[string]interface{})
+
+var (
+	logger atomic.Value
+)
+
+// SetLogger installs a logger for diagnostic events. Passing nil disables logging.
+func SetLogger(l Logger) {
+	logger.Store(l)
+}
+
+// CurrentLogger returns the installed logger, or nil, if there is no one.
+// Callers must check the result for nil before building event fields,
+// so that disabled logging does not cause allocations.
+func CurrentLogger() Logger {
+	l, _ := logger.Load().(Logger)
+	return l
+}
@@ -0,0 +1,50 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package common
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	namePrefixSeparator = "."
+)
+
+var (
+	namePrefix atomic.Value
+)
+
+// SetNamePrefix sets a namespace, which is prepended to the names of all the objects.
+// An empty prefix disables the namespace.
+func SetNamePrefix(prefix string) {
+	namePrefix.Store(prefix)
+}
+
+// NamePrefix returns current namespace prefix.
+func NamePrefix() string {
+	prefix, _ := namePrefix.Load().(string)
+	return prefix
+}
+
+// PrefixedName returns a name of an object with the current namespace prefix prepended.
+func PrefixedName(name string) string {
+	if prefix := NamePrefix(); len(prefix) > 0 {
+		return prefix + namePrefixSeparator + name
+	}
+	return name
+}
+
+// UnprefixedName strips the current namespace prefix from a name.
+// It returns false, if the name does not belong to the current namespace.
+func UnprefixedName(name string) (string, bool) {
+	prefix := NamePrefix()
+	if len(prefix) == 0 {
+		return name, true
+	}
+	prefix += namePrefixSeparator
+	if !strings.HasPrefix(name, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(name, prefix), true
+}
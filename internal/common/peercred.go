@@ -0,0 +1,13 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package common
+
+import "errors"
+
+// ErrNotSupported is returned, if an operation is not supported on the current platform or transport.
+var ErrNotSupported = errors.New("operation is not supported")
+
+// PeerCred is the platform-specific implementation of ipc.PeerCred.
+func PeerCred(fd uintptr) (pid, uid, gid int, err error) {
+	return peerCred(fd)
+}
@@ -0,0 +1,20 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package common
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func peerCred(fd uintptr) (pid, uid, gid int, err error) {
+	cred, err := unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	if err != nil {
+		if err == unix.ENOPROTOOPT || err == unix.ENOTSOCK {
+			return 0, 0, 0, ErrNotSupported
+		}
+		return 0, 0, 0, os.NewSyscallError("GETSOCKOPT", err)
+	}
+	return int(cred.Pid), int(cred.Uid), int(cred.Gid), nil
+}
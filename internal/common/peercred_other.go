@@ -0,0 +1,9 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build !linux
+
+package common
+
+func peerCred(fd uintptr) (pid, uid, gid int, err error) {
+	return 0, 0, 0, ErrNotSupported
+}
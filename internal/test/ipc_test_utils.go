@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"strings"
 	"syscall"
@@ -59,16 +60,17 @@ func BytesToString(data []byte) string {
 
 // launch helpers
 
-func goRunApp(args []string, killChan <-chan bool) (*exec.Cmd, *bytes.Buffer, error) {
+func goRunApp(args []string, files []*os.File, killChan <-chan bool) (*exec.Cmd, *bytes.Buffer, error) {
 	args = append([]string{"run"}, args...)
-	return runApp("go", args, killChan)
+	return runApp("go", args, files, killChan)
 }
 
-func runApp(command string, args []string, killChan <-chan bool) (*exec.Cmd, *bytes.Buffer, error) {
+func runApp(command string, args []string, files []*os.File, killChan <-chan bool) (*exec.Cmd, *bytes.Buffer, error) {
 	cmd := exec.Command(command, args...)
 	buff := bytes.NewBuffer(nil)
 	cmd.Stderr = buff
 	cmd.Stdout = buff
+	cmd.ExtraFiles = files
 	if err := cmd.Start(); err != nil {
 		return nil, nil, err
 	}
@@ -103,7 +105,14 @@ func waitForCommand(cmd *exec.Cmd, buff *bytes.Buffer) (result TestAppResult) {
 // RunTestApp starts a go program via 'go run'.
 // To kill the process, send to killChan
 func RunTestApp(args []string, killChan <-chan bool) (result TestAppResult) {
-	if cmd, buff, err := goRunApp(args, killChan); err == nil {
+	return RunTestAppFiles(args, nil, killChan)
+}
+
+// RunTestAppFiles starts a go program via 'go run' passing it additional open files.
+// The files are available in the program as descriptors 3, 4, and so on.
+// To kill the process, send to killChan
+func RunTestAppFiles(args []string, files []*os.File, killChan <-chan bool) (result TestAppResult) {
+	if cmd, buff, err := goRunApp(args, files, killChan); err == nil {
 		result = waitForCommand(cmd, buff)
 	} else {
 		result.Err = err
@@ -113,7 +122,7 @@ func RunTestApp(args []string, killChan <-chan bool) (result TestAppResult) {
 
 // RunApp starts a go program. To kill the process, send to killChan
 func RunApp(command string, args []string, killChan <-chan bool) (result TestAppResult) {
-	if cmd, buff, err := runApp(command, args, killChan); err == nil {
+	if cmd, buff, err := runApp(command, args, nil, killChan); err == nil {
 		result = waitForCommand(cmd, buff)
 	} else {
 		result.Err = err
@@ -126,7 +135,7 @@ func RunApp(command string, args []string, killChan <-chan bool) (result TestApp
 // To wait for the program to finish, receive on TestAppResult chan.
 func RunTestAppAsync(args []string, killChan <-chan bool) <-chan TestAppResult {
 	ch := make(chan TestAppResult, 1)
-	if cmd, buff, err := goRunApp(args, killChan); err != nil {
+	if cmd, buff, err := goRunApp(args, nil, killChan); err != nil {
 		ch <- TestAppResult{Err: err}
 	} else {
 		go func() {
@@ -0,0 +1,15 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package ipc
+
+import "github.com/nxgtw/go-ipc/internal/common"
+
+// SetLogger installs a function, which receives diagnostic events from go-ipc packages:
+//	mutex_lock, mutex_unlock - a mutex was acquired or released. fields: name, pid.
+//	mq_send, mq_receive - a message was sent to or received from a queue. fields: name, prio, size.
+//	region_map, region_unmap - a memory region was mapped or unmapped. fields: size, offset.
+// The logger is called synchronously, so it must be fast and must not use go-ipc objects itself.
+// Passing nil (default) disables logging, and then events cost nothing but a nil check.
+func SetLogger(logger func(event string, fields map[string]interface{})) {
+	common.SetLogger(logger)
+}
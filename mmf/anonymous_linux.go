@@ -0,0 +1,56 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	anonymousObjectName = "go-ipc-anonymous"
+)
+
+// NewAnonymousMemoryRegion creates a region backed by a new anonymous memory object.
+// The object has no name and can't be opened by unrelated processes.
+// It can be shared with child processes by passing them the file returned by AnonymousFile,
+// for example, via exec.Cmd.ExtraFiles. A child maps it with NewMemoryRegion.
+// The object is closed with the region, and destroyed, when all the processes close it.
+// If mode contains MEM_HUGE, the object is allocated in huge pages.
+//	mode - open flags. see MEM_* constants.
+//	size - mapping size.
+func NewAnonymousMemoryRegion(mode int, size int) (*MemoryRegion, error) {
+	if size <= 0 {
+		return nil, errors.New("the size must be positive")
+	}
+	memfdFlags := unix.MFD_CLOEXEC
+	if mode&MEM_HUGE != 0 {
+		memfdFlags |= unix.MFD_HUGETLB
+	}
+	fd, err := unix.MemfdCreate(anonymousObjectName, memfdFlags)
+	if err != nil {
+		return nil, errors.Wrap(os.NewSyscallError("memfd_create", err), "failed to create anonymous object")
+	}
+	file := os.NewFile(uintptr(fd), anonymousObjectName)
+	if err = file.Truncate(int64(size)); err != nil {
+		file.Close()
+		return nil, errors.Wrap(err, "failed to truncate anonymous object")
+	}
+	region, err := NewMemoryRegion(file, mode, 0, size)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	region.owned = file
+	return region, nil
+}
+
+// AnonymousFile returns the file of the anonymous object, which backs the region.
+// It returns nil, if the region was not created with NewAnonymousMemoryRegion, or it is closed.
+// The file is owned by the region and must not be closed by the caller.
+func (region *MemoryRegion) AnonymousFile() *os.File {
+	file, _ := region.owned.(*os.File)
+	return file
+}
@@ -0,0 +1,88 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnonymousMemoryRegion(t *testing.T) {
+	a := assert.New(t)
+	_, err := NewAnonymousMemoryRegion(MEM_READWRITE, 0)
+	a.Error(err)
+	region, err := NewAnonymousMemoryRegion(MEM_READWRITE, 1024)
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(1024, region.Size())
+	copy(region.Data(), []byte{1, 2, 3})
+	// another mapping of the same object shares the data.
+	region2, err := NewMemoryRegion(region.AnonymousFile(), MEM_READ_ONLY, 0, 3)
+	if a.NoError(err) {
+		a.Equal([]byte{1, 2, 3}, region2.Data())
+		a.NoError(region2.Close())
+	}
+	a.Nil(region2.AnonymousFile())
+	if a.NoError(region.Remap(8192)) {
+		a.Equal([]byte{1, 2, 3}, region.Data()[:3])
+	}
+	a.NoError(region.Close())
+	a.Nil(region.AnonymousFile())
+}
+
+func TestAnonymousMemoryRegionHuge(t *testing.T) {
+	const size = 2 * 1024 * 1024
+	a := assert.New(t)
+	pageSize, err := hugePageSize()
+	if err != nil || pageSize != size || freeHugePages() == 0 {
+		t.Skip("no free 2MB huge pages")
+	}
+	_, err = NewAnonymousMemoryRegion(MEM_READWRITE|MEM_HUGE, size/2)
+	a.Error(err)
+	region, err := NewAnonymousMemoryRegion(MEM_READWRITE|MEM_HUGE, size)
+	if !a.NoError(err) {
+		return
+	}
+	data := region.Data()
+	data[0], data[size-1] = 1, 2
+	a.Equal([]byte{1, 2}, []byte{data[0], data[size-1]})
+	a.NoError(region.Close())
+}
+
+func TestMemoryRegionHugeUnaligned(t *testing.T) {
+	a := assert.New(t)
+	if _, err := hugePageSize(); err != nil {
+		t.Skip("huge pages are not supported")
+	}
+	region, err := NewAnonymousMemoryRegion(MEM_READWRITE, 4096)
+	if !a.NoError(err) {
+		return
+	}
+	defer region.Close()
+	_, err = NewMemoryRegion(region.AnonymousFile(), MEM_READWRITE|MEM_HUGE, 0, 4096)
+	a.Error(err)
+}
+
+// freeHugePages returns the number of free huge pages from /proc/meminfo.
+func freeHugePages() int {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "HugePages_Free:" {
+			n, _ := strconv.Atoi(fields[1])
+			return n
+		}
+	}
+	return 0
+}
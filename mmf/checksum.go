@@ -0,0 +1,39 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"hash/fnv"
+
+	"github.com/pkg/errors"
+)
+
+// Checksum returns a 64-bit FNV-1a hash of the region's data.
+// It can be used to detect torn writes without a lock: a producer stores the checksum
+// along with the data, and a consumer compares it with the checksum of what it has read.
+func Checksum(region *MemoryRegion) uint64 {
+	h := fnv.New64a()
+	h.Write(region.Data())
+	UseMemoryRegion(region)
+	return h.Sum64()
+}
+
+// ChecksumRange returns a 64-bit FNV-1a hash of n bytes of the region's data starting at offset.
+// It allows to exclude the place, where the checksum itself is stored, for example, a trailing field.
+func ChecksumRange(region *MemoryRegion, offset int64, n int) (uint64, error) {
+	if n < 0 {
+		return 0, errors.Errorf("invalid number of bytes %d", n)
+	}
+	if err := checkCopyRange(region.Size(), offset, n); err != nil {
+		return 0, err
+	}
+	h := fnv.New64a()
+	h.Write(region.Data()[offset : offset+int64(n)])
+	UseMemoryRegion(region)
+	return h.Sum64(), nil
+}
+
+// VerifyChecksum returns true, if the checksum of the region's data is equal to expected.
+func VerifyChecksum(region *MemoryRegion, expected uint64) bool {
+	return Checksum(region) == expected
+}
@@ -0,0 +1,60 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecksum(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(1024)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	data := region.Data()
+	for i := range data {
+		data[i] = byte(i)
+	}
+	sum := Checksum(region)
+	a.True(VerifyChecksum(region, sum))
+	a.Equal(sum, Checksum(region))
+	data[517]++
+	a.NotEqual(sum, Checksum(region))
+	a.False(VerifyChecksum(region, sum))
+	data[517]--
+	a.True(VerifyChecksum(region, sum))
+}
+
+func TestChecksumRangeTrailingField(t *testing.T) {
+	const payloadSize = 1016
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(payloadSize + 8)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	data := region.Data()
+	copy(data, []byte("payload"))
+	sum, err := ChecksumRange(region, 0, payloadSize)
+	if !a.NoError(err) {
+		return
+	}
+	binary.LittleEndian.PutUint64(data[payloadSize:], sum)
+	// the consumer's side.
+	actual, err := ChecksumRange(region, 0, payloadSize)
+	a.NoError(err)
+	a.Equal(binary.LittleEndian.Uint64(data[payloadSize:]), actual)
+	data[3] = 'X'
+	actual, err = ChecksumRange(region, 0, payloadSize)
+	a.NoError(err)
+	a.NotEqual(sum, actual)
+	_, err = ChecksumRange(region, 1, payloadSize+8)
+	a.Error(err)
+	_, err = ChecksumRange(region, 0, -1)
+	a.Error(err)
+}
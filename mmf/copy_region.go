@@ -0,0 +1,34 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"github.com/pkg/errors"
+)
+
+// CopyRegion copies n bytes from src at srcOff into dst at dstOff and returns the number of bytes copied.
+// dst and src may be the same region, and the ranges may overlap.
+// If either range is out of its region bounds, nothing is copied, and an error is returned.
+func CopyRegion(dst, src *MemoryRegion, dstOff, srcOff int64, n int) (int, error) {
+	if n < 0 {
+		return 0, errors.Errorf("invalid number of bytes %d", n)
+	}
+	if err := checkCopyRange(src.Size(), srcOff, n); err != nil {
+		return 0, errors.Wrap(err, "invalid source range")
+	}
+	if err := checkCopyRange(dst.Size(), dstOff, n); err != nil {
+		return 0, errors.Wrap(err, "invalid destination range")
+	}
+	// copy works like memmove, so overlapping ranges are handled correctly.
+	copied := copy(dst.Data()[dstOff:dstOff+int64(n)], src.Data()[srcOff:srcOff+int64(n)])
+	UseMemoryRegion(dst)
+	UseMemoryRegion(src)
+	return copied, nil
+}
+
+func checkCopyRange(regionSize int, offset int64, n int) error {
+	if offset < 0 || offset > int64(regionSize) || int64(n) > int64(regionSize)-offset {
+		return errors.Errorf("%d bytes at offset %d are out of the region bounds [0, %d)", n, offset, regionSize)
+	}
+	return nil
+}
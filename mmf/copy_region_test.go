@@ -0,0 +1,81 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyRegion(t *testing.T) {
+	a := assert.New(t)
+	src, cleanupSrc, err := newTempFileRegion(64)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanupSrc()
+	dst, cleanupDst, err := newTempFileRegion(32)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanupDst()
+	for i := range src.Data() {
+		src.Data()[i] = byte(i)
+	}
+	n, err := CopyRegion(dst, src, 8, 16, 24)
+	if a.NoError(err) {
+		a.Equal(24, n)
+		a.Equal(src.Data()[16:40], dst.Data()[8:32])
+		a.Equal(make([]byte, 8), dst.Data()[:8])
+	}
+	n, err = CopyRegion(dst, src, 0, 0, 0)
+	a.NoError(err)
+	a.Equal(0, n)
+}
+
+func TestCopyRegionOverlapping(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(16)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	data := region.Data()
+	for i := range data {
+		data[i] = byte(i)
+	}
+	// forward overlapping copy.
+	n, err := CopyRegion(region, region, 4, 0, 8)
+	if a.NoError(err) {
+		a.Equal(8, n)
+		a.Equal([]byte{0, 1, 2, 3, 0, 1, 2, 3, 4, 5, 6, 7, 12, 13, 14, 15}, data)
+	}
+	// backward overlapping copy.
+	n, err = CopyRegion(region, region, 0, 4, 8)
+	if a.NoError(err) {
+		a.Equal(8, n)
+		a.Equal([]byte{0, 1, 2, 3, 4, 5, 6, 7, 4, 5, 6, 7, 12, 13, 14, 15}, data)
+	}
+}
+
+func TestCopyRegionOutOfBounds(t *testing.T) {
+	a := assert.New(t)
+	src, cleanupSrc, err := newTempFileRegion(16)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanupSrc()
+	dst, cleanupDst, err := newTempFileRegion(8)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanupDst()
+	copy(src.Data(), []byte{1, 2, 3})
+	for _, args := range [][3]int{{0, 0, 9}, {4, 0, 5}, {0, 12, 5}, {-1, 0, 1}, {0, -1, 1}, {0, 0, -1}, {9, 0, 0}, {0, 17, 0}} {
+		n, err := CopyRegion(dst, src, int64(args[0]), int64(args[1]), args[2])
+		a.Error(err, "%v", args)
+		a.Equal(0, n)
+	}
+	a.Equal(make([]byte, 8), dst.Data())
+}
@@ -1,4 +1,8 @@
 // Copyright 2016 Aleksandr Demakin. All rights reserved.
 
 // Package mmf implements privitives for mapping files into memory.
+//
+// Shared data structures, like SharedLRU and SharedHeap, are guarded by spin locks placed in their regions.
+// These locks are not robust: if a process crashes while holding a lock,
+// the data structure remains locked forever, and all the processes using it hang.
 package mmf
@@ -0,0 +1,37 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"os"
+)
+
+// fileMemoryObject is a Mappable, which wraps an already opened file.
+type fileMemoryObject struct {
+	file *os.File
+}
+
+// NewFileMemoryObject returns a Mappable for an already opened file, so that it can be mapped with NewMemoryRegion.
+// It can be used for inherited descriptors, files opened with O_TMPFILE,
+// or descriptors returned by memfd_create, which give anonymous memory, that can be shared with other processes.
+// If the size of the region is 0, the whole file is mapped.
+// The caller retains the ownership of the file: it is not closed with the region and must be closed by the caller.
+// The file must stay open while the region is being created or remapped.
+func NewFileMemoryObject(f *os.File) Mappable {
+	return &fileMemoryObject{file: f}
+}
+
+// Fd returns the descriptor of the file.
+func (obj *fileMemoryObject) Fd() uintptr {
+	return obj.file.Fd()
+}
+
+// Name returns the name of the file.
+func (obj *fileMemoryObject) Name() string {
+	return obj.file.Name()
+}
+
+// Stat returns the FileInfo structure describing the file.
+func (obj *fileMemoryObject) Stat() (os.FileInfo, error) {
+	return obj.file.Stat()
+}
@@ -0,0 +1,114 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// gzip region header contains uncompressed and compressed data lengths.
+	gzipRegionHdrSize = 16
+)
+
+type regionGzipWriter struct {
+	region     *MemoryRegion
+	gz         *gzip.Writer
+	data       []byte
+	compressed int
+	written    uint64
+	err        error
+}
+
+// RegionGzipWriter returns a writer, which compresses data directly into the region.
+// The region starts with a header containing the lengths of uncompressed and compressed data,
+// which is filled, when the writer is closed. Use RegionGzipReader to read the data.
+// If compressed data doesn't fit into the region, Write or Close return io.ErrShortWrite.
+func RegionGzipWriter(region *MemoryRegion) (io.WriteCloser, error) {
+	data := region.Data()
+	if len(data) < gzipRegionHdrSize {
+		return nil, errors.New("the region is too small")
+	}
+	for i := 0; i < gzipRegionHdrSize; i++ {
+		data[i] = 0
+	}
+	w := &regionGzipWriter{region: region, data: data[gzipRegionHdrSize:]}
+	w.gz = gzip.NewWriter((*regionGzipSink)(w))
+	return w, nil
+}
+
+func (w *regionGzipWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.gz.Write(p)
+	w.written += uint64(n)
+	if w.err != nil {
+		err = w.err
+	}
+	return n, err
+}
+
+// Close flushes compressed data and writes the header.
+func (w *regionGzipWriter) Close() error {
+	if w.gz == nil {
+		return w.err
+	}
+	err := w.gz.Close()
+	w.gz = nil
+	if w.err != nil {
+		return w.err
+	}
+	if err != nil {
+		return err
+	}
+	hdr := w.region.Data()[:gzipRegionHdrSize]
+	binary.LittleEndian.PutUint64(hdr[0:], w.written)
+	binary.LittleEndian.PutUint64(hdr[8:], uint64(w.compressed))
+	return nil
+}
+
+// regionGzipSink receives compressed data from the gzip writer.
+type regionGzipSink regionGzipWriter
+
+func (s *regionGzipSink) Write(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	n := copy(s.data[s.compressed:], p)
+	s.compressed += n
+	if n < len(p) {
+		s.err = io.ErrShortWrite
+		return n, s.err
+	}
+	return n, nil
+}
+
+type regionGzipReader struct {
+	region *MemoryRegion
+	*gzip.Reader
+}
+
+// RegionGzipReader returns a reader for the data written into the region by RegionGzipWriter.
+// It also returns the length of uncompressed data.
+func RegionGzipReader(region *MemoryRegion) (io.ReadCloser, int64, error) {
+	data := region.Data()
+	if len(data) < gzipRegionHdrSize {
+		return nil, 0, errors.New("the region is too small")
+	}
+	uncompressed := binary.LittleEndian.Uint64(data[0:])
+	compressed := binary.LittleEndian.Uint64(data[8:])
+	if compressed == 0 || compressed > uint64(len(data)-gzipRegionHdrSize) {
+		return nil, 0, errors.New("the region does not contain compressed data")
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data[gzipRegionHdrSize : gzipRegionHdrSize+int(compressed)]))
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to create gzip reader")
+	}
+	return &regionGzipReader{region: region, Reader: gz}, int64(uncompressed), nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+
+	"bitbucket.org/avd/go-ipc/mmf"
+)
+
+const usage = `  test program for memory regions.
+available commands:
+  consume file_name n
+    pops n messages from a ring placed in the file and checks,
+    that each message contains its sequence number followed by the sequence number bytes.
+  append file_name id n
+    appends n records of the writer id to a shared buffer placed in the file.
+`
+
+func consume() error {
+	if flag.NArg() != 3 {
+		return fmt.Errorf("consume: must provide file name and the number of messages")
+	}
+	n, err := strconv.Atoi(flag.Arg(2))
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(flag.Arg(1), os.O_RDWR, 0666)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	region, err := mmf.NewMemoryRegion(file, mmf.MEM_READWRITE, 0, 0)
+	if err != nil {
+		return err
+	}
+	defer region.Close()
+	ring, err := mmf.OpenSPSCRing(region)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, 64)
+	for i := 0; i < n; i++ {
+		size, ok := ring.Pop(buf)
+		for ; !ok; size, ok = ring.Pop(buf) {
+			runtime.Gosched()
+		}
+		expected := ringMessage(uint64(i))
+		if !bytes.Equal(expected, buf[:size]) {
+			return fmt.Errorf("message %d: expected %v, got %v", i, expected, buf[:size])
+		}
+	}
+	return nil
+}
+
+func appendRecords() error {
+	if flag.NArg() != 4 {
+		return fmt.Errorf("append: must provide file name, writer id and the number of records")
+	}
+	id, err := strconv.Atoi(flag.Arg(2))
+	if err != nil {
+		return err
+	}
+	n, err := strconv.Atoi(flag.Arg(3))
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(flag.Arg(1), os.O_RDWR, 0666)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	region, err := mmf.NewMemoryRegion(file, mmf.MEM_READWRITE, 0, 0)
+	if err != nil {
+		return err
+	}
+	defer region.Close()
+	buf, err := mmf.NewSharedBuffer(region)
+	if err != nil {
+		return err
+	}
+	record := bufferRecord(byte(id))
+	for i := 0; i < n; i++ {
+		if _, err = buf.Append(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bufferRecord must be in sync with the one used in mmf tests.
+func bufferRecord(id byte) []byte {
+	return []byte{id, id, id, id}
+}
+
+// ringMessage must be in sync with the one used in mmf tests.
+func ringMessage(seq uint64) []byte {
+	result := make([]byte, 8+seq%8)
+	binary.LittleEndian.PutUint64(result, seq)
+	for i := 8; i < len(result); i++ {
+		result[i] = byte(seq)
+	}
+	return result
+}
+
+func runCommand() error {
+	command := flag.Arg(0)
+	switch command {
+	case "consume":
+		return consume()
+	case "append":
+		return appendRecords()
+	default:
+		return fmt.Errorf("unknown command")
+	}
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Print(usage)
+		flag.Usage()
+		os.Exit(1)
+	}
+	if err := runCommand(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
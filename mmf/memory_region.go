@@ -3,11 +3,14 @@
 package mmf
 
 import (
+	"io"
 	"os"
 	"runtime"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/common"
 	"github.com/pkg/errors"
 )
 
@@ -17,12 +20,60 @@ const (
 	MEM_READ_PRIVATE  = 0x00000002
 	MEM_READWRITE     = 0x00000004
 	MEM_COPY_ON_WRITE = 0x00000008
+	// MEM_POPULATE can be combined with any of the modes above.
+	// It makes all the pages of the region be loaded at mapping time,
+	// so that the first access to the data does not cause a page fault.
+	// On linux MAP_POPULATE is used. On other platforms it is emulated by reading every page of the region.
+	MEM_POPULATE = 0x00000010
+	// MEM_HUGE can be combined with any of the modes above. It makes the region use huge pages,
+	// which reduces TLB pressure for large regions. It is supported on linux only, where MAP_HUGETLB is used.
+	// The object must be a file on hugetlbfs, or an anonymous region created with MEM_HUGE.
+	// The offset and the size of the region must be multiples of the huge page size.
+	// The system must have enough free huge pages reserved, see /proc/sys/vm/nr_hugepages.
+	MEM_HUGE = 0x00000020
+)
+
+// Advice values for Advise and AdviseRange. They are translated into platform-specific madvise values.
+const (
+	// ADV_NORMAL is the default access pattern.
+	ADV_NORMAL = iota
+	// ADV_RANDOM means, that the pages will be accessed in random order, so read-ahead is not useful.
+	ADV_RANDOM
+	// ADV_SEQUENTIAL means, that the pages will be accessed sequentially, so they can be read aggressively.
+	ADV_SEQUENTIAL
+	// ADV_WILLNEED means, that the pages will be accessed soon, so they can be read in advance.
+	ADV_WILLNEED
+	// ADV_DONTNEED means, that the pages will not be accessed soon, so their resources can be freed.
+	ADV_DONTNEED
+)
+
+var (
+	// ErrNotSupported is returned, if an operation is not supported on the current platform.
+	// It is the same value, as ipc.ErrNotSupported.
+	ErrNotSupported = common.ErrNotSupported
+	// ErrMemLockLimit is returned by Pin, if the system refused to lock the pages of the region in memory,
+	// because the calling process is not allowed to lock that much memory.
+	ErrMemLockLimit = errors.New("failed to lock the region in memory: the limit of locked memory is too low, raise RLIMIT_MEMLOCK (see 'ulimit -l')")
 )
 
 var (
 	mmapOffsetMultiple int64
+	closeErrorHandler  atomic.Value
 )
 
+// CloseErrorHandler is called, when a region is closed by the finalizer and the close fails.
+//	name - the name of the mapped object, if it has Name() method, or an empty string.
+//	err - close error.
+type CloseErrorHandler func(name string, err error)
+
+// SetCloseErrorHandler sets a handler, which is called, if a region closed by the gc finalizer
+// could not be unmapped. By default, such errors are ignored.
+// The handler is called from the finalizer goroutine, so it must not block for a long time.
+// It is safe to call SetCloseErrorHandler concurrently with finalizers. Pass nil to remove the handler.
+func SetCloseErrorHandler(handler func(name string, err error)) {
+	closeErrorHandler.Store(CloseErrorHandler(handler))
+}
+
 // MemoryRegion is a mmapped area of a memory object.
 // Warning. The internal object has a finalizer set,
 // so the region will be unmapped during the gc.
@@ -36,6 +87,13 @@ var (
 // To avoid this, you can use UseMemoryRegion() or region readers/writers.
 type MemoryRegion struct {
 	*memoryRegion
+	// object, flag, and offset are kept to be able to remap the region.
+	object Mappable
+	flag   int
+	offset int64
+	// owned is an object, which was created by the region itself, like an anonymous object.
+	// it is closed with the region.
+	owned io.Closer
 }
 
 // Mappable is a named object, which can return a handle,
@@ -54,16 +112,97 @@ func NewMemoryRegion(object Mappable, flag int, offset int64, size int) (*Memory
 	if err != nil {
 		return nil, err
 	}
-	result := &MemoryRegion{impl}
+	result := &MemoryRegion{memoryRegion: impl, object: object, flag: flag, offset: offset}
+	var name string
+	if named, ok := object.(interface {
+		Name() string
+	}); ok {
+		name = named.Name()
+	}
 	runtime.SetFinalizer(impl, func(region *memoryRegion) {
-		region.Close()
+		finalizeRegion(region, name)
 	})
+	logRegionEvent("region_map", size, offset)
+	common.AutoRegisterForCleanup(result)
 	return result, nil
 }
 
+// readOnly returns true, if the region's memory can't be written.
+func (region *MemoryRegion) readOnly() bool {
+	mode := region.flag &^ (MEM_POPULATE | MEM_HUGE)
+	return mode == MEM_READ_ONLY || mode == MEM_READ_PRIVATE
+}
+
+// finalizeRegion closes the region passing an error to the close error handler.
+func finalizeRegion(region *memoryRegion, name string) {
+	if err := region.Close(); err != nil {
+		if handler, ok := closeErrorHandler.Load().(CloseErrorHandler); ok && handler != nil {
+			handler(name, err)
+		}
+	}
+}
+
+// logRegionEvent passes a map/unmap event to the logger, if it is installed.
+func logRegionEvent(event string, size int, offset int64) {
+	if logger := common.CurrentLogger(); logger != nil {
+		logger(event, map[string]interface{}{"size": size, "offset": offset})
+	}
+}
+
 // Close unmaps the regions so that it cannot be longer used.
 func (region *MemoryRegion) Close() error {
-	return region.memoryRegion.Close()
+	logRegionEvent("region_unmap", region.Size(), region.offset)
+	common.UnregisterFromCleanup(region)
+	region.object = nil
+	err := region.memoryRegion.Close()
+	if region.owned != nil {
+		if closeErr := region.owned.Close(); err == nil {
+			err = closeErr
+		}
+		region.owned = nil
+	}
+	return err
+}
+
+// Remap changes the size of the mapping to newSize bytes.
+// If the mapped object is smaller, than offset + newSize, it is enlarged with its Truncate method,
+// so the object must have one, like os.File or shm.MemoryObject.
+// The object must be alive, and it must not be closed before the region.
+// After the call Data() returns the new mapping, and all the slices obtained before become invalid.
+// MemoryRegionReader must be recreated, while MemoryRegionWriter remains valid.
+// Objects placed in the region, like SharedInt64, SharedLRU, SharedHeap, and mq.TypedQueue,
+// keep pointers into the old mapping, so they must be opened again after the call.
+// Using them after Remap leads to undefined behavior.
+// Darwin: as a shared memory object can be truncated only once, shm objects can't be enlarged.
+func (region *MemoryRegion) Remap(newSize int) error {
+	if region.object == nil {
+		return errors.New("the region is closed")
+	}
+	if newSize <= 0 {
+		return errors.New("the size must be positive")
+	}
+	curSize, err := fileSizeFromFd(region.object)
+	if err != nil {
+		return errors.Wrap(err, "file size check failed")
+	}
+	if needed := region.offset + int64(newSize); curSize < needed {
+		truncater, ok := region.object.(interface {
+			Truncate(int64) error
+		})
+		if !ok {
+			return errors.New("the object can't be resized")
+		}
+		if err = truncater.Truncate(needed); err != nil {
+			return errors.Wrap(err, "failed to resize the object")
+		}
+	}
+	impl, err := newMemoryRegion(region.object, region.flag, region.offset, newSize)
+	if err != nil {
+		return err
+	}
+	old := *region.memoryRegion
+	*region.memoryRegion = *impl
+	return old.Close()
 }
 
 // Data returns region's mapped data.
@@ -77,6 +216,59 @@ func (region *MemoryRegion) Flush(async bool) error {
 	return region.memoryRegion.Flush(async)
 }
 
+// FlushRange syncs mapped content with the file data for length bytes starting at offset.
+// The range is extended to the page boundary, as required by the system.
+// It returns an error, if the range is out of the region bounds.
+func (region *MemoryRegion) FlushRange(offset int64, length int, async bool) error {
+	if offset < 0 || length < 0 || offset+int64(length) > int64(region.Size()) {
+		return errors.Errorf("the range [%d, %d) is out of the region bounds [0, %d)", offset, offset+int64(length), region.Size())
+	}
+	if length == 0 {
+		return nil
+	}
+	start := region.pageOffset + offset
+	start -= calcMmapOffsetFixup(start)
+	return region.memoryRegion.flushRange(start, region.pageOffset+offset+int64(length), async)
+}
+
+// Advise gives the system a hint about the access pattern for the whole region.
+//	advice - one of ADV_* constants.
+// It returns ErrNotSupported on platforms without madvise.
+func (region *MemoryRegion) Advise(advice int) error {
+	return region.AdviseRange(0, region.Size(), advice)
+}
+
+// AdviseRange gives the system a hint about the access pattern for length bytes starting at offset.
+// The range is extended to the page boundary, as required by the system.
+// It returns an error, if the range is out of the region bounds, and ErrNotSupported on platforms without madvise.
+func (region *MemoryRegion) AdviseRange(offset int64, length int, advice int) error {
+	if offset < 0 || length < 0 || offset+int64(length) > int64(region.Size()) {
+		return errors.Errorf("the range [%d, %d) is out of the region bounds [0, %d)", offset, offset+int64(length), region.Size())
+	}
+	if advice < ADV_NORMAL || advice > ADV_DONTNEED {
+		return errors.Errorf("invalid advice %d", advice)
+	}
+	if length == 0 {
+		return nil
+	}
+	start := region.pageOffset + offset
+	start -= calcMmapOffsetFixup(start)
+	return region.memoryRegion.adviseRange(start, region.pageOffset+offset+int64(length), advice)
+}
+
+// Pin locks all the pages of the region in RAM, so that they are never swapped out.
+// The pages stay locked until Unpin is called, or the region is closed.
+// It returns ErrMemLockLimit, if the size of the region exceeds the amount of memory the process can lock.
+// On unix mlock is used, on windows - VirtualLock.
+func (region *MemoryRegion) Pin() error {
+	return region.memoryRegion.pin()
+}
+
+// Unpin unlocks the pages of the region, locked by Pin, so that they can be swapped out again.
+func (region *MemoryRegion) Unpin() error {
+	return region.memoryRegion.unpin()
+}
+
 // Size returns mapping size.
 func (region *MemoryRegion) Size() int {
 	return region.memoryRegion.Size()
@@ -97,13 +289,31 @@ func UseMemoryRegion(region *MemoryRegion) {
 	allocator.Use(unsafe.Pointer(region))
 }
 
+// pageSink is used to prevent the compiler from optimizing out page reads in touchPages.
+var pageSink byte
+
+// touchPages reads a byte from every memory page of data, forcing them to be loaded.
+func touchPages(data []byte) {
+	var sum byte
+	pageSize := os.Getpagesize()
+	for i := 0; i < len(data); i += pageSize {
+		sum += data[i]
+	}
+	pageSink = sum
+}
+
 // calcMmapOffsetFixup returns a value X,
 // so that  offset - X is a valid mmap offset
 // typically the value of the fixup is a memory page size,
 // however, on windows it must be a multiple of the
 // memory allocation granularity value as well.
 func calcMmapOffsetFixup(offset int64) int64 {
-	return (offset - (offset/mmapOffsetMultiple)*mmapOffsetMultiple)
+	return calcMmapOffsetFixupMultiple(offset, mmapOffsetMultiple)
+}
+
+// calcMmapOffsetFixupMultiple returns a value X, so that offset - X is a multiple of 'multiple'.
+func calcMmapOffsetFixupMultiple(offset, multiple int64) int64 {
+	return (offset - (offset/multiple)*multiple)
 }
 
 // fileInfoGetter is used to obtain file's size
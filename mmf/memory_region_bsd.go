@@ -0,0 +1,18 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build darwin freebsd
+
+package mmf
+
+import "github.com/pkg/errors"
+
+const (
+	// there is no MAP_POPULATE, so MEM_POPULATE is emulated with touchPages.
+	mapPopulate = 0
+	// MEM_HUGE is not supported.
+	mapHugeTLB = 0
+)
+
+func hugePageSize() (int64, error) {
+	return 0, errors.New("huge pages are not supported on this platform")
+}
@@ -0,0 +1,43 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	mapPopulate = unix.MAP_POPULATE
+	mapHugeTLB  = unix.MAP_HUGETLB
+)
+
+// hugePageSize returns the default huge page size from /proc/meminfo.
+func hugePageSize() (int64, error) {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 || fields[0] != "Hugepagesize:" || fields[2] != "kB" {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil || size <= 0 {
+			return 0, errors.Errorf("invalid huge page size %q", fields[1])
+		}
+		return size * 1024, nil
+	}
+	if err = scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, errors.New("huge pages are not supported by the kernel")
+}
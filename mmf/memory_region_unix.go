@@ -42,11 +42,26 @@ func newMemoryRegion(obj Mappable, flag int, offset int64, size int) (*memoryReg
 	if calculatedSize > 0 && int64(size)+offset > calculatedSize {
 		return nil, errors.New("invalid mapping length")
 	}
-	pageOffset := calcMmapOffsetFixup(offset)
+	multiple := mmapOffsetMultiple
+	if flag&MEM_HUGE != 0 {
+		if multiple, err = hugePageSize(); err != nil {
+			return nil, errors.Wrap(err, "failed to get huge page size")
+		}
+		if offset%multiple != 0 || int64(size)%multiple != 0 {
+			return nil, errors.Errorf("the offset and the size of a huge page region must be multiples of %d", multiple)
+		}
+	}
+	pageOffset := calcMmapOffsetFixupMultiple(offset, multiple)
 	var data []byte
 	if data, err = unix.Mmap(int(obj.Fd()), offset-pageOffset, size+int(pageOffset), prot, flags); err != nil {
+		if flag&MEM_HUGE != 0 && err == unix.ENOMEM {
+			return nil, errors.Wrap(err, "mmap failed: not enough free huge pages")
+		}
 		return nil, errors.Wrap(err, "mmap failed")
 	}
+	if flag&MEM_POPULATE != 0 && mapPopulate == 0 {
+		touchPages(data)
+	}
 	return &memoryRegion{data: data, size: size, pageOffset: pageOffset}, nil
 }
 
@@ -66,22 +81,50 @@ func (region *memoryRegion) Data() []byte {
 }
 
 func (region *memoryRegion) Flush(async bool) error {
+	return region.flushRange(0, int64(len(region.data)), async)
+}
+
+func (region *memoryRegion) flushRange(start, end int64, async bool) error {
 	flag := unix.MS_SYNC
 	if async {
 		flag = unix.MS_ASYNC
 	}
-	if err := msync(region.data, flag); err != nil {
+	if err := msync(region.data[start:end], flag); err != nil {
 		return errors.Wrap(err, "mync failed")
 	}
 	return nil
 }
 
+func (region *memoryRegion) adviseRange(start, end int64, advice int) error {
+	if err := unix.Madvise(region.data[start:end], sysAdvice(advice)); err != nil {
+		return errors.Wrap(os.NewSyscallError("MADVISE", err), "madvise failed")
+	}
+	return nil
+}
+
+func (region *memoryRegion) pin() error {
+	if err := unix.Mlock(region.data); err != nil {
+		if err == unix.ENOMEM || err == unix.EPERM || err == unix.EAGAIN {
+			return ErrMemLockLimit
+		}
+		return errors.Wrap(os.NewSyscallError("MLOCK", err), "mlock failed")
+	}
+	return nil
+}
+
+func (region *memoryRegion) unpin() error {
+	if err := unix.Munlock(region.data); err != nil {
+		return errors.Wrap(os.NewSyscallError("MUNLOCK", err), "munlock failed")
+	}
+	return nil
+}
+
 func (region *memoryRegion) Size() int {
 	return region.size
 }
 
 func memProtAndFlagsFromMode(mode int) (prot, flags int, err error) {
-	switch mode {
+	switch mode &^ (MEM_POPULATE | MEM_HUGE) {
 	case MEM_READ_ONLY:
 		prot = unix.PROT_READ
 		flags = unix.MAP_SHARED
@@ -97,9 +140,33 @@ func memProtAndFlagsFromMode(mode int) (prot, flags int, err error) {
 	default:
 		err = errors.Errorf("invalid memory region flags %d", mode)
 	}
+	if mode&MEM_POPULATE != 0 {
+		flags |= mapPopulate
+	}
+	if mode&MEM_HUGE != 0 {
+		if mapHugeTLB == 0 {
+			err = errors.New("huge pages are not supported on this platform")
+		}
+		flags |= mapHugeTLB
+	}
 	return
 }
 
+func sysAdvice(advice int) int {
+	switch advice {
+	case ADV_RANDOM:
+		return unix.MADV_RANDOM
+	case ADV_SEQUENTIAL:
+		return unix.MADV_SEQUENTIAL
+	case ADV_WILLNEED:
+		return unix.MADV_WILLNEED
+	case ADV_DONTNEED:
+		return unix.MADV_DONTNEED
+	default:
+		return unix.MADV_NORMAL
+	}
+}
+
 // syscalls
 func msync(data []byte, flags int) error {
 	dataPointer := unsafe.Pointer(&data[0])
@@ -33,6 +33,34 @@ type native interface {
 	IsNative() bool
 }
 
+// mappingHandle is a Mappable for a file-mapping handle, which is mapped without CreateFileMapping.
+type mappingHandle uintptr
+
+func (h mappingHandle) Fd() uintptr {
+	return uintptr(h)
+}
+
+func (h mappingHandle) IsNative() bool {
+	return true
+}
+
+// NewMemoryRegionFromHandle maps a view of an existing file-mapping object,
+// for example, the one created with CreateFileMapping by another API.
+// The offset is aligned to the allocation granularity internally, so it can be any value.
+// The handle stays owned by the caller, it is not closed with the region.
+// As the view keeps the mapping object alive, the caller can close the handle after the call.
+// The region can't be remapped.
+//	handle - file-mapping handle. Its access rights must allow the requested mode.
+//	mode - open flags. see MEM_* constants.
+//	offset - offset in bytes from the beginning of the mapping.
+//	size - mapping size. It must be positive.
+func NewMemoryRegionFromHandle(handle uintptr, mode int, offset int64, size int) (*MemoryRegion, error) {
+	if size <= 0 {
+		return nil, errors.New("the size must be positive")
+	}
+	return NewMemoryRegion(mappingHandle(handle), mode, offset, size)
+}
+
 func newMemoryRegion(obj Mappable, mode int, offset int64, size int) (*memoryRegion, error) {
 	prot, flags, err := sysProtAndFlagsFromFlag(mode)
 	if err != nil {
@@ -73,8 +101,12 @@ func newMemoryRegion(obj Mappable, mode int, offset int64, size int) (*memoryReg
 	}
 
 	totalSize := size + int(pageOffset)
+	data := allocator.ByteSliceFromUnsafePointer(unsafe.Pointer(addr), totalSize, totalSize)
+	if mode&MEM_POPULATE != 0 {
+		touchPages(data)
+	}
 	return &memoryRegion{
-		data:       allocator.ByteSliceFromUnsafePointer(unsafe.Pointer(addr), totalSize, totalSize),
+		data:       data,
 		size:       size,
 		pageOffset: pageOffset,
 	}, nil
@@ -99,7 +131,39 @@ func (region *memoryRegion) Size() int {
 }
 
 func (region *memoryRegion) Flush(async bool) error {
-	err := windows.FlushViewOfFile(uintptr(allocator.ByteSliceData(region.data)), uintptr(len(region.data)))
+	return flushView(region.data)
+}
+
+func (region *memoryRegion) flushRange(start, end int64, async bool) error {
+	return flushView(region.data[start:end])
+}
+
+// adviseRange is not supported, as there is no madvise on windows.
+func (region *memoryRegion) adviseRange(start, end int64, advice int) error {
+	return ErrNotSupported
+}
+
+func (region *memoryRegion) pin() error {
+	err := windows.VirtualLock(uintptr(allocator.ByteSliceData(region.data)), uintptr(len(region.data)))
+	if err != nil {
+		if err == windows.ERROR_WORKING_SET_QUOTA {
+			return ErrMemLockLimit
+		}
+		return errors.Wrap(os.NewSyscallError("VirtualLock", err), "failed to lock the view")
+	}
+	return nil
+}
+
+func (region *memoryRegion) unpin() error {
+	err := windows.VirtualUnlock(uintptr(allocator.ByteSliceData(region.data)), uintptr(len(region.data)))
+	if err != nil {
+		return errors.Wrap(os.NewSyscallError("VirtualUnlock", err), "failed to unlock the view")
+	}
+	return nil
+}
+
+func flushView(data []byte) error {
+	err := windows.FlushViewOfFile(uintptr(allocator.ByteSliceData(data)), uintptr(len(data)))
 	if err != nil {
 		return errors.Wrap(err, "FlushViewOfFile failed")
 	}
@@ -107,7 +171,11 @@ func (region *memoryRegion) Flush(async bool) error {
 }
 
 func sysProtAndFlagsFromFlag(mode int) (prot uint32, flags uint32, err error) {
-	switch mode {
+	if mode&MEM_HUGE != 0 {
+		err = errors.New("huge pages are not supported on this platform")
+		return
+	}
+	switch mode &^ MEM_POPULATE {
 	case MEM_READ_ONLY:
 		fallthrough
 	case MEM_READ_PRIVATE:
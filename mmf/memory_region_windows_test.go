@@ -0,0 +1,49 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"testing"
+
+	"github.com/nxgtw/go-ipc/internal/sys/windows"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/windows"
+)
+
+func TestMemoryRegionFromHandle(t *testing.T) {
+	const (
+		size   = 1024 * 1024
+		offset = 100
+	)
+	a := assert.New(t)
+	handle, err := sys.CreateFileMapping(windows.InvalidHandle, nil, windows.PAGE_READWRITE, 0, size, "")
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(windows.CloseHandle(handle))
+	}()
+	_, err = NewMemoryRegionFromHandle(uintptr(handle), MEM_READWRITE, 0, 0)
+	a.Error(err)
+	// the offset is not aligned to the allocation granularity.
+	region, err := NewMemoryRegionFromHandle(uintptr(handle), MEM_READWRITE, offset, 16)
+	if !a.NoError(err) {
+		return
+	}
+	a.Error(region.Remap(32))
+	copy(region.Data(), "go-ipc")
+	a.NoError(region.Close())
+	// check the data with a view created by the raw API.
+	addr, err := windows.MapViewOfFile(handle, windows.FILE_MAP_READ, 0, 0, size)
+	if !a.NoError(err) {
+		return
+	}
+	defer windows.UnmapViewOfFile(addr)
+	// read the view as a memory of the current process, so that its address is not converted into a pointer.
+	data := make([]byte, 6)
+	if !a.NoError(windows.ReadProcessMemory(windows.CurrentProcess(), addr+offset, &data[0], uintptr(len(data)), nil)) {
+		return
+	}
+	a.Equal("go-ipc", string(data))
+}
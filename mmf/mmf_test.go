@@ -3,10 +3,15 @@
 package mmf
 
 import (
+	"bytes"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"math/rand"
 	"os"
+	"runtime"
 	"testing"
+	"unsafe"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -67,6 +72,30 @@ func TestMmfOpenReadonly(t *testing.T) {
 	region.Close()
 }
 
+func TestMmfReadonlyWriter(t *testing.T) {
+	a := assert.New(t)
+	file, err := os.Open(testFile)
+	if !a.NoError(err) {
+		return
+	}
+	defer file.Close()
+	for _, mode := range []int{MEM_READ_ONLY, MEM_READ_PRIVATE, MEM_READ_ONLY | MEM_POPULATE} {
+		region, err := NewMemoryRegion(file, mode, 0, 1024)
+		if !a.NoError(err) {
+			return
+		}
+		_, err = NewMemoryRegionWriterChecked(region)
+		if a.Error(err) {
+			a.Contains(err.Error(), "read-only")
+		}
+		a.Panics(func() {
+			NewMemoryRegionWriter(region)
+		})
+		a.NotNil(NewMemoryRegionReader(region))
+		a.NoError(region.Close())
+	}
+}
+
 func TestMmfFileCopy(t *testing.T) {
 	a := assert.New(t)
 	inFile, err := os.Open(testFile)
@@ -165,3 +194,449 @@ func ExampleMemoryRegion() {
 		panic("flush")
 	}
 }
+
+func TestMmfPopulate(t *testing.T) {
+	const (
+		offset = 67746
+	)
+	a := assert.New(t)
+	file, err := os.Open(testFile)
+	if !a.NoError(err) {
+		return
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if !a.NoError(err) {
+		return
+	}
+	size := int(stat.Size()) - offset
+	region, err := NewMemoryRegion(file, MEM_READ_ONLY|MEM_POPULATE, offset, size)
+	if !a.NoError(err) {
+		return
+	}
+	defer region.Close()
+	a.Equal(size, region.Size())
+	for i := 0; i < size; i++ {
+		if !a.Equal(byte(i+offset), region.Data()[i]) {
+			break
+		}
+	}
+	_, err = NewMemoryRegion(file, MEM_POPULATE, 0, size)
+	a.Error(err)
+}
+
+func TestMmfGzipRoundTrip(t *testing.T) {
+	a := assert.New(t)
+	payload := bytes.Repeat([]byte("go-ipc gzip region test "), 4096)
+	region, cleanup, err := newTempFileRegion(len(payload))
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	w, err := RegionGzipWriter(region)
+	if !a.NoError(err) {
+		return
+	}
+	n, err := w.Write(payload)
+	a.NoError(err)
+	a.Equal(len(payload), n)
+	if !a.NoError(w.Close()) {
+		return
+	}
+	r, size, err := RegionGzipReader(region)
+	if !a.NoError(err) {
+		return
+	}
+	defer r.Close()
+	a.Equal(int64(len(payload)), size)
+	actual, err := ioutil.ReadAll(r)
+	a.NoError(err)
+	a.Equal(payload, actual)
+}
+
+func TestMmfGzipOverflow(t *testing.T) {
+	a := assert.New(t)
+	payload := make([]byte, 65536)
+	rand.New(rand.NewSource(1)).Read(payload)
+	region, cleanup, err := newTempFileRegion(1024)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	w, err := RegionGzipWriter(region)
+	if !a.NoError(err) {
+		return
+	}
+	_, err = w.Write(payload)
+	if err == nil {
+		err = w.Close()
+	} else {
+		w.Close()
+	}
+	a.Equal(io.ErrShortWrite, err)
+	_, _, err = RegionGzipReader(region)
+	a.Error(err)
+}
+
+func newTempFileRegion(size int) (*MemoryRegion, func(), error) {
+	file, err := ioutil.TempFile("", "go-ipc-mmf")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		file.Close()
+		os.Remove(file.Name())
+	}
+	if err = file.Truncate(int64(size)); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	region, err := NewMemoryRegion(file, MEM_READWRITE, 0, size)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return region, func() {
+		region.Close()
+		cleanup()
+	}, nil
+}
+
+func TestFileMemoryObject(t *testing.T) {
+	const size = 1024
+	a := assert.New(t)
+	file, err := ioutil.TempFile("", "go-ipc-mmf")
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(file.Close())
+		os.Remove(file.Name())
+	}()
+	if !a.NoError(file.Truncate(size)) {
+		return
+	}
+	obj := NewFileMemoryObject(file)
+	// the size is taken from the file.
+	region, err := NewMemoryRegion(obj, MEM_READWRITE, 0, 0)
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(size, region.Size())
+	copy(region.Data()[size/2:], "go-ipc")
+	a.NoError(region.Flush(false))
+	a.NoError(region.Close())
+	// the file is still open and contains the data written via the region.
+	data := make([]byte, 6)
+	_, err = file.ReadAt(data, size/2)
+	a.NoError(err)
+	a.Equal("go-ipc", string(data))
+	_, err = file.WriteAt([]byte("shared"), 0)
+	if !a.NoError(err) {
+		return
+	}
+	region, err = NewMemoryRegion(obj, MEM_READ_ONLY, 0, 6)
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal("shared", string(region.Data()))
+	a.NoError(region.Close())
+}
+
+func TestMmfCloseErrorHandler(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(4096)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	var handledErr error
+	handledName := "-"
+	SetCloseErrorHandler(func(name string, err error) {
+		handledName, handledErr = name, err
+	})
+	defer SetCloseErrorHandler(nil)
+	// a region with broken data can't be unmapped.
+	data := region.memoryRegion.data
+	region.memoryRegion.data = data[1 : len(data)-1 : len(data)-1]
+	finalizeRegion(region.memoryRegion, "test")
+	a.Error(handledErr)
+	a.Equal("test", handledName)
+	region.memoryRegion.data = data
+	handledErr = nil
+	finalizeRegion(region.memoryRegion, "test")
+	a.NoError(handledErr)
+}
+
+func TestMmfRemap(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(512)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	expected := make([]byte, 512)
+	for i := range expected {
+		expected[i] = byte(rand.Int())
+	}
+	writer := NewMemoryRegionWriter(region)
+	if _, err = writer.Write(expected); !a.NoError(err) {
+		return
+	}
+	a.Error(region.Remap(0))
+	if !a.NoError(region.Remap(4096)) {
+		return
+	}
+	a.Equal(4096, region.Size())
+	data := region.Data()
+	if !a.Len(data, 4096) {
+		return
+	}
+	a.Equal(expected, data[:512])
+	a.Equal(make([]byte, 4096-512), data[512:])
+	// the writer uses the new mapping.
+	_, err = writer.Write([]byte{1, 2, 3})
+	a.NoError(err)
+	a.Equal([]byte{1, 2, 3}, region.Data()[512:515])
+	if !a.NoError(region.Remap(256)) {
+		return
+	}
+	a.Equal(expected[:256], region.Data())
+	a.NoError(region.Close())
+	a.Error(region.Remap(512))
+}
+
+type typedAccessTestStruct struct {
+	A int64
+	B [3]int32
+	C bool
+}
+
+func TestMmfTypedAccess(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(64)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	in := typedAccessTestStruct{A: -1, B: [3]int32{1, 2, 3}, C: true}
+	var out typedAccessTestStruct
+	if a.NoError(StoreAs(region, 16, in)) && a.NoError(AtAs(region, 16, &out)) {
+		a.Equal(in, out)
+	}
+	a.NoError(StoreAs(region, 0, &in))
+	slice := make([]int32, 4)
+	if a.NoError(AtAs(region, 8, slice)) {
+		a.Equal([]int32{1, 2, 3}, slice[:3])
+	}
+	a.Error(AtAs(region, 0, out))
+	a.Error(AtAs(region, 0, nil))
+	a.Error(StoreAs(region, 0, "string"))
+	a.Error(StoreAs(region, 0, map[int]int{}))
+	a.Error(AtAs(region, -1, &out))
+}
+
+func TestMmfTypedAccessOutOfBounds(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(64)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	size := int(unsafe.Sizeof(typedAccessTestStruct{}))
+	in := typedAccessTestStruct{A: 1}
+	var out typedAccessTestStruct
+	// the struct fits exactly at the end of the region.
+	a.NoError(StoreAs(region, 64-size, in))
+	a.NoError(AtAs(region, 64-size, &out))
+	// the struct straddles the end of the region.
+	a.Error(StoreAs(region, 64-size+1, in))
+	a.Error(AtAs(region, 64-size+1, &out))
+	a.Error(StoreAs(region, 64, in))
+	a.Error(AtAs(region, 1<<30, &out))
+}
+
+func TestMmfWriterSeek(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(16)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	w := NewMemoryRegionWriter(region)
+	n, err := fmt.Fprintf(w, "%d", 12345)
+	a.NoError(err)
+	a.Equal(5, n)
+	pos, err := w.Seek(-2, io.SeekCurrent)
+	a.NoError(err)
+	a.Equal(int64(3), pos)
+	_, err = w.Write([]byte("ab"))
+	a.NoError(err)
+	a.Equal([]byte("123ab"), region.Data()[:5])
+	// writing past the end returns the truncated count.
+	pos, err = w.Seek(-4, io.SeekEnd)
+	a.NoError(err)
+	a.Equal(int64(12), pos)
+	n, err = w.Write([]byte("abcdef"))
+	a.Equal(io.EOF, err)
+	a.Equal(4, n)
+	a.Equal([]byte("abcd"), region.Data()[12:])
+	n, err = w.Write([]byte("x"))
+	a.Equal(io.EOF, err)
+	a.Equal(0, n)
+	pos, err = w.Seek(100, io.SeekStart)
+	a.NoError(err)
+	a.Equal(int64(100), pos)
+	n, err = w.Write([]byte("x"))
+	a.Equal(io.EOF, err)
+	a.Equal(0, n)
+	_, err = w.Seek(-1, io.SeekStart)
+	a.Error(err)
+	_, err = w.WriteAt([]byte("x"), -1)
+	a.Error(err)
+}
+
+func TestMmfReaderWriterToBuffer(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(1024)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	expected := make([]byte, 1024)
+	for i := range expected {
+		expected[i] = byte(rand.Int())
+	}
+	w := NewMemoryRegionWriter(region)
+	// the source is larger, than the region, so only the first Size() bytes are read.
+	n, err := w.ReadFrom(io.MultiReader(bytes.NewReader(expected), bytes.NewReader(expected)))
+	a.NoError(err)
+	a.Equal(int64(len(expected)), n)
+	a.Equal(expected, region.Data())
+	n, err = w.ReadFrom(bytes.NewReader(expected))
+	a.NoError(err)
+	a.Equal(int64(0), n)
+	var buff bytes.Buffer
+	n, err = NewMemoryRegionReader(region).WriteTo(&buff)
+	a.NoError(err)
+	a.Equal(int64(len(expected)), n)
+	a.Equal(expected, buff.Bytes())
+}
+
+func TestMmfReaderWriterToFile(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(4096)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	for i := range region.Data() {
+		region.Data()[i] = byte(i)
+	}
+	file, err := ioutil.TempFile("", "go-ipc-mmf")
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(file.Close())
+		a.NoError(os.Remove(file.Name()))
+	}()
+	n, err := io.Copy(file, NewMemoryRegionReader(region))
+	a.NoError(err)
+	a.Equal(int64(4096), n)
+	if _, err = file.Seek(0, io.SeekStart); !a.NoError(err) {
+		return
+	}
+	region2, cleanup2, err := newTempFileRegion(4096)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup2()
+	w := NewMemoryRegionWriter(region2)
+	// a partial write at an offset.
+	if _, err = w.Seek(96, io.SeekStart); !a.NoError(err) {
+		return
+	}
+	n, err = w.ReadFrom(file)
+	a.NoError(err)
+	a.Equal(int64(4000), n)
+	a.Equal(region.Data()[:4000], region2.Data()[96:])
+}
+
+func TestMmfFlushRange(t *testing.T) {
+	const offset = 100
+	a := assert.New(t)
+	file, err := ioutil.TempFile("", "go-ipc-mmf")
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+	pageSize := os.Getpagesize()
+	fileSize := pageSize * 4
+	if !a.NoError(file.Truncate(int64(fileSize))) {
+		return
+	}
+	size := fileSize - offset
+	region, err := NewMemoryRegion(file, MEM_READWRITE, offset, size)
+	if !a.NoError(err) {
+		return
+	}
+	defer region.Close()
+	copy(region.Data()[size-3:], []byte{1, 2, 3})
+	a.NoError(region.FlushRange(int64(size-3), 3, false))
+	a.NoError(region.FlushRange(int64(size-pageSize-10), 20, true))
+	a.NoError(region.FlushRange(0, size, false))
+	a.NoError(region.FlushRange(int64(size), 0, false))
+	data := make([]byte, 3)
+	if _, err = file.ReadAt(data, int64(fileSize-3)); a.NoError(err) {
+		a.Equal([]byte{1, 2, 3}, data)
+	}
+	a.Error(region.FlushRange(int64(size-3), 4, false))
+	a.Error(region.FlushRange(-1, 1, false))
+	a.Error(region.FlushRange(0, -1, false))
+}
+
+func TestMmfPin(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(os.Getpagesize() * 2)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	err = region.Pin()
+	if err == ErrMemLockLimit {
+		t.Skip("the limit of locked memory is too low")
+	}
+	if !a.NoError(err) {
+		return
+	}
+	data := region.Data()
+	data[0], data[len(data)-1] = 1, 2
+	a.Equal(byte(1), data[0])
+	a.NoError(region.Unpin())
+}
+
+func TestMmfAdvise(t *testing.T) {
+	const offset = 100
+	a := assert.New(t)
+	pageSize := os.Getpagesize()
+	region, cleanup, err := newTempFileRegion(pageSize * 4)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	if runtime.GOOS == "windows" {
+		a.Equal(ErrNotSupported, region.Advise(ADV_SEQUENTIAL))
+		return
+	}
+	a.NoError(region.Advise(ADV_SEQUENTIAL))
+	a.NoError(region.AdviseRange(offset, pageSize, ADV_WILLNEED))
+	a.NoError(region.AdviseRange(int64(region.Size()), 0, ADV_RANDOM))
+	a.NoError(region.Advise(ADV_NORMAL))
+	a.Error(region.AdviseRange(offset, region.Size(), ADV_NORMAL))
+	a.Error(region.AdviseRange(-1, 1, ADV_NORMAL))
+	a.Error(region.Advise(ADV_DONTNEED + 1))
+}
@@ -0,0 +1,192 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	publisherHdrSize     = int(unsafe.Sizeof(publisherHdr{}))
+	publisherSlotHdrSize = int(unsafe.Sizeof(publisherSlotHdr{}))
+)
+
+type publisherHdr struct {
+	current    uint32
+	slots      uint32
+	slotSize   uint64
+	generation uint64
+}
+
+type publisherSlotHdr struct {
+	readers int32
+	_       int32
+	gen     uint64
+	size    uint64
+}
+
+// publication is a view of the publisher data placed in a memory region.
+type publication struct {
+	region    *MemoryRegion
+	hdr       *publisherHdr
+	slots     unsafe.Pointer
+	slotTotal int
+}
+
+// RegionPublisher publishes new versions (generations) of data to readers in other processes.
+// It keeps several versions in slots of a memory region. A new version is written
+// into a free slot, after which the current slot index is atomically switched to it,
+// so readers never see partially written data. A slot is reused only
+// when no reader references the version it contains.
+// There must be only one publisher for a region.
+// If a reader process crashes while reading, its slot is never reclaimed.
+type RegionPublisher struct {
+	publication
+}
+
+// RegionSubscriber reads versions of data published by a RegionPublisher.
+type RegionSubscriber struct {
+	publication
+}
+
+// RegionPublisherSize returns the size of a memory region needed to store
+// given number of slots of the given size.
+func RegionPublisherSize(slots, slotSize int) int {
+	return publisherHdrSize + slots*publisherSlotTotalSize(slotSize)
+}
+
+// NewRegionPublisher initializes publisher data in the region. All the data in the region is overwritten.
+//	region - memory region. it must be at least RegionPublisherSize(slots, slotSize) bytes long.
+//	slots - number of slots. must be at least 2.
+//	slotSize - maximum size of the published data.
+func NewRegionPublisher(region *MemoryRegion, slots, slotSize int) (*RegionPublisher, error) {
+	if slots < 2 || slotSize <= 0 {
+		return nil, errors.New("there must be at least 2 slots of positive size")
+	}
+	if region.Size() < RegionPublisherSize(slots, slotSize) {
+		return nil, errors.Errorf("the region is too small. need %d bytes", RegionPublisherSize(slots, slotSize))
+	}
+	result := &RegionPublisher{publication: newPublication(region)}
+	result.hdr.slots = uint32(slots)
+	result.hdr.slotSize = uint64(slotSize)
+	result.slotTotal = publisherSlotTotalSize(slotSize)
+	for i := 0; i < slots; i++ {
+		slot := result.slotAt(uint32(i))
+		atomic.StoreInt32(&slot.readers, 0)
+		slot.gen, slot.size = 0, 0
+	}
+	atomic.StoreUint64(&result.hdr.generation, 0)
+	atomic.StoreUint32(&result.hdr.current, 0)
+	return result, nil
+}
+
+// NewRegionSubscriber opens publisher data, which was initialized in the region by NewRegionPublisher.
+// The region must be writable, as readers mark the slots they use.
+func NewRegionSubscriber(region *MemoryRegion) (*RegionSubscriber, error) {
+	if region.Size() < publisherHdrSize {
+		return nil, errors.New("the region is too small")
+	}
+	result := &RegionSubscriber{publication: newPublication(region)}
+	slots, slotSize := int(result.hdr.slots), int(result.hdr.slotSize)
+	if slots < 2 || slotSize <= 0 || region.Size() < RegionPublisherSize(slots, slotSize) {
+		return nil, errors.New("the region does not contain publisher data")
+	}
+	result.slotTotal = publisherSlotTotalSize(slotSize)
+	return result, nil
+}
+
+// Publish writes data into a free slot and makes it current. It returns the generation of the data.
+// If all the slots except the current one are used by readers, it waits for one of them to be released.
+func (p *RegionPublisher) Publish(data []byte) (uint64, error) {
+	if len(data) > int(p.hdr.slotSize) {
+		return 0, errors.Errorf("the data of %d bytes does not fit into a slot of %d bytes", len(data), p.hdr.slotSize)
+	}
+	idx := p.freeSlot()
+	slot := p.slotAt(idx)
+	gen := atomic.LoadUint64(&p.hdr.generation) + 1
+	copy(p.slotData(slot), data)
+	slot.size = uint64(len(data))
+	slot.gen = gen
+	atomic.StoreUint64(&p.hdr.generation, gen)
+	atomic.StoreUint32(&p.hdr.current, idx)
+	return gen, nil
+}
+
+// freeSlot returns an index of a slot, which is not current and is not used by readers.
+func (p *RegionPublisher) freeSlot() uint32 {
+	for {
+		current := atomic.LoadUint32(&p.hdr.current)
+		for i := uint32(0); i < p.hdr.slots; i++ {
+			if i != current && atomic.LoadInt32(&p.slotAt(i).readers) == 0 {
+				return i
+			}
+		}
+		runtime.Gosched()
+	}
+}
+
+// View calls f with the current version of the data and its generation.
+// The data must not be used after f returns, as the slot can be reused by the publisher.
+// If nothing has been published yet, the generation is 0 and the data is empty.
+func (s *RegionSubscriber) View(f func(gen uint64, data []byte)) {
+	slot := s.acquire()
+	f(slot.gen, s.slotData(slot)[:slot.size])
+	atomic.AddInt32(&slot.readers, -1)
+}
+
+// Load returns a copy of the current version of the data and its generation.
+func (s *RegionSubscriber) Load() (uint64, []byte) {
+	var result []byte
+	var gen uint64
+	s.View(func(g uint64, data []byte) {
+		gen = g
+		result = make([]byte, len(data))
+		copy(result, data)
+	})
+	return gen, result
+}
+
+// acquire references current slot, so that the publisher can't reuse it.
+func (s *RegionSubscriber) acquire() *publisherSlotHdr {
+	for {
+		current := atomic.LoadUint32(&s.hdr.current)
+		slot := s.slotAt(current)
+		atomic.AddInt32(&slot.readers, 1)
+		// the publisher could have started writing into the slot before we referenced it.
+		// this is possible only if it is not current anymore.
+		if atomic.LoadUint32(&s.hdr.current) == current {
+			return slot
+		}
+		atomic.AddInt32(&slot.readers, -1)
+	}
+}
+
+func newPublication(region *MemoryRegion) publication {
+	raw := allocator.ByteSliceData(region.Data())
+	return publication{
+		region: region,
+		hdr:    (*publisherHdr)(raw),
+		slots:  allocator.AdvancePointer(raw, uintptr(publisherHdrSize)),
+	}
+}
+
+func (p *publication) slotAt(idx uint32) *publisherSlotHdr {
+	return (*publisherSlotHdr)(allocator.AdvancePointer(p.slots, uintptr(idx)*uintptr(p.slotTotal)))
+}
+
+func (p *publication) slotData(slot *publisherSlotHdr) []byte {
+	size := int(p.hdr.slotSize)
+	raw := allocator.AdvancePointer(unsafe.Pointer(slot), uintptr(publisherSlotHdrSize))
+	return allocator.ByteSliceFromUnsafePointer(raw, size, size)
+}
+
+// publisherSlotTotalSize returns the size of a slot with its header aligned to 8 bytes.
+func publisherSlotTotalSize(slotSize int) int {
+	return (publisherSlotHdrSize + slotSize + 7) &^ 7
+}
@@ -0,0 +1,88 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegionPublisher(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(RegionPublisherSize(2, 16))
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	_, err = NewRegionPublisher(region, 1, 16)
+	a.Error(err)
+	_, err = NewRegionPublisher(region, 2, 17)
+	a.Error(err)
+	p, err := NewRegionPublisher(region, 2, 16)
+	if !a.NoError(err) {
+		return
+	}
+	s, err := NewRegionSubscriber(region)
+	if !a.NoError(err) {
+		return
+	}
+	gen, data := s.Load()
+	a.Equal(uint64(0), gen)
+	a.Len(data, 0)
+	gen, err = p.Publish([]byte("first"))
+	a.NoError(err)
+	a.Equal(uint64(1), gen)
+	gen, data = s.Load()
+	a.Equal(uint64(1), gen)
+	a.Equal([]byte("first"), data)
+	_, err = p.Publish(make([]byte, 17))
+	a.Error(err)
+	gen, err = p.Publish([]byte("second"))
+	a.NoError(err)
+	a.Equal(uint64(2), gen)
+	gen, data = s.Load()
+	a.Equal(uint64(2), gen)
+	a.Equal([]byte("second"), data)
+}
+
+func TestRegionPublisherWaitsForReaders(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(RegionPublisherSize(2, 16))
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	p, err := NewRegionPublisher(region, 2, 16)
+	if !a.NoError(err) {
+		return
+	}
+	s, err := NewRegionSubscriber(region)
+	if !a.NoError(err) {
+		return
+	}
+	_, err = p.Publish([]byte("first"))
+	a.NoError(err)
+	published := make(chan uint64)
+	s.View(func(gen uint64, data []byte) {
+		// the only free slot is the one, which was current before 'first'.
+		// publishing 'second' makes the slot with 'first' not current, but it is still referenced.
+		go func() {
+			p.Publish([]byte("second"))
+			gen, _ := p.Publish([]byte("third"))
+			published <- gen
+		}()
+		select {
+		case <-published:
+			t.Errorf("the slot was reused while being read")
+		case <-time.After(time.Millisecond * 100):
+		}
+		a.Equal(uint64(1), gen)
+		a.Equal([]byte("first"), data)
+	})
+	a.Equal(uint64(3), <-published)
+	gen, data := s.Load()
+	a.Equal(uint64(3), gen)
+	a.Equal([]byte("third"), data)
+}
@@ -5,16 +5,20 @@ package mmf
 import (
 	"bytes"
 	"io"
+
+	"github.com/pkg/errors"
 )
 
 // MemoryRegionReader is a reader for safe operations over a shared memory region.
 // It holds a reference to the region, so the former can't be gc'ed.
+// Along with the methods of bytes.Reader it implements io.WriterTo.
 type MemoryRegionReader struct {
 	region *MemoryRegion
 	*bytes.Reader
 }
 
 // NewMemoryRegionReader creates a new reader for the given region.
+// Any region can be read, so the reader is valid for all the modes.
 func NewMemoryRegionReader(region *MemoryRegion) *MemoryRegionReader {
 	return &MemoryRegionReader{
 		region: region,
@@ -22,27 +26,52 @@ func NewMemoryRegionReader(region *MemoryRegion) *MemoryRegionReader {
 	}
 }
 
+// WriteTo is to implement io.WriterTo.
+// It writes the unread data of the region into w and returns the number of bytes written.
+// The region can't be gc'ed, until the operation is finished.
+func (r *MemoryRegionReader) WriteTo(w io.Writer) (n int64, err error) {
+	n, err = r.Reader.WriteTo(w)
+	UseMemoryRegion(r.region)
+	return
+}
+
 // MemoryRegionWriter is a writer for safe operations over a shared memory region.
 // It holds a reference to the region, so the former can't be gc'ed.
+// It implements io.Writer, io.WriterAt, io.Seeker, and io.ReaderFrom.
 type MemoryRegionWriter struct {
 	region *MemoryRegion
 	pos    int64
 }
 
 // NewMemoryRegionWriter creates a new writer for the given region.
+// It panics, if the region was mapped read-only, as writing into it would crash the process.
+// Use NewMemoryRegionWriterChecked to get an error instead.
 func NewMemoryRegionWriter(region *MemoryRegion) *MemoryRegionWriter {
-	return &MemoryRegionWriter{region: region}
+	w, err := NewMemoryRegionWriterChecked(region)
+	if err != nil {
+		panic(err)
+	}
+	return w
+}
+
+// NewMemoryRegionWriterChecked creates a new writer for the given region.
+// It returns an error, if the region was mapped read-only, as writing into it would crash the process.
+func NewMemoryRegionWriterChecked(region *MemoryRegion) (*MemoryRegionWriter, error) {
+	if region.readOnly() {
+		return nil, errors.New("can't write into a region mapped with a read-only mode")
+	}
+	return &MemoryRegionWriter{region: region}, nil
 }
 
 // WriteAt is to implement io.WriterAt.
+// If the data doesn't fit into the region, it writes as much as possible and returns io.EOF.
 func (w *MemoryRegionWriter) WriteAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
 	data := w.region.Data()
-	n = len(data) - int(off)
-	if n > 0 {
-		if n > len(p) {
-			n = len(p)
-		}
-		copy(data[off:], p[:n])
+	if off < int64(len(data)) {
+		n = copy(data[off:], p)
 	}
 	if n < len(p) {
 		err = io.EOF
@@ -51,8 +80,54 @@ func (w *MemoryRegionWriter) WriteAt(p []byte, off int64) (n int, err error) {
 }
 
 // Write is to implement io.Writer.
+// It writes at the current position and advances it.
+// If the data doesn't fit into the region, it writes as much as possible and returns io.EOF.
 func (w *MemoryRegionWriter) Write(p []byte) (n int, err error) {
 	n, err = w.WriteAt(p, w.pos)
 	w.pos += int64(n)
 	return n, err
 }
+
+// Seek is to implement io.Seeker.
+// It sets the position for the next Write. The position may be beyond the end of the region,
+// in this case the next Write returns io.EOF.
+func (w *MemoryRegionWriter) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = w.pos + offset
+	case io.SeekEnd:
+		pos = int64(len(w.region.Data())) + offset
+	default:
+		return 0, errors.Errorf("invalid whence %d", whence)
+	}
+	if pos < 0 {
+		return 0, errors.New("negative position")
+	}
+	w.pos = pos
+	return pos, nil
+}
+
+// ReadFrom is to implement io.ReaderFrom.
+// It reads data from r into the region starting at the current position, until r returns io.EOF,
+// or the end of the region is reached, and advances the position. io.EOF is not returned as an error.
+// The region can't be gc'ed, until the operation is finished.
+func (w *MemoryRegionWriter) ReadFrom(r io.Reader) (n int64, err error) {
+	data := w.region.Data()
+	for w.pos < int64(len(data)) {
+		var read int
+		read, err = r.Read(data[w.pos:])
+		w.pos += int64(read)
+		n += int64(read)
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			break
+		}
+	}
+	UseMemoryRegion(w.region)
+	return
+}
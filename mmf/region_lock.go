@@ -0,0 +1,29 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// regionLock is a spin lock, which is placed in a memory region together with the data it guards.
+// It is used by shared data structures, like SharedLRU and SharedHeap, as the sync package can't be used here.
+// The lock is not robust: the owner is not recorded, so, if a process crashes while holding the lock,
+// it is never released, and all the users of the data structure wait for it forever.
+type regionLock uint32
+
+// init unlocks the lock. It must be called, when a data structure is initialized in a new region.
+func (l *regionLock) init() {
+	atomic.StoreUint32((*uint32)(l), 0)
+}
+
+func (l *regionLock) lock() {
+	for !atomic.CompareAndSwapUint32((*uint32)(l), 0, 1) {
+		runtime.Gosched()
+	}
+}
+
+func (l *regionLock) unlock() {
+	atomic.StoreUint32((*uint32)(l), 0)
+}
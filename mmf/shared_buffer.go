@@ -0,0 +1,78 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"sync/atomic"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	sharedBufferHdrSize = 8
+)
+
+// SharedBuffer is an append-only byte buffer placed in a memory region.
+// The length of the written data is stored at the beginning of the region,
+// so several writers, which may be in different processes, can append to the same buffer.
+// A writer reserves space by atomically bumping the length and then copies its data,
+// so concurrent appends never overlap.
+// It holds a reference to the region, so the latter can't be gc'ed.
+type SharedBuffer struct {
+	region *MemoryRegion
+	length *uint64
+	data   []byte
+}
+
+// NewSharedBuffer opens a buffer in the region. The region's memory is not reset,
+// so all the processes see the same buffer. A zeroed region, like a new file or a new shm object, is an empty buffer.
+//
+//	region - memory region. it must be writable to append data.
+func NewSharedBuffer(region *MemoryRegion) (*SharedBuffer, error) {
+	if region.Size() < sharedBufferHdrSize {
+		return nil, errors.Errorf("the region is too small. need at least %d bytes", sharedBufferHdrSize)
+	}
+	raw := region.Data()
+	result := &SharedBuffer{
+		region: region,
+		length: (*uint64)(allocator.ByteSliceData(raw)),
+		data:   raw[sharedBufferHdrSize:],
+	}
+	if l := atomic.LoadUint64(result.length); l > uint64(len(result.data)) {
+		return nil, errors.Errorf("the region does not contain a buffer: invalid length %d", l)
+	}
+	return result, nil
+}
+
+// Append copies p to the end of the buffer. It returns len(p) on success.
+// If p does not fit into the remaining space, nothing is written, and an error is returned.
+func (b *SharedBuffer) Append(p []byte) (int, error) {
+	for {
+		old := atomic.LoadUint64(b.length)
+		if uint64(len(p)) > uint64(len(b.data))-old {
+			return 0, errors.Errorf("the buffer is full: %d bytes requested, %d bytes available", len(p), uint64(len(b.data))-old)
+		}
+		if atomic.CompareAndSwapUint64(b.length, old, old+uint64(len(p))) {
+			return copy(b.data[old:], p), nil
+		}
+	}
+}
+
+// Bytes returns the written part of the buffer. It is the region's memory, not a copy.
+// The space is reserved before the data is copied,
+// so the tail may contain zeroes of the appends, which have not finished yet.
+func (b *SharedBuffer) Bytes() []byte {
+	return b.data[:atomic.LoadUint64(b.length)]
+}
+
+// Len returns the number of written bytes.
+func (b *SharedBuffer) Len() int {
+	return int(atomic.LoadUint64(b.length))
+}
+
+// Cap returns the maximum number of bytes, which the buffer can hold.
+func (b *SharedBuffer) Cap() int {
+	return len(b.data)
+}
@@ -0,0 +1,167 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	testutil "github.com/nxgtw/go-ipc/internal/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// bufferRecord must be in sync with the one used in the test program.
+func bufferRecord(id byte) []byte {
+	return []byte{id, id, id, id}
+}
+
+func argsForBufferAppendCommand(fileName string, id, n int) []string {
+	return argsForMmfCommand("append", fileName, strconv.Itoa(id), strconv.Itoa(n))
+}
+
+// checkBufferRecords checks, that the data consists of whole records and returns the number of records of each writer.
+func checkBufferRecords(a *assert.Assertions, data []byte) map[byte]int {
+	result := make(map[byte]int)
+	if !a.Equal(0, len(data)%4) {
+		return result
+	}
+	for i := 0; i < len(data); i += 4 {
+		if !a.Equal(bufferRecord(data[i]), data[i:i+4]) {
+			return result
+		}
+		result[data[i]]++
+	}
+	return result
+}
+
+func TestSharedBuffer(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(sharedBufferHdrSize + 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	buf, err := NewSharedBuffer(region)
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(16, buf.Cap())
+	a.Empty(buf.Bytes())
+	n, err := buf.Append([]byte("hello, "))
+	a.NoError(err)
+	a.Equal(7, n)
+	n, err = buf.Append([]byte("world"))
+	a.NoError(err)
+	a.Equal(5, n)
+	a.Equal([]byte("hello, world"), buf.Bytes())
+	n, err = buf.Append([]byte("!!!!!"))
+	a.Error(err)
+	a.Equal(0, n)
+	a.Equal(12, buf.Len())
+	n, err = buf.Append([]byte("!!!!"))
+	a.NoError(err)
+	a.Equal(4, n)
+	a.Equal(buf.Cap(), buf.Len())
+
+	// another instance sees the same data.
+	buf2, err := NewSharedBuffer(region)
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal([]byte("hello, world!!!!"), buf2.Bytes())
+}
+
+func TestSharedBufferInvalidRegion(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(sharedBufferHdrSize + 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	copy(region.Data(), []byte{0xff, 0xff})
+	_, err = NewSharedBuffer(region)
+	a.Error(err)
+}
+
+func TestSharedBufferConcurrentAppend(t *testing.T) {
+	const writers, n = 8, 1000
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(sharedBufferHdrSize + writers*n*4)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	buf, err := NewSharedBuffer(region)
+	if !a.NoError(err) {
+		return
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(id byte) {
+			defer wg.Done()
+			for j := 0; j < n; j++ {
+				buf.Append(bufferRecord(id))
+			}
+		}(byte(i + 1))
+	}
+	wg.Wait()
+	counts := checkBufferRecords(a, buf.Bytes())
+	for i := 0; i < writers; i++ {
+		a.Equal(n, counts[byte(i+1)])
+	}
+	_, err = buf.Append([]byte{0})
+	a.Error(err)
+}
+
+func TestSharedBufferAnotherProcess(t *testing.T) {
+	const n = 100000
+	a := assert.New(t)
+	file, err := ioutil.TempFile("", "go-ipc-buffer")
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+	size := sharedBufferHdrSize + 2*n*4
+	if !a.NoError(file.Truncate(int64(size))) {
+		return
+	}
+	region, err := NewMemoryRegion(file, MEM_READWRITE, 0, size)
+	if !a.NoError(err) {
+		return
+	}
+	defer region.Close()
+	buf, err := NewSharedBuffer(region)
+	if !a.NoError(err) {
+		return
+	}
+	ch := testutil.RunTestAppAsync(argsForBufferAppendCommand(file.Name(), 2, n), nil)
+	record := bufferRecord(1)
+	for i := 0; i < n; i++ {
+		if _, err = buf.Append(record); !a.NoError(err) {
+			return
+		}
+	}
+	res, ok := testutil.WaitForAppResultChan(ch, time.Minute)
+	if !a.True(ok, "timeout") {
+		return
+	}
+	if res.Err != nil {
+		t.Errorf("app error: %v. the output is %q", res.Err, res.Output)
+		return
+	}
+	a.Equal(buf.Cap(), buf.Len())
+	counts := checkBufferRecords(a, buf.Bytes())
+	a.Equal(n, counts[1])
+	a.Equal(n, counts[2])
+	a.False(bytes.Contains(buf.Bytes(), []byte{0}))
+}
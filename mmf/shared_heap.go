@@ -0,0 +1,201 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	heapHdrSize = int(unsafe.Sizeof(heapHdr{}))
+)
+
+type heapHdr struct {
+	mu       regionLock
+	capacity int32
+	elemSize int32
+	count    int32
+}
+
+// SharedHeap is a fixed-capacity binary heap of fixed-size elements placed in a memory region.
+// The element at the top is the least one according to the comparator.
+// All operations are guarded by a spin lock placed in the region,
+// so the heap can be used by several processes simultaneously.
+// As the comparator can't be stored in shared memory, each process supplies its own one,
+// and all of them must define the same order. Otherwise the heap will be corrupted.
+// The lock is not robust, see the package documentation.
+// It holds a reference to the region, so the latter can't be gc'ed.
+// The heap becomes invalid, if the region is closed or remapped. Use OpenSharedHeap to open it again.
+type SharedHeap struct {
+	region *MemoryRegion
+	hdr    *heapHdr
+	elems  unsafe.Pointer
+	less   func(a, b []byte) bool
+	tmp    []byte
+}
+
+// SharedHeapSize returns the size of a memory region needed to store a heap with given parameters.
+func SharedHeapSize(elemSize, capacity int) int {
+	return heapHdrSize + elemSize*capacity
+}
+
+// NewSharedHeap initializes a new heap in the given region. All the data in the region is overwritten.
+//	region - memory region. it must be at least SharedHeapSize(elemSize, capacity) bytes long.
+//	elemSize - the size of an element.
+//	capacity - maximum number of elements in the heap.
+//	less - element comparator. it must be consistent across all processes using the heap.
+func NewSharedHeap(region *MemoryRegion, elemSize, capacity int, less func(a, b []byte) bool) (*SharedHeap, error) {
+	if elemSize <= 0 || capacity <= 0 {
+		return nil, errors.New("invalid heap parameters")
+	}
+	if less == nil {
+		return nil, errors.New("comparator must not be nil")
+	}
+	if region.Size() < SharedHeapSize(elemSize, capacity) {
+		return nil, errors.Errorf("the region is too small. need %d bytes", SharedHeapSize(elemSize, capacity))
+	}
+	result := newSharedHeap(region, less)
+	result.hdr.capacity, result.hdr.elemSize, result.hdr.count = int32(capacity), int32(elemSize), 0
+	result.tmp = make([]byte, elemSize)
+	result.hdr.mu.init()
+	return result, nil
+}
+
+// OpenSharedHeap opens a heap, which was previously initialized in the given region with NewSharedHeap.
+//	less - element comparator. it must be consistent with the one used by other processes.
+func OpenSharedHeap(region *MemoryRegion, less func(a, b []byte) bool) (*SharedHeap, error) {
+	if less == nil {
+		return nil, errors.New("comparator must not be nil")
+	}
+	if region.Size() < heapHdrSize {
+		return nil, errors.New("the region is too small")
+	}
+	result := newSharedHeap(region, less)
+	elemSize, capacity := int(result.hdr.elemSize), int(result.hdr.capacity)
+	if elemSize <= 0 || capacity <= 0 || region.Size() < SharedHeapSize(elemSize, capacity) {
+		return nil, errors.New("the region does not contain a valid heap")
+	}
+	result.tmp = make([]byte, elemSize)
+	return result, nil
+}
+
+func newSharedHeap(region *MemoryRegion, less func(a, b []byte) bool) *SharedHeap {
+	raw := allocator.ByteSliceData(region.Data())
+	return &SharedHeap{
+		region: region,
+		hdr:    (*heapHdr)(raw),
+		elems:  allocator.AdvancePointer(raw, uintptr(heapHdrSize)),
+		less:   less,
+	}
+}
+
+// Push adds an element to the heap. The element must be exactly ElemSize() bytes long.
+func (h *SharedHeap) Push(elem []byte) error {
+	if len(elem) != len(h.tmp) {
+		return errors.Errorf("invalid element size %d, must be %d", len(elem), len(h.tmp))
+	}
+	h.hdr.mu.lock()
+	defer h.hdr.mu.unlock()
+	if h.hdr.count == h.hdr.capacity {
+		return errors.New("the heap is full")
+	}
+	idx := int(h.hdr.count)
+	copy(h.at(idx), elem)
+	h.hdr.count++
+	h.up(idx)
+	return nil
+}
+
+// Pop removes the least element from the heap and returns its copy.
+// It returns false, if the heap is empty.
+func (h *SharedHeap) Pop() ([]byte, bool) {
+	h.hdr.mu.lock()
+	defer h.hdr.mu.unlock()
+	if h.hdr.count == 0 {
+		return nil, false
+	}
+	result := make([]byte, len(h.tmp))
+	copy(result, h.at(0))
+	h.hdr.count--
+	last := int(h.hdr.count)
+	if last > 0 {
+		copy(h.at(0), h.at(last))
+		h.down(0)
+	}
+	return result, true
+}
+
+// Peek returns a copy of the least element without removing it.
+// It returns false, if the heap is empty.
+func (h *SharedHeap) Peek() ([]byte, bool) {
+	h.hdr.mu.lock()
+	defer h.hdr.mu.unlock()
+	if h.hdr.count == 0 {
+		return nil, false
+	}
+	result := make([]byte, len(h.tmp))
+	copy(result, h.at(0))
+	return result, true
+}
+
+// Len returns the number of elements in the heap.
+func (h *SharedHeap) Len() int {
+	return int(atomic.LoadInt32(&h.hdr.count))
+}
+
+// Cap returns the capacity of the heap.
+func (h *SharedHeap) Cap() int {
+	return int(h.hdr.capacity)
+}
+
+// ElemSize returns the size of an element.
+func (h *SharedHeap) ElemSize() int {
+	return int(h.hdr.elemSize)
+}
+
+func (h *SharedHeap) up(idx int) {
+	for idx > 0 {
+		parent := (idx - 1) / 2
+		if !h.less(h.at(idx), h.at(parent)) {
+			break
+		}
+		h.swap(idx, parent)
+		idx = parent
+	}
+}
+
+func (h *SharedHeap) down(idx int) {
+	count := int(h.hdr.count)
+	for {
+		least := idx
+		if left := 2*idx + 1; left < count && h.less(h.at(left), h.at(least)) {
+			least = left
+		}
+		if right := 2*idx + 2; right < count && h.less(h.at(right), h.at(least)) {
+			least = right
+		}
+		if least == idx {
+			return
+		}
+		h.swap(idx, least)
+		idx = least
+	}
+}
+
+func (h *SharedHeap) swap(i, j int) {
+	copy(h.tmp, h.at(i))
+	copy(h.at(i), h.at(j))
+	copy(h.at(j), h.tmp)
+}
+
+func (h *SharedHeap) at(idx int) []byte {
+	size := len(h.tmp)
+	raw := allocator.AdvancePointer(h.elems, uintptr(idx*size))
+	return allocator.ByteSliceFromUnsafePointer(raw, size, size)
+}
+
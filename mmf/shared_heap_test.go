@@ -0,0 +1,97 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func heapUint64Less(a, b []byte) bool {
+	return bytes.Compare(a, b) < 0
+}
+
+func heapUint64(v uint64) []byte {
+	result := make([]byte, 8)
+	binary.BigEndian.PutUint64(result, v)
+	return result
+}
+
+func TestSharedHeap(t *testing.T) {
+	const count = 100
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(SharedHeapSize(8, count))
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	h, err := NewSharedHeap(region, 8, count, heapUint64Less)
+	if !a.NoError(err) {
+		return
+	}
+	_, ok := h.Pop()
+	a.False(ok)
+	_, ok = h.Peek()
+	a.False(ok)
+	a.Error(h.Push([]byte{1}))
+	for _, value := range rand.Perm(count) {
+		a.NoError(h.Push(heapUint64(uint64(value))))
+	}
+	a.Error(h.Push(heapUint64(0)))
+	a.Equal(count, h.Len())
+	top, ok := h.Peek()
+	if a.True(ok) {
+		a.Equal(heapUint64(0), top)
+	}
+	h2, err := OpenSharedHeap(region, heapUint64Less)
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(count, h2.Cap())
+	a.Equal(8, h2.ElemSize())
+	for i := 0; i < count; i++ {
+		value, ok := h2.Pop()
+		if !a.True(ok) {
+			return
+		}
+		a.Equal(heapUint64(uint64(i)), value)
+	}
+	a.Equal(0, h.Len())
+}
+
+func TestSharedHeapRemap(t *testing.T) {
+	const count = 16
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(512)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	h, err := NewSharedHeap(region, 8, count, heapUint64Less)
+	if !a.NoError(err) {
+		return
+	}
+	for _, value := range rand.Perm(count) {
+		a.NoError(h.Push(heapUint64(uint64(value))))
+	}
+	if !a.NoError(region.Remap(4096)) {
+		return
+	}
+	// the heap must be opened again after the region has been remapped.
+	h, err = OpenSharedHeap(region, heapUint64Less)
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(count, h.Len())
+	for i := 0; i < count; i++ {
+		value, ok := h.Pop()
+		if !a.True(ok) {
+			return
+		}
+		a.Equal(heapUint64(uint64(i)), value)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	sharedInt64Size = int(unsafe.Sizeof(int64(0)))
+)
+
+// SharedInt64 is an int64 value placed in a memory region.
+// All operations are atomic, so the value can be used by several processes simultaneously,
+// for example, as a counter. It holds a reference to the region, so the latter can't be gc'ed.
+// The value becomes invalid, if the region is closed or remapped.
+type SharedInt64 struct {
+	region *MemoryRegion
+	value  *int64
+}
+
+// NewSharedInt64 returns a value placed in the region at the given offset.
+// The value is not initialized, so that several processes can attach to the same value.
+// The address of the value must be 8-byte aligned, as required by atomic operations.
+// As regions are mapped at page boundaries, it is enough for the object offset to be a multiple of 8.
+func NewSharedInt64(region *MemoryRegion, offset int) (*SharedInt64, error) {
+	data := region.Data()
+	if err := checkRegionBounds(len(data), offset, sharedInt64Size); err != nil {
+		return nil, err
+	}
+	addr := allocator.AdvancePointer(allocator.ByteSliceData(data), uintptr(offset))
+	if uintptr(addr)%uintptr(sharedInt64Size) != 0 {
+		return nil, errors.Errorf("the address of the value at offset %d is not 8-byte aligned", offset)
+	}
+	return &SharedInt64{region: region, value: (*int64)(addr)}, nil
+}
+
+// Add atomically adds delta to the value and returns the new value.
+func (i *SharedInt64) Add(delta int64) int64 {
+	return atomic.AddInt64(i.value, delta)
+}
+
+// Load atomically loads the value.
+func (i *SharedInt64) Load() int64 {
+	return atomic.LoadInt64(i.value)
+}
+
+// Store atomically stores v into the value.
+func (i *SharedInt64) Store(v int64) {
+	atomic.StoreInt64(i.value, v)
+}
+
+// CompareAndSwap executes the compare-and-swap operation for the value.
+func (i *SharedInt64) CompareAndSwap(old, new int64) bool {
+	return atomic.CompareAndSwapInt64(i.value, old, new)
+}
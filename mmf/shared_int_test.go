@@ -0,0 +1,94 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedInt64(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(64)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	_, err = NewSharedInt64(region, 4)
+	a.Error(err)
+	_, err = NewSharedInt64(region, 60)
+	a.Error(err)
+	i, err := NewSharedInt64(region, 8)
+	if !a.NoError(err) {
+		return
+	}
+	i.Store(10)
+	a.Equal(int64(10), i.Load())
+	a.Equal(int64(15), i.Add(5))
+	a.False(i.CompareAndSwap(10, 20))
+	a.True(i.CompareAndSwap(15, 20))
+	a.Equal(int64(20), i.Load())
+	// the value is visible through another instance.
+	i2, err := NewSharedInt64(region, 8)
+	if a.NoError(err) {
+		a.Equal(int64(20), i2.Load())
+	}
+}
+
+func TestSharedInt64Remap(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(512)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	i, err := NewSharedInt64(region, 504)
+	if !a.NoError(err) {
+		return
+	}
+	i.Store(42)
+	if !a.NoError(region.Remap(4096)) {
+		return
+	}
+	// the value must be obtained again after the region has been remapped.
+	i, err = NewSharedInt64(region, 504)
+	if a.NoError(err) {
+		a.Equal(int64(42), i.Load())
+	}
+	i2, err := NewSharedInt64(region, 4088)
+	if a.NoError(err) {
+		a.Equal(int64(0), i2.Load())
+	}
+}
+
+func TestSharedInt64Concurrent(t *testing.T) {
+	const (
+		count      = 1000000
+		goroutines = 2
+	)
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(8)
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	i, err := NewSharedInt64(region, 0)
+	if !a.NoError(err) {
+		return
+	}
+	i.Store(0)
+	done := make(chan struct{})
+	for g := 0; g < goroutines; g++ {
+		go func() {
+			for j := 0; j < count; j++ {
+				i.Add(1)
+			}
+			done <- struct{}{}
+		}()
+	}
+	for g := 0; g < goroutines; g++ {
+		<-done
+	}
+	a.Equal(int64(count*goroutines), i.Load())
+}
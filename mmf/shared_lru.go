@@ -0,0 +1,280 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"bytes"
+	"hash/fnv"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	lruHdrSize      = int(unsafe.Sizeof(lruHdr{}))
+	lruEntryHdrSize = int(unsafe.Sizeof(lruEntryHdr{}))
+	lruNil          = -1
+)
+
+type lruHdr struct {
+	mu       regionLock
+	capacity int32
+	keySize  int32
+	valSize  int32
+	count    int32
+	head     int32 // most recently used entry.
+	tail     int32 // least recently used entry.
+	free     int32 // the head of the list of unused entries.
+	buckets  int32
+	_        int32
+}
+
+type lruEntryHdr struct {
+	prev   int32
+	next   int32
+	hnext  int32 // next entry in the hash bucket.
+	keyLen int32
+	valLen int32
+	_      int32
+}
+
+// SharedLRU is a fixed-capacity cache with least-recently-used eviction placed in a memory region.
+// Keys and values are byte slices of limited size.
+// All operations are guarded by a spin lock placed in the region,
+// so the cache can be used by several processes simultaneously.
+// The lock is not robust, see the package documentation.
+// The cache becomes invalid, if the region is closed or remapped. Use OpenSharedLRU to open it again.
+type SharedLRU struct {
+	region    *MemoryRegion
+	hdr       *lruHdr
+	buckets   unsafe.Pointer
+	entries   unsafe.Pointer
+	entrySize int
+}
+
+// SharedLRUSize returns the size of a memory region needed to store a cache with given parameters.
+func SharedLRUSize(keySize, valSize, capacity int) int {
+	return lruHdrSize + lruBucketsSize(capacity) + capacity*lruEntrySize(keySize, valSize)
+}
+
+// NewSharedLRU initializes a new cache in the given region. All the data in the region is overwritten.
+//	region - memory region. it must be at least SharedLRUSize(keySize, valSize, capacity) bytes long.
+//	keySize - maximum key size.
+//	valSize - maximum value size.
+//	capacity - maximum number of entries in the cache.
+func NewSharedLRU(region *MemoryRegion, keySize, valSize, capacity int) (*SharedLRU, error) {
+	if keySize <= 0 || valSize < 0 || capacity <= 0 {
+		return nil, errors.New("invalid cache parameters")
+	}
+	if region.Size() < SharedLRUSize(keySize, valSize, capacity) {
+		return nil, errors.Errorf("the region is too small. need %d bytes", SharedLRUSize(keySize, valSize, capacity))
+	}
+	result := newSharedLRU(region)
+	hdr := result.hdr
+	hdr.capacity, hdr.keySize, hdr.valSize = int32(capacity), int32(keySize), int32(valSize)
+	hdr.buckets = int32(lruBucketsCount(capacity))
+	hdr.count, hdr.head, hdr.tail = 0, lruNil, lruNil
+	result.setup()
+	for i := int32(0); i < hdr.buckets; i++ {
+		*result.bucket(i) = lruNil
+	}
+	// all entries are free.
+	for i := int32(0); i < hdr.capacity; i++ {
+		entry := result.entry(i)
+		entry.next = i + 1
+		entry.prev, entry.hnext = lruNil, lruNil
+	}
+	result.entry(hdr.capacity - 1).next = lruNil
+	hdr.free = 0
+	hdr.mu.init()
+	return result, nil
+}
+
+// OpenSharedLRU opens a cache, which was previously initialized in the given region with NewSharedLRU.
+func OpenSharedLRU(region *MemoryRegion) (*SharedLRU, error) {
+	if region.Size() < lruHdrSize {
+		return nil, errors.New("the region is too small")
+	}
+	result := newSharedLRU(region)
+	hdr := result.hdr
+	keySize, valSize, capacity := int(hdr.keySize), int(hdr.valSize), int(hdr.capacity)
+	if keySize <= 0 || valSize < 0 || capacity <= 0 || region.Size() < SharedLRUSize(keySize, valSize, capacity) {
+		return nil, errors.New("the region does not contain a valid cache")
+	}
+	result.setup()
+	return result, nil
+}
+
+func newSharedLRU(region *MemoryRegion) *SharedLRU {
+	raw := allocator.ByteSliceData(region.Data())
+	return &SharedLRU{
+		region:  region,
+		hdr:     (*lruHdr)(raw),
+		buckets: allocator.AdvancePointer(raw, uintptr(lruHdrSize)),
+	}
+}
+
+func (c *SharedLRU) setup() {
+	c.entries = allocator.AdvancePointer(c.buckets, uintptr(lruBucketsSize(int(c.hdr.capacity))))
+	c.entrySize = lruEntrySize(int(c.hdr.keySize), int(c.hdr.valSize))
+}
+
+// Get returns a copy of the value for the given key and marks the entry as most recently used.
+func (c *SharedLRU) Get(key []byte) ([]byte, bool) {
+	c.hdr.mu.lock()
+	defer c.hdr.mu.unlock()
+	idx, _ := c.find(key)
+	if idx == lruNil {
+		return nil, false
+	}
+	c.moveToFront(idx)
+	entry := c.entry(idx)
+	result := make([]byte, entry.valLen)
+	copy(result, c.value(entry))
+	return result, true
+}
+
+// Put sets the value for the given key and marks the entry as most recently used.
+// If the cache is full, the least recently used entry is evicted.
+func (c *SharedLRU) Put(key, value []byte) error {
+	if len(key) > int(c.hdr.keySize) {
+		return errors.Errorf("the key of %d bytes is too long", len(key))
+	}
+	if len(value) > int(c.hdr.valSize) {
+		return errors.Errorf("the value of %d bytes is too long", len(value))
+	}
+	c.hdr.mu.lock()
+	defer c.hdr.mu.unlock()
+	idx, bucket := c.find(key)
+	if idx == lruNil {
+		if c.hdr.free != lruNil {
+			idx = c.hdr.free
+			c.hdr.free = c.entry(idx).next
+			c.hdr.count++
+		} else {
+			idx = c.hdr.tail
+			c.remove(idx)
+		}
+		entry := c.entry(idx)
+		entry.keyLen = int32(len(key))
+		copy(c.key(entry), key)
+		entry.hnext = *c.bucket(bucket)
+		*c.bucket(bucket) = idx
+		c.pushFront(idx)
+	} else {
+		c.moveToFront(idx)
+	}
+	entry := c.entry(idx)
+	entry.valLen = int32(len(value))
+	copy(c.value(entry), value)
+	return nil
+}
+
+// Len returns the number of entries in the cache.
+func (c *SharedLRU) Len() int {
+	c.hdr.mu.lock()
+	defer c.hdr.mu.unlock()
+	return int(c.hdr.count)
+}
+
+// Cap returns the capacity of the cache.
+func (c *SharedLRU) Cap() int {
+	return int(c.hdr.capacity)
+}
+
+// find returns an index of the entry with the given key, or lruNil, and the key's bucket.
+func (c *SharedLRU) find(key []byte) (int32, int32) {
+	h := fnv.New32a()
+	h.Write(key)
+	bucket := int32(h.Sum32() % uint32(c.hdr.buckets))
+	for idx := *c.bucket(bucket); idx != lruNil; {
+		entry := c.entry(idx)
+		if int(entry.keyLen) == len(key) && bytes.Equal(c.key(entry)[:entry.keyLen], key) {
+			return idx, bucket
+		}
+		idx = entry.hnext
+	}
+	return lruNil, bucket
+}
+
+// remove removes an entry from its hash bucket and from the recency list.
+func (c *SharedLRU) remove(idx int32) {
+	entry := c.entry(idx)
+	_, bucket := c.find(c.key(entry)[:entry.keyLen])
+	for ptr := c.bucket(bucket); *ptr != lruNil; ptr = &c.entry(*ptr).hnext {
+		if *ptr == idx {
+			*ptr = entry.hnext
+			break
+		}
+	}
+	c.unlink(idx)
+}
+
+func (c *SharedLRU) moveToFront(idx int32) {
+	if c.hdr.head != idx {
+		c.unlink(idx)
+		c.pushFront(idx)
+	}
+}
+
+func (c *SharedLRU) unlink(idx int32) {
+	entry := c.entry(idx)
+	if entry.prev != lruNil {
+		c.entry(entry.prev).next = entry.next
+	} else {
+		c.hdr.head = entry.next
+	}
+	if entry.next != lruNil {
+		c.entry(entry.next).prev = entry.prev
+	} else {
+		c.hdr.tail = entry.prev
+	}
+	entry.prev, entry.next = lruNil, lruNil
+}
+
+func (c *SharedLRU) pushFront(idx int32) {
+	entry := c.entry(idx)
+	entry.prev, entry.next = lruNil, c.hdr.head
+	if c.hdr.head != lruNil {
+		c.entry(c.hdr.head).prev = idx
+	}
+	c.hdr.head = idx
+	if c.hdr.tail == lruNil {
+		c.hdr.tail = idx
+	}
+}
+
+func (c *SharedLRU) bucket(i int32) *int32 {
+	return (*int32)(allocator.AdvancePointer(c.buckets, uintptr(i)*4))
+}
+
+func (c *SharedLRU) entry(i int32) *lruEntryHdr {
+	return (*lruEntryHdr)(allocator.AdvancePointer(c.entries, uintptr(i)*uintptr(c.entrySize)))
+}
+
+func (c *SharedLRU) key(entry *lruEntryHdr) []byte {
+	size := int(c.hdr.keySize)
+	raw := allocator.AdvancePointer(unsafe.Pointer(entry), uintptr(lruEntryHdrSize))
+	return allocator.ByteSliceFromUnsafePointer(raw, size, size)
+}
+
+func (c *SharedLRU) value(entry *lruEntryHdr) []byte {
+	size := int(c.hdr.valSize)
+	raw := allocator.AdvancePointer(unsafe.Pointer(entry), uintptr(lruEntryHdrSize+int(c.hdr.keySize)))
+	return allocator.ByteSliceFromUnsafePointer(raw, size, size)
+}
+
+func lruBucketsCount(capacity int) int {
+	return capacity * 2
+}
+
+// lruBucketsSize returns the size of the hash index aligned to 8 bytes.
+func lruBucketsSize(capacity int) int {
+	return (lruBucketsCount(capacity)*4 + 7) &^ 7
+}
+
+func lruEntrySize(keySize, valSize int) int {
+	return (lruEntryHdrSize + keySize + valSize + 7) &^ 7
+}
@@ -0,0 +1,89 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedLRU(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(SharedLRUSize(8, 16, 3))
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	c, err := NewSharedLRU(region, 8, 16, 3)
+	if !a.NoError(err) {
+		return
+	}
+	a.Error(c.Put([]byte("too long key"), nil))
+	a.Error(c.Put([]byte("k"), make([]byte, 17)))
+	_, ok := c.Get([]byte("k1"))
+	a.False(ok)
+	for i := 1; i <= 3; i++ {
+		a.NoError(c.Put([]byte(fmt.Sprintf("k%d", i)), []byte(fmt.Sprintf("v%d", i))))
+	}
+	a.Equal(3, c.Len())
+	// k1 becomes the most recently used, so k2 is evicted.
+	value, ok := c.Get([]byte("k1"))
+	a.True(ok)
+	a.Equal([]byte("v1"), value)
+	a.NoError(c.Put([]byte("k4"), []byte("v4")))
+	a.Equal(3, c.Len())
+	_, ok = c.Get([]byte("k2"))
+	a.False(ok)
+	// update k3, so k1 is the least recently used.
+	a.NoError(c.Put([]byte("k3"), []byte("v3.1")))
+	a.NoError(c.Put([]byte("k5"), []byte("v5")))
+	_, ok = c.Get([]byte("k1"))
+	a.False(ok)
+	for key, expected := range map[string]string{"k3": "v3.1", "k4": "v4", "k5": "v5"} {
+		value, ok = c.Get([]byte(key))
+		if a.True(ok, key) {
+			a.Equal([]byte(expected), value)
+		}
+	}
+	c2, err := OpenSharedLRU(region)
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(3, c2.Len())
+	a.Equal(3, c2.Cap())
+	value, ok = c2.Get([]byte("k5"))
+	a.True(ok)
+	a.Equal([]byte("v5"), value)
+}
+
+func TestSharedLRUManyKeys(t *testing.T) {
+	const capacity = 64
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(SharedLRUSize(8, 8, capacity))
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	c, err := NewSharedLRU(region, 8, 8, capacity)
+	if !a.NoError(err) {
+		return
+	}
+	for i := 0; i < capacity*10; i++ {
+		key := []byte(fmt.Sprintf("%d", i))
+		if !a.NoError(c.Put(key, key)) {
+			return
+		}
+	}
+	a.Equal(capacity, c.Len())
+	for i := 0; i < capacity*10; i++ {
+		key := []byte(fmt.Sprintf("%d", i))
+		value, ok := c.Get(key)
+		if i < capacity*9 {
+			a.False(ok)
+		} else if a.True(ok) {
+			a.Equal(key, value)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"reflect"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+
+	"github.com/pkg/errors"
+)
+
+// SharedObject is a typed handle to an object placed in a memory region.
+// Get and Set copy the object in and out of the region, so no unsafe casts are needed to access it.
+// The copies are not atomic, so concurrent access must be protected by an interprocess locker.
+// It holds a reference to the region, so the latter can't be gc'ed.
+// The handle becomes invalid, if the region is closed or remapped.
+type SharedObject struct {
+	region *MemoryRegion
+	offset int
+	typ    reflect.Type
+}
+
+// NewSharedObject returns a handle to an object placed in the region at the given offset.
+// The object is not initialized, so that several processes can attach to the same object.
+//	prototype - a value or a pointer to a value of the type of the object.
+//		it must not contain any references, see allocator.Alloc for details.
+func NewSharedObject(region *MemoryRegion, offset int, prototype interface{}) (*SharedObject, error) {
+	if prototype == nil {
+		return nil, errors.New("nil prototype")
+	}
+	typ := reflect.TypeOf(prototype)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if err := allocator.CheckObjectReferences(reflect.Zero(typ).Interface()); err != nil {
+		return nil, errors.Wrap(err, "invalid object type")
+	}
+	if err := checkRegionBounds(len(region.Data()), offset, int(typ.Size())); err != nil {
+		return nil, err
+	}
+	return &SharedObject{region: region, offset: offset, typ: typ}, nil
+}
+
+// Get copies the object into out, which must be a pointer to a value of the object's type.
+func (o *SharedObject) Get(out interface{}) error {
+	if reflect.TypeOf(out) != reflect.PtrTo(o.typ) {
+		return errors.Errorf("expected %v, got %T", reflect.PtrTo(o.typ), out)
+	}
+	return AtAs(o.region, o.offset, out)
+}
+
+// Set copies in into the region. in must be a value of the object's type, or a pointer to it.
+func (o *SharedObject) Set(in interface{}) error {
+	if typ := reflect.TypeOf(in); typ != o.typ && typ != reflect.PtrTo(o.typ) {
+		return errors.Errorf("expected %v, got %T", o.typ, in)
+	}
+	return StoreAs(o.region, o.offset, in)
+}
@@ -0,0 +1,70 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type sharedObjectTestStruct struct {
+	ID    int64
+	Flags [4]uint8
+	Value float64
+}
+
+func TestSharedObject(t *testing.T) {
+	a := assert.New(t)
+	file, err := ioutil.TempFile("", "go-ipc-mmf")
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+	if !a.NoError(file.Truncate(128)) {
+		return
+	}
+	region, err := NewMemoryRegion(file, MEM_READWRITE, 0, 128)
+	if !a.NoError(err) {
+		return
+	}
+	defer region.Close()
+	region2, err := NewMemoryRegion(file, MEM_READ_ONLY, 0, 128)
+	if !a.NoError(err) {
+		return
+	}
+	defer region2.Close()
+	_, err = NewSharedObject(region, 120, sharedObjectTestStruct{})
+	a.Error(err)
+	_, err = NewSharedObject(region, 0, "string")
+	a.Error(err)
+	obj, err := NewSharedObject(region, 32, sharedObjectTestStruct{})
+	if !a.NoError(err) {
+		return
+	}
+	obj2, err := NewSharedObject(region2, 32, &sharedObjectTestStruct{})
+	if !a.NoError(err) {
+		return
+	}
+	expected := sharedObjectTestStruct{ID: 1, Flags: [4]uint8{1, 2, 3, 4}, Value: 3.5}
+	a.Error(obj.Set(int64(1)))
+	if !a.NoError(obj.Set(&expected)) {
+		return
+	}
+	var actual sharedObjectTestStruct
+	a.Error(obj2.Get(actual))
+	a.Error(obj2.Get(new(int64)))
+	if a.NoError(obj2.Get(&actual)) {
+		a.Equal(expected, actual)
+	}
+	expected.ID = 2
+	a.NoError(obj.Set(expected))
+	if a.NoError(obj2.Get(&actual)) {
+		a.Equal(expected, actual)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	spscRingHdrSize     = int(unsafe.Sizeof(spscRingHdr{}))
+	spscRingSlotHdrSize = 4
+	cacheLineSize       = 64
+)
+
+// spscRingHdr is placed at the beginning of the region.
+// head and tail are placed in different cache lines, as they are modified by different processes.
+type spscRingHdr struct {
+	slots    uint32
+	slotSize uint32
+	_        [cacheLineSize - 8]byte
+	head     uint64
+	_        [cacheLineSize - 8]byte
+	tail     uint64
+	_        [cacheLineSize - 8]byte
+}
+
+// SPSCRing is a lock-free ring buffer of fixed-size slots placed in a memory region.
+// It can be used by one producer and one consumer, which may be in different processes.
+// head and tail are monotonic counters of popped and pushed messages.
+// The producer writes a slot and then atomically advances tail, the consumer reads a slot
+// and then atomically advances head, so a slot is never accessed by both sides at once.
+// It holds a reference to the region, so the latter can't be gc'ed.
+type SPSCRing struct {
+	region    *MemoryRegion
+	hdr       *spscRingHdr
+	slots     unsafe.Pointer
+	slotSize  int
+	slotTotal int
+}
+
+// SPSCRingSize returns the size of a memory region needed to store
+// given number of slots of the given size.
+func SPSCRingSize(slots, slotSize int) int {
+	return spscRingHdrSize + slots*spscRingSlotTotalSize(slotSize)
+}
+
+// NewSPSCRing initializes an empty ring in the region. All the data in the region is overwritten.
+// The number of slots is determined by the size of the region.
+//
+//	region - memory region. it must be big enough to contain at least 2 slots.
+//	slotSize - maximum size of a message.
+func NewSPSCRing(region *MemoryRegion, slotSize int) (*SPSCRing, error) {
+	if slotSize <= 0 {
+		return nil, errors.New("the slot size must be positive")
+	}
+	slots := 0
+	if region.Size() > spscRingHdrSize {
+		slots = (region.Size() - spscRingHdrSize) / spscRingSlotTotalSize(slotSize)
+	}
+	if slots < 2 {
+		return nil, errors.Errorf("the region is too small. need at least %d bytes", SPSCRingSize(2, slotSize))
+	}
+	result := newSPSCRing(region)
+	result.hdr.slots = uint32(slots)
+	result.hdr.slotSize = uint32(slotSize)
+	result.setSlotSize(slotSize)
+	atomic.StoreUint64(&result.hdr.head, 0)
+	atomic.StoreUint64(&result.hdr.tail, 0)
+	return result, nil
+}
+
+// OpenSPSCRing opens a ring, which was initialized in the region by NewSPSCRing.
+func OpenSPSCRing(region *MemoryRegion) (*SPSCRing, error) {
+	if region.Size() < spscRingHdrSize {
+		return nil, errors.New("the region is too small")
+	}
+	result := newSPSCRing(region)
+	slots, slotSize := int(result.hdr.slots), int(result.hdr.slotSize)
+	if slots < 2 || slotSize <= 0 || region.Size() < SPSCRingSize(slots, slotSize) {
+		return nil, errors.New("the region does not contain a ring")
+	}
+	result.setSlotSize(slotSize)
+	return result, nil
+}
+
+// Push copies data into a free slot. It returns false, if the ring is full.
+// It must be called by the producer only. It panics, if data does not fit into a slot.
+func (r *SPSCRing) Push(data []byte) bool {
+	if len(data) > r.slotSize {
+		panic(errors.Errorf("the data of %d bytes does not fit into a slot of %d bytes", len(data), r.slotSize))
+	}
+	tail := atomic.LoadUint64(&r.hdr.tail)
+	if tail-atomic.LoadUint64(&r.hdr.head) == uint64(r.hdr.slots) {
+		return false
+	}
+	slot := r.slotAt(tail)
+	*(*uint32)(slot) = uint32(len(data))
+	copy(r.slotData(slot), data)
+	atomic.StoreUint64(&r.hdr.tail, tail+1)
+	return true
+}
+
+// Pop copies the oldest message into data and frees its slot.
+// It returns the size of the message and false, if the ring is empty.
+// If data is shorter, than the message, the message is truncated.
+// It must be called by the consumer only.
+func (r *SPSCRing) Pop(data []byte) (int, bool) {
+	head := atomic.LoadUint64(&r.hdr.head)
+	if head == atomic.LoadUint64(&r.hdr.tail) {
+		return 0, false
+	}
+	slot := r.slotAt(head)
+	size := int(*(*uint32)(slot))
+	copy(data, r.slotData(slot)[:size])
+	atomic.StoreUint64(&r.hdr.head, head+1)
+	return size, true
+}
+
+// Len returns the number of messages in the ring.
+func (r *SPSCRing) Len() int {
+	head := atomic.LoadUint64(&r.hdr.head)
+	return int(atomic.LoadUint64(&r.hdr.tail) - head)
+}
+
+// Cap returns the number of slots in the ring.
+func (r *SPSCRing) Cap() int {
+	return int(r.hdr.slots)
+}
+
+func newSPSCRing(region *MemoryRegion) *SPSCRing {
+	raw := allocator.ByteSliceData(region.Data())
+	return &SPSCRing{
+		region: region,
+		hdr:    (*spscRingHdr)(raw),
+		slots:  allocator.AdvancePointer(raw, uintptr(spscRingHdrSize)),
+	}
+}
+
+func (r *SPSCRing) setSlotSize(slotSize int) {
+	r.slotSize = slotSize
+	r.slotTotal = spscRingSlotTotalSize(slotSize)
+}
+
+func (r *SPSCRing) slotAt(counter uint64) unsafe.Pointer {
+	idx := counter % uint64(r.hdr.slots)
+	return allocator.AdvancePointer(r.slots, uintptr(idx)*uintptr(r.slotTotal))
+}
+
+func (r *SPSCRing) slotData(slot unsafe.Pointer) []byte {
+	raw := allocator.AdvancePointer(slot, uintptr(spscRingSlotHdrSize))
+	return allocator.ByteSliceFromUnsafePointer(raw, r.slotSize, r.slotSize)
+}
+
+// spscRingSlotTotalSize returns the size of a slot with its header aligned to 8 bytes.
+func spscRingSlotTotalSize(slotSize int) int {
+	return (spscRingSlotHdrSize + slotSize + 7) &^ 7
+}
@@ -0,0 +1,139 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	testutil "github.com/nxgtw/go-ipc/internal/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	mmfProgPath = "./internal/test/"
+)
+
+func argsForMmfCommand(command string, args ...string) []string {
+	files, err := testutil.LocatePackageFiles(mmfProgPath)
+	if err != nil {
+		panic(err)
+	}
+	for i, name := range files {
+		files[i] = mmfProgPath + name
+	}
+	return append(append(files, command), args...)
+}
+
+func argsForRingConsumeCommand(fileName string, n int) []string {
+	return argsForMmfCommand("consume", fileName, strconv.Itoa(n))
+}
+
+// ringMessage must be in sync with the one used in the test program.
+func ringMessage(seq uint64) []byte {
+	result := make([]byte, 8+seq%8)
+	binary.LittleEndian.PutUint64(result, seq)
+	for i := 8; i < len(result); i++ {
+		result[i] = byte(seq)
+	}
+	return result
+}
+
+func TestSPSCRing(t *testing.T) {
+	a := assert.New(t)
+	region, cleanup, err := newTempFileRegion(SPSCRingSize(3, 8))
+	if !a.NoError(err) {
+		return
+	}
+	defer cleanup()
+	_, err = NewSPSCRing(region, 0)
+	a.Error(err)
+	_, err = NewSPSCRing(region, 32)
+	a.Error(err)
+	_, err = OpenSPSCRing(region)
+	a.Error(err)
+	p, err := NewSPSCRing(region, 8)
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(3, p.Cap())
+	c, err := OpenSPSCRing(region)
+	if !a.NoError(err) {
+		return
+	}
+	buf := make([]byte, 8)
+	_, ok := c.Pop(buf)
+	a.False(ok)
+	a.True(p.Push([]byte{1}))
+	a.True(p.Push([]byte{2, 2}))
+	a.True(p.Push([]byte{3, 3, 3}))
+	a.False(p.Push([]byte{4}))
+	a.Equal(3, c.Len())
+	a.Panics(func() { p.Push(make([]byte, 9)) })
+	for i := 1; i <= 3; i++ {
+		n, ok := c.Pop(buf)
+		if a.True(ok) && a.Equal(i, n) {
+			a.Equal(byte(i), buf[n-1])
+		}
+	}
+	_, ok = c.Pop(buf)
+	a.False(ok)
+	// the ring wraps around.
+	a.True(p.Push([]byte{5, 5, 5, 5, 5}))
+	n, ok := c.Pop(buf[:2])
+	a.True(ok)
+	a.Equal(5, n)
+	a.Equal([]byte{5, 5}, buf[:2])
+	a.Equal(0, c.Len())
+}
+
+func TestSPSCRingAnotherProcess(t *testing.T) {
+	const n = 100000
+	a := assert.New(t)
+	file, err := ioutil.TempFile("", "go-ipc-ring")
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+	size := SPSCRingSize(64, 16)
+	if !a.NoError(file.Truncate(int64(size))) {
+		return
+	}
+	region, err := NewMemoryRegion(file, MEM_READWRITE, 0, size)
+	if !a.NoError(err) {
+		return
+	}
+	defer region.Close()
+	ring, err := NewSPSCRing(region, 16)
+	if !a.NoError(err) {
+		return
+	}
+	ch := testutil.RunTestAppAsync(argsForRingConsumeCommand(file.Name(), n), nil)
+	deadline := time.Now().Add(time.Minute)
+	for i := uint64(0); i < n; i++ {
+		for !ring.Push(ringMessage(i)) {
+			if time.Now().After(deadline) {
+				t.Errorf("timeout")
+				return
+			}
+			runtime.Gosched()
+		}
+	}
+	res, ok := testutil.WaitForAppResultChan(ch, time.Minute)
+	if !a.True(ok, "timeout") {
+		return
+	}
+	if res.Err != nil {
+		t.Errorf("app error: %v. the output is %q", res.Err, res.Output)
+	}
+	a.Equal(0, ring.Len())
+}
@@ -0,0 +1,69 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mmf
+
+import (
+	"reflect"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+
+	"github.com/pkg/errors"
+)
+
+// AtAs copies the data of the region starting at the given offset into the object.
+// It is a safe alternative to casting region.Data() to a pointer.
+// The object must be a non-nil pointer or a slice, and must not contain any references,
+// see allocator.Alloc for details. If the object does not fit into the region, an error is returned.
+func AtAs(region *MemoryRegion, offset int, out interface{}) error {
+	if out == nil {
+		return errors.New("the object must be a non-nil pointer or a slice")
+	}
+	if kind := reflect.ValueOf(out).Kind(); kind != reflect.Ptr && kind != reflect.Slice {
+		return errors.New("the object must be a non-nil pointer or a slice")
+	}
+	objData, err := allocator.ObjectData(out)
+	if err != nil {
+		return errors.Wrap(err, "invalid object")
+	}
+	data := region.Data()
+	if err = checkRegionBounds(len(data), offset, len(objData)); err != nil {
+		return err
+	}
+	copy(objData, data[offset:])
+	allocator.UseValue(out)
+	UseMemoryRegion(region)
+	return nil
+}
+
+// StoreAs copies the object into the region at the given offset.
+// The object must not contain any references, see allocator.Alloc for details.
+// Pointers are dereferenced. If the object does not fit into the region, an error is returned.
+func StoreAs(region *MemoryRegion, offset int, in interface{}) error {
+	value := reflect.ValueOf(in)
+	if !value.IsValid() {
+		return errors.New("invalid object")
+	}
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return errors.New("nil object")
+		}
+		value = value.Elem()
+	}
+	data := region.Data()
+	if err := checkRegionBounds(len(data), offset, allocator.ObjectSize(value)); err != nil {
+		return err
+	}
+	if err := allocator.Alloc(data[offset:], in); err != nil {
+		return errors.Wrap(err, "invalid object")
+	}
+	UseMemoryRegion(region)
+	return nil
+}
+
+// checkRegionBounds returns an error, if size bytes at offset don't fit into regionSize bytes.
+func checkRegionBounds(regionSize, offset, size int) error {
+	if offset < 0 || offset > regionSize || size > regionSize-offset {
+		return errors.Errorf("an object of %d bytes at offset %d is out of the region bounds [0, %d)", size, offset, regionSize)
+	}
+	return nil
+}
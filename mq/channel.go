@@ -0,0 +1,246 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"os"
+	"runtime"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/helper"
+	"bitbucket.org/avd/go-ipc/mmf"
+	"bitbucket.org/avd/go-ipc/shm"
+	ipc_sync "bitbucket.org/avd/go-ipc/sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	channelHdrSize     = int(unsafe.Sizeof(channelHdr{}))
+	channelInitTimeout = time.Second
+)
+
+var (
+	// ErrChannelClosed is returned by Channel.Send, if the channel has been shut down.
+	ErrChannelClosed = errors.New("the channel is closed")
+)
+
+type channelHdr struct {
+	ready  int32
+	closed int32
+}
+
+// Channel is a typed interprocess channel.
+// It is built of a TypedQueue placed into a shared memory object and two semaphores,
+// which count filled and free slots in the queue.
+// Send and Recv block, if the channel is full or empty respectively.
+// Elements are converted with RawCodec, so they must not contain any references.
+// Like TypedQueue, the channel is not generic, as the package supports Go versions without type parameters.
+type Channel struct {
+	name   string
+	region *mmf.MemoryRegion
+	hdr    *channelHdr
+	queue  *TypedQueue
+	items  *ipc_sync.Semaphore
+	slots  *ipc_sync.Semaphore
+}
+
+// NewChannel creates a new channel, or opens an existing one.
+// All the processes must pass the same capacity and element size.
+//	name - channel name. implementation will create a shm object and two semaphores with this name as a prefix.
+//	capacity - maximum number of elements in the channel. it must not be greater, than sync.CSemMaxVal.
+//	elemSize - maximum size of an element.
+//	perm - object's permission bits.
+func NewChannel(name string, capacity, elemSize int, perm os.FileMode) (*Channel, error) {
+	if capacity <= 0 || capacity > ipc_sync.CSemMaxVal {
+		return nil, errors.Errorf("channel capacity must be in range [1, %d]", ipc_sync.CSemMaxVal)
+	}
+	if elemSize <= 0 {
+		return nil, errors.New("element size must be positive")
+	}
+	size := channelHdrSize + TypedQueueSize(capacity, elemSize)
+	region, created, err := helper.CreateWritableRegion(channelStateName(name), os.O_CREATE, perm, size)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create shared state")
+	}
+	result := &Channel{
+		name:   name,
+		region: region,
+		hdr:    (*channelHdr)(allocator.ByteSliceData(region.Data())),
+	}
+	if created {
+		err = result.init(capacity, elemSize, perm)
+	} else {
+		err = result.open()
+	}
+	if err != nil {
+		result.Close()
+		if created {
+			DestroyChannel(name)
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// init creates channel's objects. It cleans up semaphores, which could be left from
+// the previous instances of the channel, then initializes the queue and marks the channel as ready.
+func (c *Channel) init(capacity, elemSize int, perm os.FileMode) error {
+	var err error
+	if err = ipc_sync.DestroySemaphore(channelSemaName(c.name, "i")); err != nil {
+		return errors.Wrap(err, "channel: failed to destroy items semaphore")
+	}
+	if err = ipc_sync.DestroySemaphore(channelSemaName(c.name, "s")); err != nil {
+		return errors.Wrap(err, "channel: failed to destroy slots semaphore")
+	}
+	if c.items, err = ipc_sync.NewSemaphore(channelSemaName(c.name, "i"), os.O_CREATE|os.O_EXCL, perm, 0); err != nil {
+		return errors.Wrap(err, "channel: failed to create items semaphore")
+	}
+	if c.slots, err = ipc_sync.NewSemaphore(channelSemaName(c.name, "s"), os.O_CREATE|os.O_EXCL, perm, capacity); err != nil {
+		return errors.Wrap(err, "channel: failed to create slots semaphore")
+	}
+	if c.queue, err = newTypedQueue(c.region.Data()[channelHdrSize:], capacity, elemSize, nil); err != nil {
+		return errors.Wrap(err, "channel: failed to create a queue")
+	}
+	atomic.StoreInt32(&c.hdr.ready, 1)
+	return nil
+}
+
+// open waits for the channel to be initialized by its creator and opens channel's objects.
+func (c *Channel) open() error {
+	var err error
+	start := time.Now()
+	for atomic.LoadInt32(&c.hdr.ready) == 0 {
+		if time.Since(start) > channelInitTimeout {
+			return errors.New("channel: the channel has not been initialized")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if c.items, err = ipc_sync.NewSemaphore(channelSemaName(c.name, "i"), 0, 0666, 0); err != nil {
+		return errors.Wrap(err, "channel: failed to open items semaphore")
+	}
+	if c.slots, err = ipc_sync.NewSemaphore(channelSemaName(c.name, "s"), 0, 0666, 0); err != nil {
+		return errors.Wrap(err, "channel: failed to open slots semaphore")
+	}
+	if c.queue, err = openTypedQueue(c.region.Data()[channelHdrSize:], nil); err != nil {
+		return errors.Wrap(err, "channel: failed to open a queue")
+	}
+	return nil
+}
+
+// Send puts the object into the channel. It blocks if the channel is full.
+// It returns ErrChannelClosed, if the channel has been shut down, and ErrPointer, if the object is a pointer.
+func (c *Channel) Send(object interface{}) error {
+	if err := checkSendObject(object); err != nil {
+		return err
+	}
+	if c.isClosed() {
+		return ErrChannelClosed
+	}
+	c.slots.Wait()
+	if c.isClosed() {
+		// pass the wakeup to other blocked senders.
+		c.slots.Signal(1)
+		return ErrChannelClosed
+	}
+	if err := c.queue.Enqueue(object); err != nil {
+		c.slots.Signal(1)
+		return err
+	}
+	c.items.Signal(1)
+	return nil
+}
+
+// Recv takes the oldest object from the channel. It blocks if the channel is empty.
+// It returns false, if the channel has been shut down and there are no more objects in it.
+//	object - a pointer to an object to decode received data into. if it is not a pointer or a slice,
+//	ErrNotPointer is returned.
+func (c *Channel) Recv(object interface{}) (bool, error) {
+	if err := checkReceiveObject(object); err != nil {
+		return false, err
+	}
+	c.items.Wait()
+	for {
+		err := c.queue.Dequeue(object)
+		if err != mqEmptyError {
+			c.slots.Signal(1)
+			return err == nil, err
+		}
+		if c.isClosed() {
+			// the wakeup was caused by the shutdown. pass it to other blocked receivers.
+			c.items.Signal(1)
+			return false, nil
+		}
+		// another sender has reserved an earlier slot, but has not published its element yet.
+		runtime.Gosched()
+	}
+}
+
+// Shutdown marks the channel as closed for all processes.
+// Subsequent sends fail with ErrChannelClosed. Receivers get all the remaining objects,
+// and then Recv returns false.
+func (c *Channel) Shutdown() {
+	if atomic.CompareAndSwapInt32(&c.hdr.closed, 0, 1) {
+		c.items.Signal(1)
+		c.slots.Signal(1)
+	}
+}
+
+// Close closes current instance of the channel.
+func (c *Channel) Close() error {
+	var err error
+	if c.items != nil {
+		if err = c.items.Close(); err != nil {
+			err = errors.Wrap(err, "failed to close items semaphore")
+		}
+	}
+	if c.slots != nil {
+		if errSlots := c.slots.Close(); errSlots != nil && err == nil {
+			err = errors.Wrap(errSlots, "failed to close slots semaphore")
+		}
+	}
+	if errRegion := c.region.Close(); errRegion != nil && err == nil {
+		err = errors.Wrap(errRegion, "failed to close shm region")
+	}
+	return err
+}
+
+// Destroy closes current instance of the channel and removes it permanently.
+func (c *Channel) Destroy() error {
+	if err := c.Close(); err != nil {
+		return errors.Wrap(err, "channel close failed")
+	}
+	return DestroyChannel(c.name)
+}
+
+func (c *Channel) isClosed() bool {
+	return atomic.LoadInt32(&c.hdr.closed) != 0
+}
+
+// DestroyChannel permanently removes a channel.
+func DestroyChannel(name string) error {
+	errObject := shm.DestroyMemoryObject(channelStateName(name))
+	errItems := ipc_sync.DestroySemaphore(channelSemaName(name, "i"))
+	errSlots := ipc_sync.DestroySemaphore(channelSemaName(name, "s"))
+	if errObject != nil {
+		return errors.Wrap(errObject, "failed to destroy memory object")
+	}
+	if errItems != nil {
+		return errors.Wrap(errItems, "failed to destroy items semaphore")
+	}
+	if errSlots != nil {
+		return errors.Wrap(errSlots, "failed to destroy slots semaphore")
+	}
+	return nil
+}
+
+func channelStateName(name string) string {
+	return name + ".ch"
+}
+
+func channelSemaName(name, typ string) string {
+	return name + ".ch" + typ
+}
@@ -0,0 +1,182 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	testutil "github.com/nxgtw/go-ipc/internal/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testChannelName = "go-ipc.test-chan"
+)
+
+func TestChannelClose(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyChannel(testChannelName)) {
+		return
+	}
+	ch, err := NewChannel(testChannelName, 4, 24, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(ch.Destroy())
+	}()
+	ch2, err := NewChannel(testChannelName, 4, 24, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer ch2.Close()
+	value := newTypedQueueTestStruct(0)
+	a.Equal(ErrPointer, ch.Send(&value))
+	_, err = ch.Recv(value)
+	a.Equal(ErrNotPointer, err)
+	for i := 0; i < 3; i++ {
+		a.NoError(ch.Send(newTypedQueueTestStruct(i)))
+	}
+	done := make(chan int)
+	go func() {
+		var received int
+		var elem typedQueueTestStruct
+		for {
+			ok, err := ch2.Recv(&elem)
+			if !a.NoError(err) || !ok {
+				break
+			}
+			a.Equal(newTypedQueueTestStruct(received), elem)
+			received++
+		}
+		done <- received
+	}()
+	ch.Shutdown()
+	a.Equal(3, <-done)
+	a.Equal(ErrChannelClosed, ch.Send(newTypedQueueTestStruct(0)))
+	var elem typedQueueTestStruct
+	ok, err := ch.Recv(&elem)
+	a.NoError(err)
+	a.False(ok)
+}
+
+func TestChannelConcurrentSendRecv(t *testing.T) {
+	const (
+		workers = 4
+		count   = 1000
+	)
+	a := assert.New(t)
+	if !a.NoError(DestroyChannel(testChannelName)) {
+		return
+	}
+	ch, err := NewChannel(testChannelName, 4, 24, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(ch.Destroy())
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < count; j++ {
+				a.NoError(ch.Send(newTypedQueueTestStruct(j)))
+			}
+		}()
+	}
+	received := make(chan int, workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			var cnt int
+			var elem typedQueueTestStruct
+			for {
+				ok, err := ch.Recv(&elem)
+				if !a.NoError(err) || !ok {
+					break
+				}
+				cnt++
+			}
+			received <- cnt
+		}()
+	}
+	wg.Wait()
+	ch.Shutdown()
+	var total int
+	for i := 0; i < workers; i++ {
+		total += <-received
+	}
+	a.Equal(workers*count, total)
+}
+
+func TestChannelSendToAnotherProcess(t *testing.T) {
+	const count = 1000
+	a := assert.New(t)
+	if !a.NoError(DestroyChannel(testChannelName)) {
+		return
+	}
+	ch, err := NewChannel(testChannelName, 16, 24, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(ch.Destroy())
+	}()
+	resultChan := testutil.RunTestAppAsync(argsForChannelCommand("chanrecv", testChannelName, 16, 24, count), nil)
+	for i := 0; i < count; i++ {
+		if !a.NoError(ch.Send(newTypedQueueTestStruct(i))) {
+			return
+		}
+	}
+	ch.Shutdown()
+	result := <-resultChan
+	if !a.NoError(result.Err) {
+		t.Logf("program output is %q", result.Output)
+	}
+}
+
+func TestChannelRecvFromAnotherProcess(t *testing.T) {
+	const count = 1000
+	a := assert.New(t)
+	if !a.NoError(DestroyChannel(testChannelName)) {
+		return
+	}
+	ch, err := NewChannel(testChannelName, 16, 24, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(ch.Destroy())
+	}()
+	resultChan := testutil.RunTestAppAsync(argsForChannelCommand("chansend", testChannelName, 16, 24, count), nil)
+	var received int
+	var elem typedQueueTestStruct
+	for {
+		ok, err := ch.Recv(&elem)
+		if !a.NoError(err) || !ok {
+			break
+		}
+		if !a.Equal(newTypedQueueTestStruct(received), elem) {
+			break
+		}
+		received++
+	}
+	a.Equal(count, received)
+	result := <-resultChan
+	if !a.NoError(result.Err) {
+		t.Logf("program output is %q", result.Output)
+	}
+}
+
+func argsForChannelCommand(command, name string, capacity, elemSize, n int) []string {
+	return append(mqProgArgs,
+		"-object="+name,
+		"-options="+strconv.Itoa(capacity)+","+strconv.Itoa(elemSize),
+		command,
+		strconv.Itoa(n),
+	)
+}
@@ -0,0 +1,139 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// coalescedHdrSize is the size of a record header in a coalesced message.
+// Each record is stored as its length (uint32, little endian) followed by its data.
+const coalescedHdrSize = 4
+
+// coalescer accumulates small records and sends them as a single message,
+// when the size or time threshold is reached.
+type coalescer struct {
+	mu       sync.Mutex
+	maxBytes int
+	maxDelay time.Duration
+	buff     []byte
+	timer    *time.Timer
+	// err is an error of a flush made by the timer. it is returned by the next call.
+	err    error
+	closed bool
+	send   func(data []byte) error
+}
+
+func newCoalescer(maxBytes int, maxDelay time.Duration, send func(data []byte) error) *coalescer {
+	return &coalescer{
+		maxBytes: maxBytes,
+		maxDelay: maxDelay,
+		buff:     make([]byte, 0, maxBytes),
+		send:     send,
+	}
+}
+
+// add appends a record to the buffer, flushing it, if needed.
+func (c *coalescer) add(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.takeErr(); err != nil {
+		return err
+	}
+	recSize := coalescedHdrSize + len(data)
+	if recSize > c.maxBytes {
+		return errors.Errorf("the record of %d bytes exceeds coalescing limit of %d bytes", len(data), c.maxBytes-coalescedHdrSize)
+	}
+	if len(c.buff)+recSize > c.maxBytes {
+		if err := c.flushLocked(); err != nil {
+			return err
+		}
+	}
+	var hdr [coalescedHdrSize]byte
+	binary.LittleEndian.PutUint32(hdr[:], uint32(len(data)))
+	c.buff = append(c.buff, hdr[:]...)
+	c.buff = append(c.buff, data...)
+	if len(c.buff)+coalescedHdrSize >= c.maxBytes {
+		return c.flushLocked()
+	}
+	if c.timer == nil && c.maxDelay > 0 {
+		c.timer = time.AfterFunc(c.maxDelay, c.onTimer)
+	}
+	return nil
+}
+
+// flush sends buffered records, if any.
+func (c *coalescer) flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.takeErr(); err != nil {
+		return err
+	}
+	return c.flushLocked()
+}
+
+// close flushes buffered records and stops the timer.
+func (c *coalescer) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := c.takeErr()
+	if flushErr := c.flushLocked(); err == nil {
+		err = flushErr
+	}
+	c.closed = true
+	return err
+}
+
+func (c *coalescer) onTimer() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timer = nil
+	if c.closed {
+		return
+	}
+	if err := c.flushLocked(); err != nil && c.err == nil {
+		c.err = err
+	}
+}
+
+func (c *coalescer) flushLocked() error {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if len(c.buff) == 0 {
+		return nil
+	}
+	err := c.send(c.buff)
+	c.buff = c.buff[:0]
+	return err
+}
+
+func (c *coalescer) takeErr() error {
+	err := c.err
+	c.err = nil
+	return err
+}
+
+// UnpackCoalesced splits a message, which was sent by a queue with coalescing enabled,
+// into the original records. Returned slices point into data.
+func UnpackCoalesced(data []byte) ([][]byte, error) {
+	var result [][]byte
+	for len(data) > 0 {
+		if len(data) < coalescedHdrSize {
+			return result, errors.New("truncated record header")
+		}
+		size := int(binary.LittleEndian.Uint32(data))
+		data = data[coalescedHdrSize:]
+		if size > len(data) {
+			return result, errors.Errorf("truncated record: need %d bytes, have %d", size, len(data))
+		}
+		result = append(result, data[:size])
+		data = data[size:]
+	}
+	return result, nil
+}
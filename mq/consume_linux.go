@@ -0,0 +1,48 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"time"
+
+	"github.com/nxgtw/go-ipc/internal/common"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// consumeCheckInterval is how often Consume checks the stop channel while the queue is empty.
+	consumeCheckInterval = 100 * time.Millisecond
+)
+
+// Consume receives messages from the queue in a loop and passes them to the handler.
+// Interrupted syscalls are retried transparently.
+// The loop stops when the stop channel is closed, or the handler returns an error.
+//	handler - a function called for every message. data is valid only until the handler returns.
+//	stop - a channel, closing which stops the loop. if nil, the loop runs until an error occurs.
+// Returns nil, if stopped via the channel, or the first error of the handler or the queue.
+func Consume(mq *LinuxMessageQueue, handler func(data []byte, prio int) error, stop <-chan struct{}) error {
+	attrs, err := mq.getAttrs()
+	if err != nil {
+		return err
+	}
+	data := make([]byte, attrs.Msgsize)
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		l, prio, err := mq.ReceiveTimeoutPriority(data, consumeCheckInterval)
+		if err != nil {
+			cause := errors.Cause(err)
+			if IsTemporary(cause) || common.IsInterruptedSyscallErr(cause) {
+				continue
+			}
+			return err
+		}
+		if err = handler(data[:l], prio); err != nil {
+			return err
+		}
+	}
+}
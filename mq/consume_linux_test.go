@@ -0,0 +1,69 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinuxMqConsume(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 5, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	for i := 0; i < 5; i++ {
+		if !a.NoError(mq.SendPriority([]byte{byte(i)}, 1)) {
+			return
+		}
+	}
+	handlerErr := errors.New("handler error")
+	var received []byte
+	err = Consume(mq, func(data []byte, prio int) error {
+		a.Equal(1, prio)
+		received = append(received, data...)
+		if len(received) == 3 {
+			return handlerErr
+		}
+		return nil
+	}, nil)
+	a.Equal(handlerErr, err)
+	a.Equal([]byte{0, 1, 2}, received)
+	attrs, err := mq.getAttrs()
+	if a.NoError(err) {
+		a.Equal(2, attrs.Curmsgs)
+	}
+}
+
+func TestLinuxMqConsumeStop(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 5, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	stop := make(chan struct{})
+	go func() {
+		a.NoError(mq.Send([]byte{1}))
+		<-time.After(time.Millisecond * 50)
+		close(stop)
+	}()
+	var count int
+	a.NoError(Consume(mq, func(data []byte, prio int) error {
+		count++
+		return nil
+	}, stop))
+	a.Equal(1, count)
+}
@@ -2,5 +2,14 @@
 
 // Package mq implements interprocess queues logic.
 // It provides access to system mq mechanisms, such as sysv mq and linux mq.
+// On windows it provides WindowsMessageQueue, a fifo queue based on shared memory and named semaphores.
 // Also, it provides access to multi-platform priority queue, FastMq.
+//
+// New, Open, and Destroy use the default implementation for the current platform:
+//	- on windows, WindowsMessageQueue. FastMq is used instead, if the package is built with 'fast_mq' tag.
+//	- on unix, System V mq. On linux, linux mq is used instead, if the package is built with 'linux_mq' tag.
+//
+// Notifications about new messages (see LinuxMessageQueue.Notify) are available on linux only.
+// Darwin does not implement POSIX message queues, and System V queues are not backed by descriptors,
+// so they can't be watched with kqueue. Use timed receive operations to wait for messages there.
 package mq
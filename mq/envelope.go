@@ -0,0 +1,77 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+
+	"github.com/pkg/errors"
+)
+
+// typeRegistry maps type tags of typed messages to the types of their objects.
+var typeRegistry = struct {
+	sync.RWMutex
+	types map[uint16]reflect.Type
+}{types: make(map[uint16]reflect.Type)}
+
+// RegisterType associates typeID with the type of the object, so that typed messages
+// with this tag can be decoded with DecodeTyped. Pointers are dereferenced,
+// so RegisterType(1, T{}) and RegisterType(1, &T{}) are equal.
+// It fails, if the type contains references, or if typeID is registered for another type.
+func RegisterType(typeID uint16, object interface{}) error {
+	if object == nil {
+		return errors.New("the object must not be nil")
+	}
+	t := reflect.TypeOf(object)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if err := allocator.CheckObjectReferences(reflect.Zero(t).Interface()); err != nil {
+		return errors.Wrapf(err, "type %v can't be registered", t)
+	}
+	typeRegistry.Lock()
+	defer typeRegistry.Unlock()
+	if old, ok := typeRegistry.types[typeID]; ok && old != t {
+		return errors.Errorf("type id %d is already registered for %v", typeID, old)
+	}
+	typeRegistry.types[typeID] = t
+	return nil
+}
+
+// RegisteredType returns the type registered with RegisterType for the given typeID.
+func RegisteredType(typeID uint16) (reflect.Type, bool) {
+	typeRegistry.RLock()
+	defer typeRegistry.RUnlock()
+	t, ok := typeRegistry.types[typeID]
+	return t, ok
+}
+
+// DecodeTyped copies the data of a typed message into out.
+//	typeID - the tag of the message. It must be registered with RegisterType.
+//	data - the payload of the message. Its length must be equal to the size of the registered type.
+//	out - a pointer to a value of the registered type.
+func DecodeTyped(typeID uint16, data []byte, out interface{}) error {
+	t, ok := RegisteredType(typeID)
+	if !ok {
+		return errors.Errorf("unknown type id %d", typeID)
+	}
+	if err := checkReceiveObject(out); err != nil {
+		return err
+	}
+	if outType := reflect.TypeOf(out); outType.Kind() != reflect.Ptr || outType.Elem() != t {
+		return errors.Errorf("type id %d is registered for %v, can't decode into %T", typeID, t, out)
+	}
+	if int(t.Size()) != len(data) {
+		return errors.Errorf("the message of %d bytes does not match the size of %v (%d bytes)", len(data), t, t.Size())
+	}
+	objData, err := allocator.ObjectData(out)
+	if err != nil {
+		return err
+	}
+	copy(objData, data)
+	allocator.UseValue(out)
+	return nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"encoding/binary"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+
+	"github.com/pkg/errors"
+)
+
+const typeTagSize = 2
+
+// SendTyped sends an object with the given priority, prepending it with a 2-byte type tag,
+// so that a receiver of a queue, which carries different kinds of messages, knows how to decode it.
+// Such messages must be received with ReceiveTyped.
+//	typeID - the tag of the message. Use RegisterType on the receiving side to decode messages with DecodeTyped.
+//	object - an object, a pointer to an object, or a slice, which must not contain any references.
+//		its size must not exceed max message size of the queue minus 2 bytes.
+func (mq *LinuxMessageQueue) SendTyped(typeID uint16, object interface{}, prio int) error {
+	data, err := mq.objectData(object)
+	if err != nil {
+		return errors.Wrap(err, "failed to get object data")
+	}
+	msg := make([]byte, typeTagSize+len(data))
+	binary.LittleEndian.PutUint16(msg, typeID)
+	copy(msg[typeTagSize:], data)
+	allocator.UseValue(object)
+	return mq.SendPriority(msg, prio)
+}
+
+// ReceiveTyped receives a message sent with SendTyped.
+// It returns the type tag of the message and its payload, which can be decoded with DecodeTyped.
+// It blocks if the queue is empty.
+//	prio - if not nil, the priority of the message is stored here.
+func (mq *LinuxMessageQueue) ReceiveTyped(prio *int) (uint16, []byte, error) {
+	msg := make([]byte, len(mq.inputBuff))
+	n, msgPrio, err := mq.ReceivePriority(msg)
+	if err != nil {
+		return 0, nil, err
+	}
+	if n < typeTagSize {
+		return 0, nil, errors.Errorf("the message of %d bytes is too short to have a type tag", n)
+	}
+	if prio != nil {
+		*prio = msgPrio
+	}
+	return binary.LittleEndian.Uint16(msg), msg[typeTagSize:n], nil
+}
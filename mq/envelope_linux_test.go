@@ -0,0 +1,87 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	typedTestPointID = 100 + iota
+	typedTestEventID
+)
+
+type typedTestPoint struct {
+	X, Y int32
+}
+
+type typedTestEvent struct {
+	Code    uint16
+	Payload [8]byte
+}
+
+func TestLinuxMqTyped(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(RegisterType(typedTestPointID, typedTestPoint{})) {
+		return
+	}
+	if !a.NoError(RegisterType(typedTestEventID, &typedTestEvent{})) {
+		return
+	}
+	a.NoError(RegisterType(typedTestPointID, &typedTestPoint{}))
+	a.Error(RegisterType(typedTestPointID, typedTestEvent{}))
+	a.Error(RegisterType(typedTestPointID+100, struct{ s string }{}))
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 4, 32)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	point := typedTestPoint{X: 1, Y: -2}
+	event := typedTestEvent{Code: 7, Payload: [8]byte{1, 2, 3}}
+	if !a.NoError(mq.SendTyped(typedTestPointID, point, 1)) {
+		return
+	}
+	if !a.NoError(mq.SendTyped(typedTestEventID, &event, 1)) {
+		return
+	}
+	var received []interface{}
+	for i := 0; i < 2; i++ {
+		var prio int
+		typeID, data, err := mq.ReceiveTyped(&prio)
+		if !a.NoError(err) {
+			return
+		}
+		a.Equal(1, prio)
+		typ, ok := RegisteredType(typeID)
+		if !a.True(ok) {
+			return
+		}
+		out := reflect.New(typ).Interface()
+		if !a.NoError(DecodeTyped(typeID, data, out)) {
+			return
+		}
+		switch obj := out.(type) {
+		case *typedTestPoint:
+			a.Equal(uint16(typedTestPointID), typeID)
+			received = append(received, *obj)
+		case *typedTestEvent:
+			a.Equal(uint16(typedTestEventID), typeID)
+			received = append(received, *obj)
+		default:
+			t.Errorf("unexpected type %T", out)
+		}
+	}
+	a.Equal([]interface{}{point, event}, received)
+	// decoding errors.
+	var wrong typedTestEvent
+	a.Error(DecodeTyped(typedTestPointID, make([]byte, 8), &wrong))
+	a.Error(DecodeTyped(typedTestPointID, make([]byte, 4), new(typedTestPoint)))
+	a.Error(DecodeTyped(typedTestPointID+200, make([]byte, 8), new(typedTestPoint)))
+}
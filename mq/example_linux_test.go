@@ -0,0 +1,46 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func ExampleLinuxMessageQueue_Fd() {
+	DestroyLinuxMessageQueue("mq")
+	mq, err := CreateLinuxMessageQueue("mq", os.O_EXCL|os.O_RDWR, 0666, 1, 8)
+	if err != nil {
+		panic("new queue")
+	}
+	defer mq.Destroy()
+	go func() {
+		if err := mq.Send([]byte{1, 2, 3}); err != nil {
+			panic("send")
+		}
+	}()
+	// wait until the queue becomes readable.
+	fds := []unix.PollFd{{Fd: int32(mq.Fd()), Events: unix.POLLIN}}
+	for {
+		n, err := unix.Poll(fds, -1)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			panic("poll")
+		}
+		if n > 0 {
+			break
+		}
+	}
+	// the queue has a message, so the receive must not block.
+	data := make([]byte, 8)
+	l, err := mq.ReceiveTimeout(data, 0)
+	if err != nil {
+		panic("receive")
+	}
+	if l != 3 {
+		panic("wrong len")
+	}
+}
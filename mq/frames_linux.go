@@ -0,0 +1,92 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// frameHdrSize is the size of a header of each message of a payload sent with SendAll.
+// The header contains:
+//	total size of the payload (uint32, little endian).
+//	the offset of the frame in the payload (uint32, little endian).
+const frameHdrSize = 8
+
+// SendAll sends a payload of any size with the given priority.
+// If the payload does not fit into one message, it is split into several frames,
+// each of them fits into max message size of the queue. Such payloads must be received with ReceiveAll.
+// As the frames of a payload can be interleaved with other messages, a payload, which takes
+// several frames, can be received correctly only if there is one producer,
+// and all the messages have the same priority.
+func (mq *LinuxMessageQueue) SendAll(data []byte, prio int) error {
+	attrs, err := mq.getAttrs()
+	if err != nil {
+		return errors.Wrap(err, "failed to get mq attrs")
+	}
+	frameSize := attrs.Msgsize - orderStampSize - frameHdrSize
+	if frameSize <= 0 {
+		return errors.New("max message size of the queue is too small")
+	}
+	if uint64(len(data)) > uint64(^uint32(0)) {
+		return errors.Errorf("the payload of %d bytes is too large", len(data))
+	}
+	msg := make([]byte, frameHdrSize+frameSize)
+	binary.LittleEndian.PutUint32(msg, uint32(len(data)))
+	for offset := 0; ; {
+		binary.LittleEndian.PutUint32(msg[4:], uint32(offset))
+		n := copy(msg[frameHdrSize:], data[offset:])
+		if err = mq.SendPriority(msg[:frameHdrSize+n], prio); err != nil {
+			return err
+		}
+		if offset += n; offset == len(data) {
+			return nil
+		}
+	}
+}
+
+// ReceiveAll receives a payload sent with SendAll.
+// It blocks, until all the frames of the payload are received.
+//	prio - if not nil, the priority of the first frame is stored here.
+func (mq *LinuxMessageQueue) ReceiveAll(prio *int) ([]byte, error) {
+	attrs, err := mq.getAttrs()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get mq attrs")
+	}
+	msg := make([]byte, attrs.Msgsize)
+	var data []byte
+	var total int
+	for {
+		n, msgPrio, err := mq.ReceivePriority(msg)
+		if err != nil {
+			return nil, err
+		}
+		if n < frameHdrSize {
+			return nil, errors.New("the message does not have a frame header")
+		}
+		msgTotal := int(binary.LittleEndian.Uint32(msg))
+		offset := int(binary.LittleEndian.Uint32(msg[4:]))
+		if data == nil {
+			total = msgTotal
+			data = make([]byte, 0, total)
+			if prio != nil {
+				*prio = msgPrio
+			}
+		}
+		if msgTotal != total {
+			return nil, errors.Errorf("invalid payload size %d in a frame, expected %d", msgTotal, total)
+		}
+		if offset != len(data) {
+			return nil, errors.Errorf("invalid frame offset %d, expected %d", offset, len(data))
+		}
+		data = append(data, msg[frameHdrSize:n]...)
+		if len(data) >= total {
+			break
+		}
+	}
+	if len(data) != total {
+		return nil, errors.Errorf("invalid payload size %d, expected %d", len(data), total)
+	}
+	return data, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"os"
+	"testing"
+
+	"github.com/nxgtw/go-ipc/internal/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func framesTestPayload(n int) []byte {
+	result := make([]byte, n)
+	for i := range result {
+		result[i] = byte(i % 251)
+	}
+	return result
+}
+
+func TestLinuxMqSendAllSameProcess(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 8, 64)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	// empty payload, a single frame, an exact number of frames, and a short final frame.
+	for _, n := range []int{0, 10, 2 * (64 - frameHdrSize), 200} {
+		data := framesTestPayload(n)
+		if !a.NoError(mq.SendAll(data, 3)) {
+			return
+		}
+		var prio int
+		received, err := mq.ReceiveAll(&prio)
+		if a.NoError(err) {
+			a.Equal(data, received)
+			a.Equal(3, prio)
+		}
+	}
+}
+
+func TestLinuxMqReceiveAllInvalidFrame(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 8, 64)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	a.NoError(mq.Send([]byte{1, 2, 3}))
+	_, err = mq.ReceiveAll(nil)
+	a.Error(err)
+	// the second frame has a wrong offset.
+	a.NoError(mq.Send([]byte{16, 0, 0, 0, 0, 0, 0, 0, 1, 2}))
+	a.NoError(mq.Send([]byte{16, 0, 0, 0, 4, 0, 0, 0, 3, 4}))
+	_, err = mq.ReceiveAll(nil)
+	a.Error(err)
+}
+
+func TestLinuxMqSendAllToAnotherProcess(t *testing.T) {
+	const n = 1024 * 1024
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 8, 4096)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	resultChan := testutil.RunTestAppAsync(argsForLinuxMqCommand(testMqName, "allrecv", n), nil)
+	a.NoError(mq.SendAll(framesTestPayload(n), 0))
+	result := <-resultChan
+	if !a.NoError(result.Err) {
+		t.Logf("program output is %q", result.Output)
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"bytes"
+	"encoding/gob"
+
+	"github.com/pkg/errors"
+)
+
+// SendGob encodes the object with encoding/gob and sends it with the given priority.
+// Unlike Send, it allows to send objects with references, like strings, slices, and maps.
+// The encoded object is sent with SendAll, so the same restrictions apply.
+func (mq *LinuxMessageQueue) SendGob(object interface{}, prio int) error {
+	var buff bytes.Buffer
+	if err := gob.NewEncoder(&buff).Encode(object); err != nil {
+		return errors.Wrap(err, "failed to encode the object")
+	}
+	return mq.SendAll(buff.Bytes(), prio)
+}
+
+// ReceiveGob receives an object sent with SendGob and decodes it with encoding/gob.
+//	object - a pointer to an object to decode into.
+//	prio - if not nil, the priority of the message is stored here.
+func (mq *LinuxMessageQueue) ReceiveGob(object interface{}, prio *int) error {
+	data, err := mq.ReceiveAll(prio)
+	if err != nil {
+		return err
+	}
+	if err = gob.NewDecoder(bytes.NewReader(data)).Decode(object); err != nil {
+		return errors.Wrap(err, "failed to decode the object")
+	}
+	return nil
+}
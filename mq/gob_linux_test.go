@@ -0,0 +1,96 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/nxgtw/go-ipc/internal/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type gobTestStruct struct {
+	Name   string
+	Values []int
+	Attrs  map[string]string
+}
+
+func newGobTestStruct(n int) gobTestStruct {
+	result := gobTestStruct{Name: fmt.Sprintf("test struct %d", n), Attrs: map[string]string{"n": strconv.Itoa(n)}}
+	for i := 0; i < n; i++ {
+		result.Values = append(result.Values, i*i)
+	}
+	return result
+}
+
+func TestLinuxMqGobSameProcess(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 8, 64)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	for _, n := range []int{1, 100} {
+		a.NoError(mq.SendGob(newGobTestStruct(n), 2))
+		var received gobTestStruct
+		var prio int
+		if a.NoError(mq.ReceiveGob(&received, &prio)) {
+			a.Equal(newGobTestStruct(n), received)
+			a.Equal(2, prio)
+		}
+	}
+}
+
+func TestLinuxMqGobToAnotherProcess(t *testing.T) {
+	const n = 200
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 4, 64)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	resultChan := testutil.RunTestAppAsync(argsForLinuxMqCommand(testMqName, "gobrecv", n), nil)
+	a.NoError(mq.SendGob(newGobTestStruct(n), 1))
+	result := <-resultChan
+	if !a.NoError(result.Err) {
+		t.Logf("program output is %q", result.Output)
+	}
+}
+
+func TestLinuxMqGobFromAnotherProcess(t *testing.T) {
+	const n = 200
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 4, 64)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	resultChan := testutil.RunTestAppAsync(argsForLinuxMqCommand(testMqName, "gobsend", n), nil)
+	var received gobTestStruct
+	var prio int
+	if a.NoError(mq.ReceiveGob(&received, &prio)) {
+		a.Equal(newGobTestStruct(n), received)
+		a.Equal(1, prio)
+	}
+	result := <-resultChan
+	if !a.NoError(result.Err) {
+		t.Logf("program output is %q", result.Output)
+	}
+}
+
+func argsForLinuxMqCommand(name, command string, n int) []string {
+	return append(mqProgArgs, "-object="+name, "-type=linux", command, strconv.Itoa(n))
+}
@@ -6,12 +6,15 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/nxgtw/go-ipc/internal/test"
+	"bitbucket.org/avd/go-ipc/mmf"
 	"bitbucket.org/avd/go-ipc/mq"
+	"bitbucket.org/avd/go-ipc/shm"
 )
 
 var (
@@ -28,6 +31,23 @@ available commands:
   test {expected values byte array}
   send {values byte array}
   notifywait
+  drain n
+    receives n messages of any content, making a short pause before each receive
+  gobsend n
+    sends a gob-encoded test struct with n values
+  gobrecv n
+    receives a gob-encoded test struct and checks, that it has n values
+  allrecv n
+    receives a payload sent with SendAll and checks, that it is a test payload of n bytes
+    receives n messages of any content, making a short pause before each receive
+  typedrecv shm_name n
+    dequeues n test structs from a typed queue placed in shm_name region
+  pipeecho
+    receives a message from 'b' end of a pipe and sends it back
+  chansend n
+    sends n test structs into a channel and shuts it down. options are 'capacity,elem_size'
+  chanrecv n
+    receives test structs from a channel until it is shut down and checks, that there were n of them
 byte array should be passed as a continuous string of 2-symbol hex byte values like '01020A'
 `
 
@@ -112,6 +132,256 @@ func send() error {
 	return err
 }
 
+func drain() error {
+	if flag.NArg() != 2 {
+		return fmt.Errorf("drain: must provide exactly one argument")
+	}
+	n, err := strconv.Atoi(flag.Arg(1))
+	if err != nil {
+		return err
+	}
+	msgQueue, err := openMqWithType(*objName, os.O_RDONLY, *typ)
+	if err != nil {
+		return err
+	}
+	defer msgQueue.Close()
+	// count received messages, if the queue supports it, so that the sender can use Sync.
+	if counted, ok := msgQueue.(interface {
+		EnableCounters(perm os.FileMode) error
+	}); ok {
+		if err = counted.EnableCounters(0666); err != nil {
+			return err
+		}
+	}
+	received := make([]byte, 8192)
+	for i := 0; i < n; i++ {
+		time.Sleep(10 * time.Millisecond)
+		if _, err = msgQueue.Receive(received); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gobMessenger is implemented by queues, which can send gob-encoded objects.
+type gobMessenger interface {
+	SendGob(object interface{}, prio int) error
+	ReceiveGob(object interface{}, prio *int) error
+}
+
+type gobTestStruct struct {
+	Name   string
+	Values []int
+	Attrs  map[string]string
+}
+
+func newGobTestStruct(n int) gobTestStruct {
+	result := gobTestStruct{Name: fmt.Sprintf("test struct %d", n), Attrs: map[string]string{"n": strconv.Itoa(n)}}
+	for i := 0; i < n; i++ {
+		result.Values = append(result.Values, i*i)
+	}
+	return result
+}
+
+func openGobMq() (gobMessenger, func(), int, error) {
+	if flag.NArg() != 2 {
+		return nil, nil, 0, fmt.Errorf("must provide exactly one argument")
+	}
+	n, err := strconv.Atoi(flag.Arg(1))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	msgQueue, err := openMqWithType(*objName, os.O_RDWR, *typ)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	gm, ok := msgQueue.(gobMessenger)
+	if !ok {
+		msgQueue.Close()
+		return nil, nil, 0, fmt.Errorf("selected mq implementation does not support gob")
+	}
+	return gm, func() { msgQueue.Close() }, n, nil
+}
+
+func gobsend() error {
+	gm, closer, n, err := openGobMq()
+	if err != nil {
+		return err
+	}
+	defer closer()
+	return gm.SendGob(newGobTestStruct(n), 1)
+}
+
+func gobrecv() error {
+	gm, closer, n, err := openGobMq()
+	if err != nil {
+		return err
+	}
+	defer closer()
+	var received gobTestStruct
+	if err = gm.ReceiveGob(&received, nil); err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(received, newGobTestStruct(n)) {
+		return fmt.Errorf("invalid value received: %v", received)
+	}
+	return nil
+}
+
+// allMessenger is implemented by queues, which can send payloads of any size.
+type allMessenger interface {
+	ReceiveAll(prio *int) ([]byte, error)
+}
+
+func allrecv() error {
+	if flag.NArg() != 2 {
+		return fmt.Errorf("allrecv: must provide exactly one argument")
+	}
+	n, err := strconv.Atoi(flag.Arg(1))
+	if err != nil {
+		return err
+	}
+	msgQueue, err := openMqWithType(*objName, os.O_RDWR, *typ)
+	if err != nil {
+		return err
+	}
+	defer msgQueue.Close()
+	am, ok := msgQueue.(allMessenger)
+	if !ok {
+		return fmt.Errorf("selected mq implementation does not support ReceiveAll")
+	}
+	data, err := am.ReceiveAll(nil)
+	if err != nil {
+		return err
+	}
+	if len(data) != n {
+		return fmt.Errorf("invalid payload size %d, expected %d", len(data), n)
+	}
+	for i, b := range data {
+		if b != byte(i%251) {
+			return fmt.Errorf("invalid byte %d at %d", b, i)
+		}
+	}
+	return nil
+}
+
+type typedQueueTestStruct struct {
+	Idx  int64
+	Data [4]int32
+}
+
+func typedrecv() error {
+	if flag.NArg() != 3 {
+		return fmt.Errorf("typedrecv: must provide exactly two arguments")
+	}
+	n, err := strconv.Atoi(flag.Arg(2))
+	if err != nil {
+		return err
+	}
+	obj, err := shm.NewMemoryObject(flag.Arg(1), os.O_RDWR, 0666)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+	region, err := mmf.NewMemoryRegion(obj, mmf.MEM_READWRITE, 0, 0)
+	if err != nil {
+		return err
+	}
+	defer region.Close()
+	q, err := mq.OpenTypedQueue(region, nil)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; {
+		var received typedQueueTestStruct
+		if err = q.Dequeue(&received); err != nil {
+			if mq.IsTemporary(err) {
+				continue
+			}
+			return err
+		}
+		expected := typedQueueTestStruct{Idx: int64(i), Data: [4]int32{int32(i), int32(i + 1), int32(i + 2), int32(i + 3)}}
+		if received != expected {
+			return fmt.Errorf("invalid value at %d. expected '%v', got '%v'", i, expected, received)
+		}
+		i++
+	}
+	return nil
+}
+
+func pipeecho() error {
+	if flag.NArg() != 1 {
+		return fmt.Errorf("pipeecho: must not provide any arguments")
+	}
+	end, err := mq.OpenPipeEnd(*objName, mq.PipeB)
+	if err != nil {
+		return err
+	}
+	defer end.Close()
+	data := make([]byte, 8192)
+	l, err := end.Receive(data)
+	if err != nil {
+		return err
+	}
+	return end.Send(data[:l])
+}
+
+func openChannel() (*mq.Channel, int, error) {
+	if flag.NArg() != 2 {
+		return nil, 0, fmt.Errorf("must provide exactly one argument")
+	}
+	n, err := strconv.Atoi(flag.Arg(1))
+	if err != nil {
+		return nil, 0, err
+	}
+	capacity, elemSize, err := parseTwoInts(*options)
+	if err != nil {
+		return nil, 0, err
+	}
+	ch, err := mq.NewChannel(*objName, capacity, elemSize, 0666)
+	return ch, n, err
+}
+
+func chansend() error {
+	ch, n, err := openChannel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+	for i := 0; i < n; i++ {
+		if err = ch.Send(typedQueueTestStruct{Idx: int64(i), Data: [4]int32{int32(i), int32(i + 1), int32(i + 2), int32(i + 3)}}); err != nil {
+			return err
+		}
+	}
+	ch.Shutdown()
+	return nil
+}
+
+func chanrecv() error {
+	ch, n, err := openChannel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+	for i := 0; ; i++ {
+		var received typedQueueTestStruct
+		ok, err := ch.Recv(&received)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if i != n {
+				return fmt.Errorf("expected %d values, got %d", n, i)
+			}
+			return nil
+		}
+		expected := typedQueueTestStruct{Idx: int64(i), Data: [4]int32{int32(i), int32(i + 1), int32(i + 2), int32(i + 3)}}
+		if received != expected {
+			return fmt.Errorf("invalid value at %d. expected '%v', got '%v'", i, expected, received)
+		}
+	}
+}
+
 func runCommand() error {
 	command := flag.Arg(0)
 	switch command {
@@ -128,6 +398,22 @@ func runCommand() error {
 			return fmt.Errorf("notifywait: must not provide any arguments")
 		}
 		return notifywait(*objName, *timeout, *typ)
+	case "drain":
+		return drain()
+	case "gobsend":
+		return gobsend()
+	case "gobrecv":
+		return gobrecv()
+	case "allrecv":
+		return allrecv()
+	case "typedrecv":
+		return typedrecv()
+	case "pipeecho":
+		return pipeecho()
+	case "chansend":
+		return chansend()
+	case "chanrecv":
+		return chanrecv()
 	default:
 		return fmt.Errorf("unknown command")
 	}
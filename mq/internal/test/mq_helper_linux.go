@@ -5,6 +5,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"bitbucket.org/avd/go-ipc/mq"
@@ -16,6 +17,12 @@ func createMqWithType(name string, perm os.FileMode, typ, opt string) (mq.Messen
 		return mq.New(name, os.O_RDWR, perm)
 	case "sysv":
 		return mq.CreateSystemVMessageQueue(name, os.O_RDWR, perm)
+	case "sysvkey":
+		key, err := strconv.Atoi(name)
+		if err != nil {
+			return nil, err
+		}
+		return mq.CreateSystemVMessageQueueKey(key, perm)
 	case "fast":
 		mqSize, msgSize := mq.DefaultLinuxMqMaxSize, mq.DefaultLinuxMqMessageSize
 		if first, second, err := parseTwoInts(opt); err == nil {
@@ -39,6 +46,12 @@ func openMqWithType(name string, flags int, typ string) (mq.Messenger, error) {
 		return mq.Open(name, flags)
 	case "sysv":
 		return mq.OpenSystemVMessageQueue(name, flags)
+	case "sysvkey":
+		key, err := strconv.Atoi(name)
+		if err != nil {
+			return nil, err
+		}
+		return mq.OpenSystemVMessageQueueKey(key, flags)
 	case "fast":
 		return mq.OpenFastMq(name, flags)
 	case "linux":
@@ -54,6 +67,16 @@ func destroyMqWithType(name, typ string) error {
 		return mq.Destroy(name)
 	case "sysv":
 		return mq.DestroySystemVMessageQueue(name)
+	case "sysvkey":
+		key, err := strconv.Atoi(name)
+		if err != nil {
+			return err
+		}
+		q, err := mq.OpenSystemVMessageQueueKey(key, 0)
+		if err != nil {
+			return err
+		}
+		return q.Destroy()
 	case "fast":
 		return mq.DestroyFastMq(name)
 	case "linux":
@@ -7,6 +7,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"strconv"
 
 	"bitbucket.org/avd/go-ipc/mq"
 )
@@ -23,6 +24,12 @@ func createMqWithType(name string, perm os.FileMode, typ, opt string) (mq.Messen
 		return mq.CreateFastMq(name, 0, perm, mqSize, msgSize)
 	case "sysv":
 		return mq.CreateSystemVMessageQueue(name, 0, perm)
+	case "sysvkey":
+		key, err := strconv.Atoi(name)
+		if err != nil {
+			return nil, err
+		}
+		return mq.CreateSystemVMessageQueueKey(key, perm)
 	default:
 		return nil, fmt.Errorf("unknown mq type %q", typ)
 	}
@@ -36,6 +43,12 @@ func openMqWithType(name string, flags int, typ string) (mq.Messenger, error) {
 		return mq.OpenFastMq(name, flags)
 	case "sysv":
 		return mq.OpenSystemVMessageQueue(name, flags)
+	case "sysvkey":
+		key, err := strconv.Atoi(name)
+		if err != nil {
+			return nil, err
+		}
+		return mq.OpenSystemVMessageQueueKey(key, flags)
 	default:
 		return nil, fmt.Errorf("unknown mq type %q", typ)
 	}
@@ -49,6 +62,16 @@ func destroyMqWithType(name, typ string) error {
 		return mq.DestroyFastMq(name)
 	case "sysv":
 		return mq.DestroySystemVMessageQueue(name)
+	case "sysvkey":
+		key, err := strconv.Atoi(name)
+		if err != nil {
+			return err
+		}
+		q, err := mq.OpenSystemVMessageQueueKey(key, 0)
+		if err != nil {
+			return err
+		}
+		return q.Destroy()
 	default:
 		return fmt.Errorf("unknown mq type %q", typ)
 	}
@@ -11,7 +11,15 @@ import (
 
 func createMqWithType(name string, perm os.FileMode, typ, opt string) (mq.Messenger, error) {
 	switch typ {
-	case "default", "fast":
+	case "default":
+		return mq.New(name, 0, perm)
+	case "windows":
+		mqSize, msgSize := mq.DefaultWindowsMqMaxSize, mq.DefaultWindowsMqMessageSize
+		if first, second, err := parseTwoInts(opt); err == nil {
+			mqSize, msgSize = first, second
+		}
+		return mq.CreateWindowsMessageQueue(name, 0, perm, mqSize, msgSize)
+	case "fast":
 		mqSize, msgSize := mq.DefaultFastMqMaxSize, mq.DefaultFastMqMessageSize
 		if first, second, err := parseTwoInts(opt); err == nil {
 			mqSize, msgSize = first, second
@@ -24,7 +32,11 @@ func createMqWithType(name string, perm os.FileMode, typ, opt string) (mq.Messen
 
 func openMqWithType(name string, flags int, typ string) (mq.Messenger, error) {
 	switch typ {
-	case "default", "fast":
+	case "default":
+		return mq.Open(name, flags)
+	case "windows":
+		return mq.OpenWindowsMessageQueue(name, flags)
+	case "fast":
 		return mq.OpenFastMq(name, flags)
 	default:
 		return nil, fmt.Errorf("unknown mq type %q", typ)
@@ -33,7 +45,11 @@ func openMqWithType(name string, flags int, typ string) (mq.Messenger, error) {
 
 func destroyMqWithType(name, typ string) error {
 	switch typ {
-	case "default", "fast":
+	case "default":
+		return mq.Destroy(name)
+	case "windows":
+		return mq.DestroyWindowsMessageQueue(name)
+	case "fast":
 		return mq.DestroyFastMq(name)
 	default:
 		return fmt.Errorf("unknown mq type %q", typ)
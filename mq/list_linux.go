@@ -0,0 +1,64 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/nxgtw/go-ipc/internal/common"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultMqueuePath = "/dev/mqueue"
+)
+
+// ListLinuxMessageQueues returns the names of all the linux message queues in the system.
+// It reads the directory, where the mqueue filesystem is mounted, so it must be mounted.
+// The result includes queues created by other programs.
+// If a namespace prefix is set, only the queues from this namespace are returned, without the prefix.
+func ListLinuxMessageQueues() ([]string, error) {
+	dir, err := mqueueDirectory()
+	if err != nil {
+		return nil, err
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read mqueue directory")
+	}
+	result := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if name, ok := common.UnprefixedName(info.Name()); ok {
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
+
+// mqueueDirectory returns the mount point of the mqueue filesystem.
+func mqueueDirectory() (string, error) {
+	file, err := os.Open("/proc/mounts")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read mount points")
+	}
+	defer file.Close()
+	var dir string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 2 && fields[2] == "mqueue" {
+			if fields[1] == defaultMqueuePath {
+				return fields[1], nil
+			}
+			dir = fields[1]
+		}
+	}
+	if len(dir) == 0 {
+		return "", errors.New("mqueue filesystem is not mounted")
+	}
+	return dir, nil
+}
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/nxgtw/go-ipc/internal/common"
+
+	"github.com/pkg/errors"
 )
 
 const (
@@ -69,11 +71,16 @@ type PriorityMessenger interface {
 //	flag - create flags. You can specify:
 //		os.O_EXCL if you don't want to open a queue if it exists.
 //		O_NONBLOCK if you don't want to block on send/receive.
-//			This flag may not be supported by a particular implementation. To be sure, you can convert Messenger
-//			to Blocker and call SetBlocking to set/unset non-blocking mode.
+//			This flag may not be supported by a particular implementation. To be sure, you can call
+//			SetBlocking(m, false), which works with any implementation of Blocker.
 //	perm - permissions for the new queue.
 func New(name string, flag int, perm os.FileMode) (Messenger, error) {
-	return createMQ(name, flag, perm)
+	mq, err := createMQ(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	common.AutoRegisterForCleanup(mq)
+	return mq, nil
 }
 
 // Open opens a mq with a given name and flags.
@@ -82,19 +89,71 @@ func New(name string, flag int, perm os.FileMode) (Messenger, error) {
 //	name  - unique queue name.
 //	flags - 0 or O_NONBLOCK.
 func Open(name string, flags int) (Messenger, error) {
-	return openMQ(name, flags)
+	mq, err := openMQ(name, flags)
+	if err != nil {
+		return nil, err
+	}
+	common.AutoRegisterForCleanup(mq)
+	return mq, nil
 }
 
 // Destroy permanently removes mq object.
+// It returns nil, if the queue does not exist.
 func Destroy(name string) error {
 	return destroyMq(name)
 }
 
+// Exists returns true, if a queue with the given name exists.
+// It returns false and an error, if the queue can't be opened
+// for any reason other, than its absence, for example, because of the permissions.
+func Exists(name string) (bool, error) {
+	mq, err := Open(name, 0)
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, mq.Close()
+}
+
+// MustDestroy permanently removes mq object.
+// Unlike Destroy, it returns an error, if the queue does not exist.
+// In this case os.IsNotExist(errors.Cause(err)) is true.
+func MustDestroy(name string) error {
+	mq, err := Open(name, 0)
+	if err != nil {
+		return errors.Wrap(err, "failed to open the queue")
+	}
+	mq.Close()
+	return Destroy(name)
+}
+
+// SetBlocking sets or unsets non-blocking mode of a messenger.
+// It returns an error, if the messenger does not implement Blocker.
+func SetBlocking(m Messenger, blocking bool) error {
+	blocker, ok := m.(Blocker)
+	if !ok {
+		return errors.Errorf("%T does not support non-blocking mode", m)
+	}
+	return blocker.SetBlocking(blocking)
+}
+
 func checkMqPerm(perm os.FileMode) bool {
 	return uint(perm)&0111 == 0
 }
 
 // IsTemporary returns true, if an error is a timeout error.
 func IsTemporary(err error) bool {
+	if mqErr, ok := err.(*MqError); ok {
+		return mqErr.Temporary()
+	}
 	return common.IsTimeoutErr(err) || isTemporaryError(err)
 }
+
+// logMqEvent passes a send/receive event to the logger, if it is installed.
+func logMqEvent(event, name string, prio, size int) {
+	if logger := common.CurrentLogger(); logger != nil {
+		logger(event, map[string]interface{}{"name": name, "prio": prio, "size": size})
+	}
+}
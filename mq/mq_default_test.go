@@ -0,0 +1,116 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"os"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func defaultMqCtor(name string, flag int, perm os.FileMode) (Messenger, error) {
+	return New(name, flag, perm)
+}
+
+func defaultMqOpener(name string, flags int) (Messenger, error) {
+	return Open(name, flags)
+}
+
+func TestCreateDefaultMq(t *testing.T) {
+	testCreateMq(t, defaultMqCtor, Destroy)
+}
+
+func TestOpenDefaultMq(t *testing.T) {
+	testOpenMq(t, defaultMqCtor, defaultMqOpener, Destroy)
+}
+
+func TestDefaultMqMustDestroy(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(Destroy(testMqName)) {
+		return
+	}
+	a.NoError(Destroy(testMqName))
+	err := MustDestroy(testMqName)
+	if a.Error(err) {
+		a.True(os.IsNotExist(errors.Cause(err)))
+	}
+	mq, err := New(testMqName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	// on windows the queue exists, while it is open.
+	a.NoError(MustDestroy(testMqName))
+	a.NoError(mq.Close())
+	a.Error(MustDestroy(testMqName))
+}
+
+func TestDefaultMqExists(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(Destroy(testMqName)) {
+		return
+	}
+	exists, err := Exists(testMqName)
+	a.NoError(err)
+	a.False(exists)
+	mq, err := New(testMqName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(mq.Close())
+		a.NoError(Destroy(testMqName))
+	}()
+	exists, err = Exists(testMqName)
+	a.NoError(err)
+	a.True(exists)
+}
+
+func TestDefaultMqSendIntSameProcess(t *testing.T) {
+	testMqSendIntSameProcess(t, defaultMqCtor, defaultMqOpener, Destroy)
+}
+
+func TestDefaultMqReceiveTimeout(t *testing.T) {
+	testMqReceiveTimeout(t, defaultMqCtor, Destroy)
+}
+
+func TestDefaultMqSendToAnotherProcess(t *testing.T) {
+	testMqSendToAnotherProcess(t, defaultMqCtor, Destroy, "default")
+}
+
+func TestDefaultMqSetBlocking(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(Destroy(testMqName)) {
+		return
+	}
+	mq, err := New(testMqName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer Destroy(testMqName)
+	defer mq.Close()
+	if !a.NoError(SetBlocking(mq, false)) {
+		return
+	}
+	_, err = mq.Receive(make([]byte, 8))
+	a.Error(err)
+	a.True(IsTemporary(errors.Cause(err)))
+	a.NoError(SetBlocking(mq, true))
+}
+
+func TestSetBlockingNotSupported(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyPipe(testMqName)) {
+		return
+	}
+	pa, pb, cleanup, err := Pipe(testMqName, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(cleanup())
+	}()
+	a.Error(SetBlocking(pa, false))
+	a.Error(SetBlocking(pb, true))
+}
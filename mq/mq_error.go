@@ -0,0 +1,77 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	mqOpSend    = "send"
+	mqOpReceive = "receive"
+)
+
+// MqError is returned by send and receive operations of linux message queues.
+// It contains the name of the operation and the underlying errno.
+// Use IsFull, IsEmpty, IsTooBig, and IsTimeout to find out the reason of the failure.
+type MqError struct {
+	Op    string
+	Errno syscall.Errno
+}
+
+// Error is to implement error interface.
+func (e *MqError) Error() string {
+	return "mq " + e.Op + " failed: " + e.Errno.Error()
+}
+
+// Temporary returns true, if the operation would block, or its timeout expired.
+func (e *MqError) Temporary() bool {
+	return e.Errno == syscall.EAGAIN || e.Errno == syscall.ETIMEDOUT
+}
+
+// newMqError converts a syscall error into MqError. Other errors are returned as is.
+func newMqError(op string, err error) error {
+	if sysErr, ok := err.(*os.SyscallError); ok {
+		if errno, ok := sysErr.Err.(syscall.Errno); ok {
+			return &MqError{Op: op, Errno: errno}
+		}
+	}
+	return err
+}
+
+// IsFull returns true, if a message was not sent, because the queue was full.
+func IsFull(err error) bool {
+	cause := errors.Cause(err)
+	if cause == mqFullError {
+		return true
+	}
+	mqErr, ok := cause.(*MqError)
+	return ok && mqErr.Op == mqOpSend && mqErr.Temporary()
+}
+
+// IsEmpty returns true, if a message was not received, because the queue was empty.
+func IsEmpty(err error) bool {
+	cause := errors.Cause(err)
+	if cause == mqEmptyError {
+		return true
+	}
+	mqErr, ok := cause.(*MqError)
+	return ok && mqErr.Op == mqOpReceive && mqErr.Temporary()
+}
+
+// IsTooBig returns true, if a message was not sent, because it is larger, than the max message size of the queue,
+// or if it was not received, because the buffer was too small.
+func IsTooBig(err error) bool {
+	mqErr, ok := errors.Cause(err).(*MqError)
+	return ok && mqErr.Errno == syscall.EMSGSIZE
+}
+
+// IsTimeout returns true, if an operation could not be completed in time,
+// or would block on a queue in non-blocking mode. It is the same as IsTemporary,
+// however, it also handles errors wrapped with github.com/pkg/errors.
+func IsTimeout(err error) bool {
+	return IsTemporary(errors.Cause(err))
+}
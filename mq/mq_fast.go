@@ -28,13 +28,15 @@ const (
 // this is to ensure, that FastMq satisfies queue interfaces.
 var (
 	_ Messenger         = (*FastMq)(nil)
+	_ Blocker           = (*FastMq)(nil)
 	_ TimedMessenger    = (*FastMq)(nil)
 	_ PriorityMessenger = (*FastMq)(nil)
 )
 
 var (
-	mqFullError  = newTemporaryError(errors.New("the queue is full"))
-	mqEmptyError = newTemporaryError(errors.New("the queue is empty"))
+	mqFullError   = newTemporaryError(errors.New("the queue is full"))
+	mqEmptyError  = newTemporaryError(errors.New("the queue is empty"))
+	mqClosedError = errors.New("the queue is closed")
 )
 
 // FastMq is a priority message queue based on shared memory.
@@ -180,6 +182,9 @@ func (mq *FastMq) SendTimeout(data []byte, timeout time.Duration) error {
 // SendPriorityTimeout sends a message with the given priority. It blocks if the queue is full,
 // waiting for not longer, then the timeout.
 func (mq *FastMq) SendPriorityTimeout(data []byte, prio int, timeout time.Duration) error {
+	if mq.impl == nil {
+		return mqClosedError
+	}
 	if len(data) > mq.impl.heap.maxMsgSize() {
 		return errors.New("the message is too big")
 	}
@@ -206,6 +211,7 @@ func (mq *FastMq) SendPriorityTimeout(data []byte, prio int, timeout time.Durati
 		mq.condRecv.Signal()
 	}
 	mq.locker.Unlock()
+	logMqEvent("mq_send", mq.name, prio, len(data))
 
 	return nil
 }
@@ -231,6 +237,9 @@ func (mq *FastMq) ReceiveTimeout(data []byte, timeout time.Duration) (int, error
 // ReceivePriorityTimeout receives a message and returns its priority. It blocks if the queue is empty,
 // waiting for not longer, then the timeout.
 func (mq *FastMq) ReceivePriorityTimeout(data []byte, timeout time.Duration) (int, int, error) {
+	if mq.impl == nil {
+		return 0, 0, mqClosedError
+	}
 
 	// optimization: do lock the locker if the queue is empty.
 	if mq.flag&O_NONBLOCK != 0 && mq.Empty() {
@@ -255,6 +264,9 @@ func (mq *FastMq) ReceivePriorityTimeout(data []byte, timeout time.Duration) (in
 		mq.condSend.Signal()
 	}
 	mq.locker.Unlock()
+	if err == nil {
+		logMqEvent("mq_receive", mq.name, prio, len)
+	}
 
 	return len, prio, err
 }
@@ -277,6 +289,9 @@ func (mq *FastMq) SetBlocking(block bool) error {
 
 // Close closes a FastMq instance.
 func (mq *FastMq) Close() error {
+	common.UnregisterFromCleanup(mq)
+	// the state is placed in the region, so it can't be used after the region is unmapped.
+	mq.impl = nil
 	errLocker := mq.locker.Close()
 	if errRegion := mq.region.Close(); errRegion != nil {
 		return errors.Wrap(errRegion, "failed to close memory region")
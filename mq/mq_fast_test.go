@@ -5,6 +5,8 @@ package mq
 import (
 	"os"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func fastMqCtor(name string, flag int, perm os.FileMode) (Messenger, error) {
@@ -88,3 +90,25 @@ func BenchmarkFastMqBlock(b *testing.B) {
 	params := &prioBenchmarkParams{readers: 4, writers: 4, mqSize: 8, msgSize: 1024, flag: 0}
 	benchmarkPrioMq1(b, fastMqCtorPrio, fastMqOpenerPrio, fastMqDtor, params)
 }
+
+func TestFastMqErrors(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyFastMq(testMqName)) {
+		return
+	}
+	mq, err := CreateFastMq(testMqName, os.O_EXCL, 0666, 1, 8)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	a.NoError(mq.SetBlocking(false))
+	buff := make([]byte, 8)
+	_, err = mq.Receive(buff)
+	a.True(IsEmpty(err))
+	a.True(IsTimeout(err))
+	a.NoError(mq.Send(buff))
+	err = mq.Send(buff)
+	a.True(IsFull(err))
+	a.True(IsTimeout(err))
+	a.False(IsEmpty(err))
+}
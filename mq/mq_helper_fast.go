@@ -1,6 +1,6 @@
 // Copyright 2016 Aleksandr Demakin. All rights reserved.
 
-//+build windows
+//+build windows,fast_mq
 
 package mq
 
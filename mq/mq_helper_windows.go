@@ -0,0 +1,27 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+//+build !fast_mq
+
+package mq
+
+import "os"
+
+func createMQ(name string, flag int, perm os.FileMode) (Messenger, error) {
+	mq, err := CreateWindowsMessageQueue(name, flag, perm, DefaultWindowsMqMaxSize, DefaultWindowsMqMessageSize)
+	if err != nil {
+		return nil, err
+	}
+	return mq, nil
+}
+
+func openMQ(name string, flags int) (Messenger, error) {
+	mq, err := OpenWindowsMessageQueue(name, flags)
+	if err != nil {
+		return nil, err
+	}
+	return mq, nil
+}
+
+func destroyMq(name string) error {
+	return DestroyWindowsMessageQueue(name)
+}
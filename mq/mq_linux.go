@@ -3,12 +3,21 @@
 package mq
 
 import (
+	"encoding/binary"
+	"io"
 	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 
 	"github.com/nxgtw/go-ipc/internal/allocator"
 	"github.com/nxgtw/go-ipc/internal/common"
+	"github.com/nxgtw/go-ipc/internal/helper"
+	"bitbucket.org/avd/go-ipc/mmf"
+	"bitbucket.org/avd/go-ipc/shm"
+	ipc_sync "bitbucket.org/avd/go-ipc/sync"
 
 	"github.com/pkg/errors"
 	"golang.org/x/sys/unix"
@@ -25,11 +34,31 @@ const (
 // this is to ensure, that linux implementation of ipc mq satisfies queue interfaces.
 var (
 	_ Messenger         = (*LinuxMessageQueue)(nil)
+	_ Blocker           = (*LinuxMessageQueue)(nil)
 	_ TimedMessenger    = (*LinuxMessageQueue)(nil)
 	_ PriorityMessenger = (*LinuxMessageQueue)(nil)
 )
 
+// ReceiveMode defines the behavior of receive operations, if the buffer is too small for a message.
+type ReceiveMode int
+
+const (
+	// ReceiveKeep makes receive operations fail without touching the queue,
+	// if the buffer is smaller, than the maximum message size of the queue.
+	// As a message can't be examined without removing it from the queue, the size of the buffer
+	// is checked against the maximum size, so the message always stays in the queue.
+	ReceiveKeep ReceiveMode = iota
+	// ReceiveTruncate makes receive operations consume the message and copy its first len(data) bytes.
+	// The number of copied bytes is returned, and the truncation is reported separately.
+	ReceiveTruncate
+	// receiveFit is used by receive operations without an explicit mode.
+	// A buffer smaller, than the maximum message size, is accepted, and if the message does not fit,
+	// it is consumed, and an error is returned.
+	receiveFit
+)
+
 // LinuxMessageQueue is a linux-specific ipc mechanism based on message passing.
+// Errors of the system calls of send and receive operations are returned as *MqError.
 type LinuxMessageQueue struct {
 	id           int
 	name         string
@@ -40,6 +69,40 @@ type LinuxMessageQueue struct {
 	// In this case we use inputBuff to receive a message, and if the real size
 	// of the message <= the input buffer size, we copy our buffer into that object.
 	inputBuff []byte
+	// peekMu serializes Peek calls, which take all the messages from the queue and send them back.
+	peekMu sync.Mutex
+	// order is a no-op unless the package is built with 'mq_debug' tag.
+	order orderChecker
+	// counters are placed in a shared memory region and count all sent and received messages.
+	// they are nil, unless EnableCounters has been called.
+	counters       *linuxMqCounters
+	countersRegion *mmf.MemoryRegion
+	// coalescer is not nil, if coalescing is enabled.
+	coalescer *coalescer
+	// maxMsg caches queue capacity, which can't change after the queue is created.
+	// 0 means it has not been queried yet.
+	maxMsg int
+	// receiveBuffs is a pool of *[]byte buffers used by ReceiveTo.
+	receiveBuffs sync.Pool
+	// checkedTypes caches the results of type checks of objects passed to Peek and ReceiveBatch.
+	checkedTypes   map[reflect.Type]error
+	checkedTypesMu sync.RWMutex
+}
+
+// MqNotification is an event sent by NotifyEvent, when a message arrives to an empty queue.
+type MqNotification struct {
+	// Name is the name of the queue.
+	Name string
+	// ID is the id of the queue, see LinuxMessageQueue.ID.
+	ID int
+	// Curmsgs is the number of messages in the queue, when the notification was received.
+	Curmsgs int
+}
+
+// linuxMqCounters is a pair of counters shared by all instances of the queue.
+type linuxMqCounters struct {
+	sent     uint64
+	received uint64
 }
 
 // linuxMqAttr contains attributes of the queue.
@@ -48,6 +111,8 @@ type linuxMqAttr struct {
 	Maxmsg  int /* Max. # of messages on queue */
 	Msgsize int /* Max. message size (bytes) */
 	Curmsgs int /* # of messages currently in queue */
+	// the kernel writes the whole struct mq_attr, including its reserved fields.
+	_ [4]int
 }
 
 // CreateLinuxMessageQueue creates new queue with the given name and permissions.
@@ -60,22 +125,28 @@ func CreateLinuxMessageQueue(name string, flag int, perm os.FileMode, maxQueueSi
 	if !checkMqPerm(perm) {
 		return nil, errors.New("invalid mq permissions")
 	}
-	sysflags := unix.O_CREAT | unix.O_RDWR | unix.O_CLOEXEC
+	sysflags := unix.O_CREAT | unix.O_RDWR | unix.O_CLOEXEC | flag&O_NONBLOCK
 	if flag&os.O_EXCL != 0 {
 		sysflags |= unix.O_EXCL
 	}
-	attrs := &linuxMqAttr{Maxmsg: maxQueueSize, Msgsize: maxMsgSize}
+	attrs := &linuxMqAttr{Maxmsg: maxQueueSize, Msgsize: maxMsgSize + orderStampSize}
 	id, err := mq_open(name, sysflags, uint32(perm), attrs)
 	if err != nil {
 		return nil, errors.Wrap(err, "mq_open failed")
 	}
-	return &LinuxMessageQueue{
+	result := &LinuxMessageQueue{
 		id:           id,
 		name:         name,
 		cancelSocket: -1,
-		inputBuff:    make([]byte, maxMsgSize),
+		inputBuff:    make([]byte, attrs.Msgsize),
 		flags:        flag,
-	}, nil
+		order:        newOrderChecker(),
+	}
+	if flag&os.O_EXCL != 0 {
+		// the queue is new, so the counters left by a previous queue with the same name, if any, are stale.
+		shm.DestroyMemoryObject(linuxMqCountersName(name))
+	}
+	return result, nil
 }
 
 // OpenLinuxMessageQueue opens an existing message queue. It returns an error, if it does not exist.
@@ -97,6 +168,7 @@ func OpenLinuxMessageQueue(name string, flag int) (*LinuxMessageQueue, error) {
 		name:         name,
 		cancelSocket: -1,
 		flags:        flag,
+		order:        newOrderChecker(),
 	}
 	attrs, err := result.getAttrs()
 	if err != nil {
@@ -107,12 +179,70 @@ func OpenLinuxMessageQueue(name string, flag int) (*LinuxMessageQueue, error) {
 	return result, nil
 }
 
+// OpenOrCreateLinuxMessageQueue opens or creates a queue depending on open flags.
+// Queue attributes are applied only if the queue was actually created.
+//	name - unique mq name.
+//	flag - a combination of open flags from 'os' package and O_NONBLOCK:
+//		0 - open an existing queue.
+//		os.O_CREATE - open an existing queue or create a new one.
+//		os.O_CREATE|os.O_EXCL - create a new queue, fail if it exists.
+//	perm - object's permission bits.
+//	maxQueueSize - queue capacity.
+//	maxMsgSize - maximum message size.
+// Returns the queue, and true, if it was created.
+func OpenOrCreateLinuxMessageQueue(name string, flag int, perm os.FileMode, maxQueueSize, maxMsgSize int) (*LinuxMessageQueue, bool, error) {
+	var result *LinuxMessageQueue
+	creator := func(create bool) error {
+		var err error
+		if create {
+			result, err = CreateLinuxMessageQueue(name, (flag&O_NONBLOCK)|os.O_EXCL, perm, maxQueueSize, maxMsgSize)
+		} else {
+			result, err = OpenLinuxMessageQueue(name, common.FlagsForAccess(flag))
+		}
+		return errors.Cause(err)
+	}
+	created, err := common.OpenOrCreate(creator, flag)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to open/create linux mq")
+	}
+	return result, created, nil
+}
+
 // SendTimeoutPriority sends a message with a given priority.
 // It blocks if the queue is full, waiting for a message unless timeout is passed.
+// If coalescing is enabled, buffered messages are flushed first.
 func (mq *LinuxMessageQueue) SendTimeoutPriority(data []byte, prio int, timeout time.Duration) error {
-	return common.UninterruptedSyscallTimeout(func(curTimeout time.Duration) error {
-		return mq_timedsend(mq.ID(), data, prio, common.AbsTimeoutToTimeSpec(curTimeout))
-	}, timeout)
+	if mq.coalescer != nil {
+		if err := mq.coalescer.flush(); err != nil {
+			return errors.Wrap(err, "failed to flush coalesced messages")
+		}
+	}
+	return mq.sendTimeoutPriority(data, prio, timeout)
+}
+
+func (mq *LinuxMessageQueue) sendTimeoutPriority(data []byte, prio int, timeout time.Duration) error {
+	return mq.sendAbsTimeout(data, prio, common.AbsTimeoutToTimeSpec(timeout))
+}
+
+// sendAbsTimeout sends a message waiting until the absolute time ts. nil ts makes the timeout infinite.
+func (mq *LinuxMessageQueue) sendAbsTimeout(data []byte, prio int, ts *unix.Timespec) error {
+	if prioMax := mqPrioMax(); prio < 0 || prio >= prioMax {
+		return errors.Errorf("invalid priority %d: it must be in range [0, %d]", prio, prioMax-1)
+	}
+	size := len(data)
+	data = mq.order.beginSend(data, prio)
+	err := common.UninterruptedSyscall(func() error {
+		return mq_timedsend(mq.ID(), data, prio, ts)
+	})
+	mq.order.endSend()
+	if err != nil {
+		return newMqError(mqOpSend, err)
+	}
+	if mq.counters != nil {
+		atomic.AddUint64(&mq.counters.sent, 1)
+	}
+	logMqEvent("mq_send", mq.name, prio, size)
+	return nil
 }
 
 // SendPriority sends a message with a given priority.
@@ -125,6 +255,13 @@ func (mq *LinuxMessageQueue) SendPriority(data []byte, prio int) error {
 	return mq.SendTimeoutPriority(data, prio, timeout)
 }
 
+// SendRemap sends a message with a priority, which is translated into the raw priority with remap.
+// It blocks if the queue is full. remap must be pure.
+// It is the counterpart of ReceiveRemap.
+func (mq *LinuxMessageQueue) SendRemap(data []byte, prio int, remap func(prio int) int) error {
+	return mq.SendPriority(data, remap(prio))
+}
+
 // SendTimeout sends a message with a default (0) priority.
 // It blocks if the queue is full, waiting for a message unless timeout is passed.
 func (mq *LinuxMessageQueue) SendTimeout(data []byte, timeout time.Duration) error {
@@ -133,48 +270,189 @@ func (mq *LinuxMessageQueue) SendTimeout(data []byte, timeout time.Duration) err
 
 // Send sends a message with a default (0) priority.
 // It blocks if the queue is full.
+// If coalescing is enabled, the message is buffered, see EnableCoalescing.
 func (mq *LinuxMessageQueue) Send(data []byte) error {
-	timeout := time.Duration(-1)
+	if mq.coalescer != nil {
+		return mq.coalescer.add(data)
+	}
+	return mq.SendTimeoutPriority(data, 0, mq.sendTimeout())
+}
+
+// EnableCoalescing makes Send accumulate messages in a buffer and send them as a single message,
+// when their total size reaches maxBytes, or when maxDelay has passed since the first buffered message.
+// This reduces the number of syscalls and queue slots used by chatty producers
+// at the cost of latency: a message can be delivered up to maxDelay later, than it was sent.
+// The receiver must use UnpackCoalesced to recover the original messages.
+// Buffered messages are sent with default priority and can be flushed explicitly with Flush.
+// Other send operations flush the buffer before sending their message.
+// An error of a flush, which was made on timeout, is returned by the next call to Send or Flush.
+//	maxBytes - maximum size of a coalesced message. it must not exceed max message size of the queue.
+//		pass 0 to flush buffered messages and disable coalescing.
+//	maxDelay - maximum time messages can be buffered. if it is not positive,
+//		messages are flushed only when the buffer is full, or on Flush.
+func (mq *LinuxMessageQueue) EnableCoalescing(maxBytes int, maxDelay time.Duration) error {
+	if mq.coalescer != nil {
+		err := mq.coalescer.close()
+		mq.coalescer = nil
+		if err != nil {
+			return errors.Wrap(err, "failed to flush coalesced messages")
+		}
+	}
+	if maxBytes <= 0 {
+		return nil
+	}
+	if maxBytes <= coalescedHdrSize || maxBytes > len(mq.inputBuff)-orderStampSize {
+		return errors.Errorf("invalid coalesced message size %d", maxBytes)
+	}
+	mq.coalescer = newCoalescer(maxBytes, maxDelay, func(data []byte) error {
+		return mq.sendTimeoutPriority(data, 0, mq.sendTimeout())
+	})
+	return nil
+}
+
+// Flush sends messages buffered by Send, if coalescing is enabled.
+func (mq *LinuxMessageQueue) Flush() error {
+	if mq.coalescer == nil {
+		return nil
+	}
+	return mq.coalescer.flush()
+}
+
+// sendTimeout returns the timeout of blocking send operations.
+func (mq *LinuxMessageQueue) sendTimeout() time.Duration {
 	if mq.flags&O_NONBLOCK != 0 {
-		timeout = time.Duration(0)
+		return time.Duration(0)
 	}
-	return mq.SendTimeoutPriority(data, 0, timeout)
+	return time.Duration(-1)
 }
 
 // ReceiveTimeoutPriority receives a message, returning its priority.
 // It blocks if the queue is empty, waiting for a message unless timeout is passed.
 // Returns message len and priority.
+// The buffer can be smaller, than the maximum message size, however, if the message does not fit,
+// it is removed from the queue, and an error is returned. Use ReceiveTimeoutPriorityMode
+// with ReceiveKeep to never lose messages.
 func (mq *LinuxMessageQueue) ReceiveTimeoutPriority(input []byte, timeout time.Duration) (int, int, error) {
-	dataToReceive := input
+	n, prio, _, err := mq.receiveTimeoutPriorityMode(input, timeout, receiveFit)
+	return n, prio, err
+}
+
+// ReceiveTimeoutPriorityMode receives a message, returning its priority.
+// It blocks if the queue is empty, waiting for a message unless timeout is passed.
+// Returns the number of bytes copied into input, message priority,
+// and true, if the message was truncated, which is possible with ReceiveTruncate mode only.
+//	mode - defines what to do, if the buffer is too small for the message.
+func (mq *LinuxMessageQueue) ReceiveTimeoutPriorityMode(input []byte, timeout time.Duration, mode ReceiveMode) (int, int, bool, error) {
+	if mode != ReceiveKeep && mode != ReceiveTruncate {
+		return 0, 0, false, errors.Errorf("invalid receive mode %d", mode)
+	}
+	return mq.receiveTimeoutPriorityMode(input, timeout, mode)
+}
+
+func (mq *LinuxMessageQueue) receiveTimeoutPriorityMode(input []byte, timeout time.Duration, mode ReceiveMode) (int, int, bool, error) {
+	return mq.receiveAbsTimeoutMode(input, common.AbsTimeoutToTimeSpec(timeout), mode)
+}
+
+// receiveAbsTimeoutMode receives a message waiting until the absolute time ts. nil ts makes the timeout infinite.
+func (mq *LinuxMessageQueue) receiveAbsTimeoutMode(input []byte, ts *unix.Timespec, mode ReceiveMode) (int, int, bool, error) {
 	curMaxMsgSize := len(mq.inputBuff)
+	if mode == ReceiveKeep && len(input) < curMaxMsgSize-orderStampSize {
+		return 0, 0, false, errors.Errorf("the buffer of %d bytes is smaller, than the maximum message size of %d bytes",
+			len(input), curMaxMsgSize-orderStampSize)
+	}
+	dataToReceive := input
 	if len(input) < curMaxMsgSize {
 		dataToReceive = mq.inputBuff
 	}
 	var prio, actualMsgSize, maxMsgSize int
-	err := common.UninterruptedSyscallTimeout(func(curTimeout time.Duration) error {
+	err := common.UninterruptedSyscall(func() error {
 		var err error
-		actualMsgSize, maxMsgSize, err = mq_timedreceive(
-			mq.ID(),
-			dataToReceive,
-			&prio,
-			common.AbsTimeoutToTimeSpec(curTimeout))
+		actualMsgSize, maxMsgSize, err = mq_timedreceive(mq.ID(), dataToReceive, &prio, ts)
 		return err
-	}, timeout)
+	})
 	if maxMsgSize != 0 && actualMsgSize != 0 {
 		if curMaxMsgSize != maxMsgSize {
 			mq.inputBuff = make([]byte, maxMsgSize)
 		}
 	}
 	if err != nil {
-		return 0, 0, errors.Wrap(err, "linux mq: receive failed")
+		return 0, 0, false, errors.Wrap(newMqError(mqOpReceive, err), "linux mq: receive failed")
 	}
+	if mq.counters != nil {
+		atomic.AddUint64(&mq.counters.received, 1)
+	}
+	actualMsgSize = mq.order.verify(dataToReceive[:actualMsgSize], prio)
+	logMqEvent("mq_receive", mq.name, prio, actualMsgSize)
 	if len(input) < curMaxMsgSize {
-		if len(input) < actualMsgSize {
-			return 0, 0, errors.Errorf("the buffer of %d bytes is too small for a %d bytes message", len(input), actualMsgSize)
+		if len(input) < actualMsgSize && mode == receiveFit {
+			return 0, 0, false, errors.Errorf("the buffer of %d bytes is too small for a %d bytes message", len(input), actualMsgSize)
 		}
-		copy(input, dataToReceive[:actualMsgSize])
+		n := copy(input, dataToReceive[:actualMsgSize])
+		return n, prio, n < actualMsgSize, nil
 	}
-	return actualMsgSize, prio, nil
+	return actualMsgSize, prio, false, nil
+}
+
+// Peek returns the message, which would be received next, without removing it.
+// It blocks if the queue is empty, unless the queue is in non-blocking mode.
+// As POSIX queues can't examine messages without removing them, Peek receives all the messages
+// and sends them back with their priorities, so that their order is preserved.
+// Concurrent Peek calls on the instance are serialized, but other receivers and senders,
+// including the ones in other processes, are not blocked. So, with several readers, a peeked message
+// can be received by another reader, and messages sent during the call can be placed
+// before the messages being sent back. If the messages can't be sent back, an error is returned,
+// and the messages, which were not sent, are lost.
+// It returns an error, if the queue was opened with os.O_WRONLY.
+//	object - a pointer to an object or a slice, which must not contain any references.
+//	prio - if not nil, the priority of the message is stored here.
+func (mq *LinuxMessageQueue) Peek(object interface{}, prio *int) error {
+	if mq.flags&(os.O_WRONLY|os.O_RDWR) == os.O_WRONLY {
+		return errors.New("the queue was opened for writing only")
+	}
+	if err := checkReceiveObject(object); err != nil {
+		return err
+	}
+	data, err := mq.objectData(object)
+	if err != nil {
+		return errors.Wrap(err, "failed to get object data")
+	}
+	mq.peekMu.Lock()
+	defer mq.peekMu.Unlock()
+	timeout := time.Duration(-1)
+	if mq.flags&O_NONBLOCK != 0 {
+		timeout = time.Duration(0)
+	}
+	var messages []Message
+	buff := make([]byte, len(mq.inputBuff))
+	for {
+		n, msgPrio, err := mq.ReceiveTimeoutPriority(buff, timeout)
+		if err != nil {
+			if len(messages) > 0 && IsTemporary(errors.Cause(err)) {
+				break
+			}
+			if len(messages) > 0 {
+				mq.resend(messages)
+			}
+			return err
+		}
+		msgData := make([]byte, n)
+		copy(msgData, buff[:n])
+		messages = append(messages, Message{Data: msgData, Prio: msgPrio})
+		timeout = 0
+	}
+	if err = mq.resend(messages); err != nil {
+		return errors.Wrap(err, "failed to send messages back")
+	}
+	first := messages[0]
+	if len(data) < len(first.Data) {
+		return errors.Errorf("the object of %d bytes is too small for a %d bytes message", len(data), len(first.Data))
+	}
+	copy(data, first.Data)
+	allocator.UseValue(object)
+	if prio != nil {
+		*prio = first.Prio
+	}
+	return nil
 }
 
 // ReceivePriority receives a message, returning its priority.
@@ -187,6 +465,18 @@ func (mq *LinuxMessageQueue) ReceivePriority(data []byte) (int, int, error) {
 	return mq.ReceiveTimeoutPriority(data, timeout)
 }
 
+// ReceiveRemap receives a message, translating its priority with remap.
+// It blocks if the queue is empty. Returns message len and translated priority.
+// remap is applied to the raw priority after the message has been received, so it must be pure.
+// It allows to isolate application priority semantics from platform priority ranges.
+func (mq *LinuxMessageQueue) ReceiveRemap(data []byte, remap func(raw int) int) (int, int, error) {
+	l, prio, err := mq.ReceivePriority(data)
+	if err != nil {
+		return 0, 0, err
+	}
+	return l, remap(prio), nil
+}
+
 // ReceiveTimeout receives a message.
 // It blocks if the queue is empty, waiting for a message unless timeout is passed.
 // Returns message len.
@@ -206,35 +496,335 @@ func (mq *LinuxMessageQueue) Receive(data []byte) (int, error) {
 	return len, err
 }
 
+// ReceiveBatch receives up to max messages into a slice of objects.
+// It receives at least one message, blocking if the queue is empty, and then receives
+// the rest of available messages without blocking, until the queue is empty or max messages are received.
+// If the queue is in non-blocking mode (see SetBlocking), the first receive doesn't block either,
+// and a temporary error is returned, if there are no messages.
+// Returns the number of received messages. If the queue becomes empty before max messages are received,
+// the error is nil. If some other error occurs, the messages received before it are kept.
+//	objects - a pointer to a slice of objects, which must not contain any references.
+//		the slice is truncated and then filled with received messages.
+//	prios - if not nil, priorities of received messages are stored here. it must be at least max elements long.
+//	max - maximum number of messages to receive.
+func (mq *LinuxMessageQueue) ReceiveBatch(objects interface{}, prios []int, max int) (int, error) {
+	if max <= 0 {
+		return 0, errors.New("max must be positive")
+	}
+	if prios != nil && len(prios) < max {
+		return 0, errors.Errorf("prios slice is too small for %d messages", max)
+	}
+	ptr := reflect.ValueOf(objects)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() || ptr.Elem().Kind() != reflect.Slice {
+		return 0, errors.New("objects must be a non-nil pointer to a slice")
+	}
+	slice := ptr.Elem()
+	elemType := slice.Type().Elem()
+	if err := mq.checkType(reflect.PtrTo(elemType)); err != nil {
+		return 0, errors.Wrap(err, "invalid object type")
+	}
+	if slice.Cap() < max {
+		slice.Set(reflect.MakeSlice(slice.Type(), 0, max))
+	} else {
+		slice.SetLen(0)
+	}
+	timeout := time.Duration(-1)
+	if mq.flags&O_NONBLOCK != 0 {
+		timeout = time.Duration(0)
+	}
+	var received int
+	for ; received < max; received++ {
+		slice.SetLen(received + 1)
+		elem := slice.Index(received)
+		elem.Set(reflect.Zero(elemType))
+		data, err := allocator.ValueData(elem.Addr())
+		if err != nil {
+			slice.SetLen(received)
+			return received, errors.Wrap(err, "failed to get object data")
+		}
+		_, prio, err := mq.ReceiveTimeoutPriority(data, timeout)
+		if err != nil {
+			slice.SetLen(received)
+			if received > 0 && IsTemporary(errors.Cause(err)) {
+				return received, nil
+			}
+			return received, err
+		}
+		if prios != nil {
+			prios[received] = prio
+		}
+		timeout = 0
+	}
+	return received, nil
+}
+
+// ReceiveTo receives a message and writes it into w.
+// The message is received into a buffer from a pool, so the call does not allocate memory for the message.
+// It blocks if the queue is empty. Returns the number of bytes written into w.
+// If w fails, the message is lost.
+//	prio - if not nil, the priority of the message is stored here.
+func (mq *LinuxMessageQueue) ReceiveTo(w io.Writer, prio *int) (int, error) {
+	buffPtr, _ := mq.receiveBuffs.Get().(*[]byte)
+	if buffPtr == nil || len(*buffPtr) < len(mq.inputBuff) {
+		buff := make([]byte, len(mq.inputBuff))
+		buffPtr = &buff
+	}
+	defer mq.receiveBuffs.Put(buffPtr)
+	n, msgPrio, err := mq.ReceivePriority(*buffPtr)
+	if err != nil {
+		return 0, err
+	}
+	if prio != nil {
+		*prio = msgPrio
+	}
+	return w.Write((*buffPtr)[:n])
+}
+
+// TrySend makes one attempt to send an object with the given priority without blocking.
+// The blocking mode of the queue is not changed. It returns false and no error, if the queue is full.
+//	object - an object, a pointer to an object, or a slice, which must not contain any references.
+func (mq *LinuxMessageQueue) TrySend(object interface{}, prio int) (bool, error) {
+	data, err := mq.objectData(object)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get object data")
+	}
+	err = mq.SendTimeoutPriority(data, prio, 0)
+	allocator.UseValue(object)
+	if err != nil {
+		if IsFull(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// TryReceive makes one attempt to receive a message into an object without blocking.
+// The blocking mode of the queue is not changed. It returns false and no error, if the queue is empty.
+//	object - a pointer to an object or a slice, which must not contain any references.
+//	prio - if not nil, the priority of the message is stored here.
+func (mq *LinuxMessageQueue) TryReceive(object interface{}, prio *int) (bool, error) {
+	if err := checkReceiveObject(object); err != nil {
+		return false, err
+	}
+	data, err := mq.objectData(object)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get object data")
+	}
+	_, msgPrio, err := mq.ReceiveTimeoutPriority(data, 0)
+	allocator.UseValue(object)
+	if err != nil {
+		if IsEmpty(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if prio != nil {
+		*prio = msgPrio
+	}
+	return true, nil
+}
+
+// SendDeadline sends an object with a given priority. It blocks if the queue is full,
+// waiting for not longer, than until the deadline. The deadline is passed to the kernel as is,
+// so it does not drift, if the call is interrupted and restarted.
+// If the deadline has already passed, a timeout error is returned without an attempt to send.
+//	object - an object or a slice, which must not contain any references.
+func (mq *LinuxMessageQueue) SendDeadline(object interface{}, prio int, deadline time.Time) error {
+	if !time.Now().Before(deadline) {
+		return &MqError{Op: mqOpSend, Errno: unix.ETIMEDOUT}
+	}
+	data, err := mq.objectData(object)
+	if err != nil {
+		return errors.Wrap(err, "failed to get object data")
+	}
+	if mq.coalescer != nil {
+		if err = mq.coalescer.flush(); err != nil {
+			return errors.Wrap(err, "failed to flush coalesced messages")
+		}
+	}
+	ts := unix.NsecToTimespec(deadline.UnixNano())
+	err = mq.sendAbsTimeout(data, prio, &ts)
+	allocator.UseValue(object)
+	return err
+}
+
+// ReceiveDeadline receives a message into an object. It blocks if the queue is empty,
+// waiting for not longer, than until the deadline. The deadline is passed to the kernel as is,
+// so it does not drift, if the call is interrupted and restarted.
+// If the deadline has already passed, a timeout error is returned without an attempt to receive.
+//	object - a pointer to an object or a slice, which must not contain any references.
+//	prio - if not nil, the priority of the message is stored here.
+func (mq *LinuxMessageQueue) ReceiveDeadline(object interface{}, prio *int, deadline time.Time) error {
+	if err := checkReceiveObject(object); err != nil {
+		return err
+	}
+	if !time.Now().Before(deadline) {
+		return &MqError{Op: mqOpReceive, Errno: unix.ETIMEDOUT}
+	}
+	data, err := mq.objectData(object)
+	if err != nil {
+		return errors.Wrap(err, "failed to get object data")
+	}
+	ts := unix.NsecToTimespec(deadline.UnixNano())
+	_, msgPrio, _, err := mq.receiveAbsTimeoutMode(data, &ts, receiveFit)
+	allocator.UseValue(object)
+	if err != nil {
+		return err
+	}
+	if prio != nil {
+		*prio = msgPrio
+	}
+	return nil
+}
+
+// objectData returns the data of the object like allocator.ObjectData does.
+// The type of the object is checked once, and then the result of the check is cached.
+func (mq *LinuxMessageQueue) objectData(object interface{}) ([]byte, error) {
+	value := reflect.ValueOf(object)
+	if err := mq.checkType(value.Type()); err != nil {
+		return nil, err
+	}
+	return allocator.ValueData(value)
+}
+
+// checkType checks, that objects of the given type don't contain references.
+// The results are cached, as the checks are expensive for complex types.
+func (mq *LinuxMessageQueue) checkType(t reflect.Type) error {
+	mq.checkedTypesMu.RLock()
+	err, ok := mq.checkedTypes[t]
+	mq.checkedTypesMu.RUnlock()
+	if ok {
+		return err
+	}
+	err = allocator.CheckObjectReferences(reflect.Zero(t).Interface())
+	mq.checkedTypesMu.Lock()
+	if mq.checkedTypes == nil {
+		mq.checkedTypes = make(map[reflect.Type]error)
+	}
+	mq.checkedTypes[t] = err
+	mq.checkedTypesMu.Unlock()
+	return err
+}
+
+// lenPrefixSize is the size of a length prefix of messages sent with SendLen.
+const lenPrefixSize = 4
+
+// SendLen sends a message with a given priority, prepending it with its length.
+// Such messages must be received with ReceiveLen.
+// The length of data must not exceed max message size of the queue minus 4 bytes.
+// It blocks if the queue is full.
+func (mq *LinuxMessageQueue) SendLen(data []byte, prio int) error {
+	msg := make([]byte, lenPrefixSize+len(data))
+	binary.LittleEndian.PutUint32(msg, uint32(len(data)))
+	copy(msg[lenPrefixSize:], data)
+	return mq.SendPriority(msg, prio)
+}
+
+// ReceiveLen receives a message sent with SendLen.
+// It returns the number of meaningful bytes written by the sender.
+// It blocks if the queue is empty.
+//	prio - if not nil, the priority of the message is stored here.
+func (mq *LinuxMessageQueue) ReceiveLen(data []byte, prio *int) (int, error) {
+	msg := make([]byte, len(mq.inputBuff))
+	n, msgPrio, err := mq.ReceivePriority(msg)
+	if err != nil {
+		return 0, err
+	}
+	if n < lenPrefixSize {
+		return 0, errors.New("the message does not have a length prefix")
+	}
+	size := int(binary.LittleEndian.Uint32(msg))
+	if size > n-lenPrefixSize {
+		return 0, errors.Errorf("invalid message length %d", size)
+	}
+	if size > len(data) {
+		return 0, errors.Errorf("the buffer of %d bytes is too small for a %d bytes message", len(data), size)
+	}
+	copy(data, msg[lenPrefixSize:lenPrefixSize+size])
+	if prio != nil {
+		*prio = msgPrio
+	}
+	return size, nil
+}
+
 // ID returns unique id of the queue.
 func (mq *LinuxMessageQueue) ID() int {
 	return mq.id
 }
 
-// Close closes the queue.
+// Fd returns the file descriptor of the queue, so that it can be registered
+// with external pollers, like epoll or poll.
+// The descriptor is owned by the queue and must not be closed by the caller.
+// It stays valid until Close or Destroy is called.
+func (mq *LinuxMessageQueue) Fd() uintptr {
+	return uintptr(mq.id)
+}
+
+// Close closes the queue. If coalescing is enabled, buffered messages are flushed.
+// The queue is closed even if the flush fails, and the flush error is returned.
 func (mq *LinuxMessageQueue) Close() error {
+	common.UnregisterFromCleanup(mq)
+	var flushErr error
+	if mq.coalescer != nil {
+		flushErr = mq.coalescer.close()
+	}
 	if mq.cancelSocket >= 0 {
 		if err := mq.NotifyCancel(); err != nil {
 			return errors.Wrap(err, "failed to cancel notifications")
 		}
 	}
+	if mq.countersRegion != nil {
+		mq.countersRegion.Close()
+	}
 	err := unix.Close(mq.ID())
-	*mq = LinuxMessageQueue{cancelSocket: -1}
+	*mq = LinuxMessageQueue{id: -1, cancelSocket: -1}
+	if err == nil && flushErr != nil {
+		err = errors.Wrap(flushErr, "failed to flush coalesced messages")
+	}
 	return err
 }
 
+// MaxPriority returns the maximum priority of a message, which is sysconf(_SC_MQ_PRIO_MAX) - 1.
+// The value is queried once and then cached.
+// Sending a message with a priority out of the range [0, MaxPriority()] fails with a descriptive error.
+func (mq *LinuxMessageQueue) MaxPriority() (int, error) {
+	return mqPrioMax() - 1, nil
+}
+
 // Cap returns the size of the mq buffer.
+// The value is queried once and then cached, as it is immutable after the queue is created.
 func (mq *LinuxMessageQueue) Cap() int {
+	if mq.maxMsg == 0 {
+		attrs, err := mq.getAttrs()
+		if err != nil {
+			return 0
+		}
+		mq.maxMsg = attrs.Maxmsg
+	}
+	return mq.maxMsg
+}
+
+// Len returns the number of messages currently in the queue.
+func (mq *LinuxMessageQueue) Len() (int, error) {
 	attrs, err := mq.getAttrs()
 	if err != nil {
-		return 0
+		return 0, err
 	}
-	return attrs.Maxmsg
+	return attrs.Curmsgs, nil
 }
 
 // SetBlocking sets whether the send/receive operations on the queue block.
-// This applies to the current instance only.
+// This applies to the current instance only. The mode is set for the queue descriptor,
+// so in non-blocking mode the operations with a timeout don't wait either.
 func (mq *LinuxMessageQueue) SetBlocking(block bool) error {
+	attrs := new(linuxMqAttr)
+	if !block {
+		attrs.Flags = unix.O_NONBLOCK
+	}
+	if err := mq_getsetattr(mq.ID(), attrs, nil); err != nil {
+		return errors.Wrap(err, "mq_getsetattr failed")
+	}
 	if block {
 		mq.flags &= ^O_NONBLOCK
 	} else {
@@ -243,6 +833,63 @@ func (mq *LinuxMessageQueue) SetBlocking(block bool) error {
 	return nil
 }
 
+// IsBlocking returns true, if the send/receive operations on the queue block.
+// The mode is obtained from the attributes of the queue descriptor.
+func (mq *LinuxMessageQueue) IsBlocking() (bool, error) {
+	attrs, err := mq.getAttrs()
+	if err != nil {
+		return false, err
+	}
+	return attrs.Flags&unix.O_NONBLOCK == 0, nil
+}
+
+// SetCloseOnExec sets or clears close-on-exec flag of the queue descriptor.
+// Queues are opened with the flag set, so the descriptor is not inherited by child processes.
+// Clear it to pass the queue to a child process, the descriptor keeps its number (see Fd) after exec.
+func (mq *LinuxMessageQueue) SetCloseOnExec(closeOnExec bool) error {
+	flags, err := unix.FcntlInt(uintptr(mq.ID()), unix.F_GETFD, 0)
+	if err != nil {
+		return os.NewSyscallError("FCNTL", err)
+	}
+	if closeOnExec {
+		flags |= unix.FD_CLOEXEC
+	} else {
+		flags &^= unix.FD_CLOEXEC
+	}
+	if _, err = unix.FcntlInt(uintptr(mq.ID()), unix.F_SETFD, flags); err != nil {
+		return os.NewSyscallError("FCNTL", err)
+	}
+	return nil
+}
+
+// Clone returns a new instance of the queue with a duplicate of its descriptor.
+// Both instances refer to the same kernel queue, but they can be closed independently,
+// so the clone can be passed to another goroutine, which closes it, when it is done.
+// The descriptors share the open file description, so the blocking mode set by SetBlocking
+// is shared as well. Other settings, like coalescing and notifications, are not copied.
+func (mq *LinuxMessageQueue) Clone() (*LinuxMessageQueue, error) {
+	id, err := unix.FcntlInt(uintptr(mq.ID()), unix.F_DUPFD_CLOEXEC, 0)
+	if err != nil {
+		return nil, errors.Wrap(os.NewSyscallError("FCNTL", err), "failed to duplicate the descriptor")
+	}
+	result := &LinuxMessageQueue{
+		id:           id,
+		name:         mq.name,
+		cancelSocket: -1,
+		inputBuff:    make([]byte, len(mq.inputBuff)),
+		flags:        mq.flags,
+		order:        newOrderChecker(),
+		maxMsg:       mq.maxMsg,
+	}
+	if mq.counters != nil {
+		if err = result.EnableCounters(0); err != nil {
+			result.Close()
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
 // Destroy closes the queue and removes it permanently.
 func (mq *LinuxMessageQueue) Destroy() error {
 	name := mq.name
@@ -259,10 +906,34 @@ func (mq *LinuxMessageQueue) Notify(ch chan<- int) error {
 	if ch == nil {
 		return errors.Errorf("cannot notify on a nil-chan")
 	}
+	return mq.notify(func(id int) {
+		ch <- id
+	})
+}
+
+// NotifyEvent acts like Notify, but sends the name of the queue, its id,
+// and the number of messages in the queue at the moment of the notification.
+// If the number of messages could not be obtained, Curmsgs is -1.
+func (mq *LinuxMessageQueue) NotifyEvent(ch chan<- MqNotification) error {
+	if ch == nil {
+		return errors.Errorf("cannot notify on a nil-chan")
+	}
+	name := mq.name
+	return mq.notify(func(id int) {
+		event := MqNotification{Name: name, ID: id, Curmsgs: -1}
+		attrs := new(linuxMqAttr)
+		if err := mq_getsetattr(id, nil, attrs); err == nil {
+			event.Curmsgs = attrs.Curmsgs
+		}
+		ch <- event
+	})
+}
+
+func (mq *LinuxMessageQueue) notify(f func(id int)) error {
 	if mq.cancelSocket >= 0 {
 		return errors.Errorf("notify has already been called")
 	}
-	notifySocket, cancelSocket, err := initLinuxMqNotifications(ch)
+	notifySocket, cancelSocket, err := initLinuxMqNotifications(f)
 	if err != nil {
 		return errors.Wrap(err, "unable to init notifications subsystem")
 	}
@@ -297,6 +968,296 @@ func (mq *LinuxMessageQueue) NotifyCancel() error {
 	return err
 }
 
+// WaitEmpty blocks until there are no messages in the queue, waiting for not longer, than timeout.
+// Passing negative value as a timeout makes the timeout infinite.
+// It polls queue attributes with an increasing interval.
+// If the queue has not become empty in time, it returns a temporary error.
+func (mq *LinuxMessageQueue) WaitEmpty(timeout time.Duration) error {
+	const maxPollInterval = 100 * time.Millisecond
+	empty, err := common.PollTimeout(func() (bool, error) {
+		attrs, err := mq.getAttrs()
+		if err != nil {
+			return false, errors.Wrap(err, "failed to get mq attrs")
+		}
+		return attrs.Curmsgs == 0, nil
+	}, timeout, maxPollInterval, nil)
+	if err != nil {
+		return err
+	}
+	if !empty {
+		return common.NewTimeoutError("WAITEMPTY")
+	}
+	return nil
+}
+
+// DrainAndClose waits until there are no messages in the queue, and closes the queue.
+// It is useful for a graceful shutdown of a producer. Unlike Clear, it doesn't discard messages,
+// so they must be received by consumers. If coalescing is enabled, buffered messages are flushed first.
+// Passing negative value as a timeout makes the timeout infinite.
+// The queue is closed in any case. If the queue has not become empty in time, it returns a temporary error.
+func (mq *LinuxMessageQueue) DrainAndClose(timeout time.Duration) error {
+	err := mq.Flush()
+	if err == nil {
+		err = mq.WaitEmpty(timeout)
+	}
+	if closeErr := mq.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Sync blocks until all the messages, which were sent into the queue before the call,
+// have been received, waiting for not longer, than timeout.
+// Passing negative value as a timeout makes the timeout infinite.
+// Unlike WaitEmpty, it is not affected by messages sent by concurrent producers after the call.
+// It relies on the counters of sent and received messages, which must be turned on with EnableCounters.
+// Messages sent or received by instances without counters, or by other means, are not counted.
+// If the messages have different priorities, newer messages with a higher priority can be received
+// instead of older ones, so Sync can return before these older messages are received.
+// If the messages have not been received in time, it returns a temporary error.
+func (mq *LinuxMessageQueue) Sync(timeout time.Duration) error {
+	const maxPollInterval = 100 * time.Millisecond
+	if mq.counters == nil {
+		return errors.New("message counters are not available for this queue")
+	}
+	target := atomic.LoadUint64(&mq.counters.sent)
+	synced, _ := common.PollTimeout(func() (bool, error) {
+		return int64(atomic.LoadUint64(&mq.counters.received)-target) >= 0, nil
+	}, timeout, maxPollInterval, nil)
+	if !synced {
+		return common.NewTimeoutError("SYNC")
+	}
+	return nil
+}
+
+// EnableCounters turns on counting of the messages sent and received by this instance of the queue.
+// The counters are required by Sync. They are kept in a shared memory object, which is shared by all the instances,
+// that have enabled them, and is removed by DestroyLinuxMessageQueue. As messages sent or received by instances
+// without counters are not counted, all the instances, which use the queue, should enable them.
+// If the queue was created with os.O_EXCL, the counters left by a previous queue with the same name are discarded.
+//	perm - permissions of the shared memory object, if it does not exist yet.
+func (mq *LinuxMessageQueue) EnableCounters(perm os.FileMode) error {
+	if mq.counters != nil {
+		return nil
+	}
+	region, _, err := helper.CreateWritableRegion(linuxMqCountersName(mq.name), os.O_CREATE, perm, int(unsafe.Sizeof(linuxMqCounters{})))
+	if err != nil {
+		return errors.Wrap(err, "failed to open message counters")
+	}
+	mq.countersRegion = region
+	mq.counters = (*linuxMqCounters)(allocator.ByteSliceData(region.Data()))
+	return nil
+}
+
+func linuxMqCountersName(name string) string {
+	return name + ".cnt"
+}
+
+// Readable returns a Waitable, which becomes ready, when there are messages in the queue.
+// Waiting for it does not receive messages, so another receiver can take the message first.
+// It can be used with sync.WaitAny. The waiting is done with epoll on the queue descriptor.
+func (mq *LinuxMessageQueue) Readable() ipc_sync.Waitable {
+	return linuxMqReadable{mq: mq}
+}
+
+type linuxMqReadable struct {
+	mq *LinuxMessageQueue
+}
+
+var _ ipc_sync.FdWaitable = linuxMqReadable{}
+
+// WaitTimeout waits until there are messages in the queue, or the timeout elapses.
+func (r linuxMqReadable) WaitTimeout(timeout time.Duration) bool {
+	epoll, err := common.NewEpoll([]int{r.mq.id})
+	if err != nil {
+		return false
+	}
+	defer epoll.Close()
+	ok, err := epoll.WaitReadable(timeout)
+	return ok && err == nil
+}
+
+// Fd returns the descriptor of the queue.
+func (r linuxMqReadable) Fd() uintptr {
+	return uintptr(r.mq.id)
+}
+
+// Message is a message received from a queue along with its priority.
+type Message struct {
+	Data []byte
+	Prio int
+}
+
+// DrainBudget receives messages without blocking until either the queue is empty,
+// or the total size of received messages reaches maxBytes.
+// Returns received messages and their total size.
+// It does not change the blocking mode of the queue.
+// As a message can't be examined without removing it from the queue, a message,
+// which does not fit into the rest of the budget, is sent back with its original priority.
+// It becomes the last message of its priority, so the order of such messages may change.
+// If the message can't be sent back, it is returned along with the error.
+func (mq *LinuxMessageQueue) DrainBudget(maxBytes int) ([]Message, int, error) {
+	attrs, err := mq.getAttrs()
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to get mq attrs")
+	}
+	var result []Message
+	var total int
+	buff := make([]byte, attrs.Msgsize)
+	for total < maxBytes {
+		n, prio, err := mq.ReceiveTimeoutPriority(buff, 0)
+		if err != nil {
+			if IsTemporary(errors.Cause(err)) {
+				break
+			}
+			return result, total, err
+		}
+		if total+n > maxBytes {
+			if err = mq.SendTimeoutPriority(buff[:n], prio, 0); err != nil {
+				data := make([]byte, n)
+				copy(data, buff[:n])
+				result = append(result, Message{Data: data, Prio: prio})
+				return result, total + n, errors.Wrap(err, "failed to return the message into the queue")
+			}
+			break
+		}
+		data := make([]byte, n)
+		copy(data, buff[:n])
+		result = append(result, Message{Data: data, Prio: prio})
+		total += n
+	}
+	return result, total, nil
+}
+
+// Clear discards all the messages in the queue and returns the number of discarded messages.
+// Unlike destroying and recreating the queue, it keeps its id and notification subscriptions.
+// Messages are received without blocking, so the blocking mode of the queue is not changed.
+// Messages sent by concurrent producers during the call may be discarded too.
+func (mq *LinuxMessageQueue) Clear() (int, error) {
+	attrs, err := mq.getAttrs()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get mq attrs")
+	}
+	buff := make([]byte, attrs.Msgsize)
+	var count int
+	for {
+		if _, _, err = mq.ReceiveTimeoutPriority(buff, 0); err != nil {
+			if IsEmpty(err) {
+				return count, nil
+			}
+			return count, err
+		}
+		count++
+	}
+}
+
+// Forward moves up to count messages from src to dst preserving their priorities.
+// Each receive waits for not longer, than timeout. Passing negative value as a timeout makes it infinite.
+// Send operations block according to the blocking mode of dst.
+// Returns the number of forwarded messages. If src becomes empty, and the timeout expires,
+// the number of already forwarded messages is returned along with the timeout error.
+// If a message was received, but could not be sent, it is lost.
+func Forward(src, dst *LinuxMessageQueue, count int, timeout time.Duration) (int, error) {
+	attrs, err := src.getAttrs()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get mq attrs")
+	}
+	buff := make([]byte, attrs.Msgsize)
+	for forwarded := 0; forwarded < count; forwarded++ {
+		n, prio, err := src.ReceiveTimeoutPriority(buff, timeout)
+		if err != nil {
+			return forwarded, err
+		}
+		if err = dst.SendPriority(buff[:n], prio); err != nil {
+			return forwarded, errors.Wrap(err, "failed to send a message, it is lost")
+		}
+	}
+	return count, nil
+}
+
+// Resize changes max queue size and max message size of the queue.
+// As linux mq attributes can't be changed after creation, it receives all the messages,
+// destroys the queue, creates a new one with the same name and permissions, and sends the messages back
+// preserving their order and priorities.
+// Other instances of the queue, including the ones in other processes, keep using the old queue,
+// so it is safe to call Resize only if no one else uses the queue.
+// The queue must be opened for both reading and writing, and notifications must not be enabled.
+// If the messages do not fit into the new queue, an error is returned, and the queue is not changed.
+//	maxQueueSize - new queue capacity.
+//	maxMsgSize - new maximum message size.
+func (mq *LinuxMessageQueue) Resize(maxQueueSize, maxMsgSize int) error {
+	if mode, err := unix.FcntlInt(uintptr(mq.ID()), unix.F_GETFL, 0); err != nil {
+		return errors.Wrap(err, "failed to get mq flags")
+	} else if mode&unix.O_ACCMODE != unix.O_RDWR {
+		return errors.New("the queue must be opened for reading and writing")
+	}
+	if mq.cancelSocket >= 0 {
+		return errors.New("notifications must be cancelled before resizing the queue")
+	}
+	if err := mq.Flush(); err != nil {
+		return errors.Wrap(err, "failed to flush coalesced messages")
+	}
+	var st unix.Stat_t
+	if err := unix.Fstat(mq.ID(), &st); err != nil {
+		return errors.Wrap(err, "failed to get mq permissions")
+	}
+	attrs, err := mq.getAttrs()
+	if err != nil {
+		return errors.Wrap(err, "failed to get mq attrs")
+	}
+	// receive everything.
+	var messages []Message
+	buff := make([]byte, attrs.Msgsize)
+	maxLen := 0
+	for {
+		n, prio, err := mq.ReceiveTimeoutPriority(buff, 0)
+		if err != nil {
+			if IsTemporary(errors.Cause(err)) {
+				break
+			}
+			mq.resend(messages)
+			return errors.Wrap(err, "failed to receive messages")
+		}
+		data := make([]byte, n)
+		copy(data, buff[:n])
+		messages = append(messages, Message{Data: data, Prio: prio})
+		if n > maxLen {
+			maxLen = n
+		}
+	}
+	if len(messages) > maxQueueSize || maxLen > maxMsgSize {
+		mq.resend(messages)
+		return errors.New("the messages do not fit into the resized queue")
+	}
+	if err = mq_unlink(mq.name); err != nil {
+		mq.resend(messages)
+		return errors.Wrap(err, "mq_unlink failed")
+	}
+	newAttrs := &linuxMqAttr{Maxmsg: maxQueueSize, Msgsize: maxMsgSize + orderStampSize}
+	id, err := mq_open(mq.name, unix.O_CREAT|unix.O_EXCL|unix.O_RDWR|unix.O_CLOEXEC|mq.flags&O_NONBLOCK, st.Mode&0777, newAttrs)
+	if err != nil {
+		return errors.Wrapf(err, "mq_open failed, %d messages were lost", len(messages))
+	}
+	unix.Close(mq.ID())
+	mq.id = id
+	mq.inputBuff = make([]byte, newAttrs.Msgsize)
+	mq.maxMsg = maxQueueSize
+	if err = mq.resend(messages); err != nil {
+		return errors.Wrap(err, "failed to send messages back")
+	}
+	return nil
+}
+
+// resend sends messages into the queue without blocking.
+func (mq *LinuxMessageQueue) resend(messages []Message) error {
+	for _, msg := range messages {
+		if err := mq.sendTimeoutPriority(msg.Data, msg.Prio, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // getAttrs returns attributes of the queue.
 func (mq *LinuxMessageQueue) getAttrs() (*linuxMqAttr, error) {
 	attrs := new(linuxMqAttr)
@@ -316,6 +1277,11 @@ func DestroyLinuxMessageQueue(name string) error {
 			err = errors.Wrap(err, "mq_unlink failed")
 		}
 	}
+	if err == nil {
+		if err = shm.DestroyMemoryObject(linuxMqCountersName(name)); err != nil {
+			err = errors.Wrap(err, "failed to destroy message counters")
+		}
+	}
 	return err
 }
 
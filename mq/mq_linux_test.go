@@ -5,12 +5,23 @@
 package mq
 
 import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 	"time"
+	"unsafe"
 
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/common"
 	"github.com/nxgtw/go-ipc/internal/test"
+	"bitbucket.org/avd/go-ipc/shm"
+	ipc_sync "bitbucket.org/avd/go-ipc/sync"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
 )
 
 func linuxMqCtor(name string, flag int, perm os.FileMode) (Messenger, error) {
@@ -100,6 +111,101 @@ func TestLinuxMqGetAttrs(t *testing.T) {
 	assert.Equal(t, 1, attrs.Curmsgs)
 }
 
+func TestLinuxMqLenCap(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 5, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	a.Equal(5, mq.Cap())
+	mq2, err := OpenLinuxMessageQueue(testMqName, os.O_RDWR)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq2.Close()
+	a.Equal(5, mq2.Cap())
+	checkLen := func(expected int) {
+		l, err := mq.Len()
+		if a.NoError(err) {
+			a.Equal(expected, l)
+		}
+	}
+	checkLen(0)
+	for i := 0; i < 3; i++ {
+		a.NoError(mq2.Send([]byte{byte(i)}))
+		checkLen(i + 1)
+	}
+	data := make([]byte, 16)
+	for i := 3; i > 0; i-- {
+		_, err = mq2.Receive(data)
+		a.NoError(err)
+		checkLen(i - 1)
+	}
+}
+
+func TestLinuxMqOpenOrCreate(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	_, _, err := OpenOrCreateLinuxMessageQueue(testMqName, os.O_RDWR, 0666, 5, 16)
+	a.True(os.IsNotExist(errors.Cause(err)))
+	mq, created, err := OpenOrCreateLinuxMessageQueue(testMqName, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666, 5, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	a.True(created)
+	_, _, err = OpenOrCreateLinuxMessageQueue(testMqName, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666, 5, 16)
+	a.True(os.IsExist(errors.Cause(err)))
+	// attributes of an existing queue are not changed.
+	mq2, created, err := OpenOrCreateLinuxMessageQueue(testMqName, os.O_CREATE|os.O_RDWR, 0666, 3, 8)
+	if !a.NoError(err) {
+		return
+	}
+	a.False(created)
+	a.Equal(5, mq2.Cap())
+	a.NoError(mq2.Close())
+	mq3, created, err := OpenOrCreateLinuxMessageQueue(testMqName, os.O_RDONLY, 0666, 3, 8)
+	if !a.NoError(err) {
+		return
+	}
+	a.False(created)
+	a.Error(mq3.Send([]byte{1}))
+	a.NoError(mq3.Close())
+}
+
+func TestLinuxMqOpenOrCreateConcurrent(t *testing.T) {
+	const count = 8
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	defer DestroyLinuxMessageQueue(testMqName)
+	results := make(chan bool, count)
+	for i := 0; i < count; i++ {
+		go func() {
+			mq, created, err := OpenOrCreateLinuxMessageQueue(testMqName, os.O_CREATE|os.O_RDWR, 0666, 5, 16)
+			if a.NoError(err) {
+				a.Equal(5, mq.Cap())
+				mq.Close()
+			}
+			results <- created
+		}()
+	}
+	var createdCount int
+	for i := 0; i < count; i++ {
+		if <-results {
+			createdCount++
+		}
+	}
+	a.Equal(1, createdCount)
+}
+
 func TestLinuxMqNotifyOnce(t *testing.T) {
 	if !assert.NoError(t, DestroyLinuxMessageQueue(testMqName)) {
 		return
@@ -135,6 +241,34 @@ func TestLinuxMqNotifyTwice(t *testing.T) {
 	assert.NoError(t, mq.Notify(ch))
 }
 
+func TestLinuxMqNotifyEvent(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 5, 121)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(mq.Destroy())
+	}()
+	ch := make(chan MqNotification)
+	a.NoError(mq.NotifyEvent(ch))
+	a.Error(mq.Notify(make(chan int)))
+	go func() {
+		mq.Send(make([]byte, 1))
+	}()
+	select {
+	case event := <-ch:
+		a.Equal(testMqName, event.Name)
+		a.Equal(mq.ID(), event.ID)
+		a.Equal(1, event.Curmsgs)
+	case <-time.After(time.Second * 2):
+		t.Error("notification timeout")
+	}
+}
+
 func TestLinuxMqNotifyAnotherProcess(t *testing.T) {
 	a := assert.New(t)
 	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
@@ -178,16 +312,1203 @@ func TestLinuxMqNotifyAnotherProcess(t *testing.T) {
 	}
 }
 
-func TestLinuxMqPrio1(t *testing.T) {
-	testPrioMq1(t, linuxMqCtorPrio, linuxMqOpenerPrio, linuxMqDtor)
+func TestLinuxMqWaitEmpty(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 5, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	a.NoError(mq.WaitEmpty(0))
+	for i := 0; i < 3; i++ {
+		a.NoError(mq.Send(make([]byte, 16)))
+	}
+	err = mq.WaitEmpty(time.Millisecond * 50)
+	a.Error(err)
+	a.True(IsTemporary(err))
+	go func() {
+		data := make([]byte, 16)
+		for i := 0; i < 3; i++ {
+			<-time.After(time.Millisecond * 50)
+			_, err := mq.Receive(data)
+			a.NoError(err)
+		}
+	}()
+	a.NoError(mq.WaitEmpty(time.Second * 2))
+	attrs, err := mq.getAttrs()
+	if a.NoError(err) {
+		a.Equal(0, attrs.Curmsgs)
+	}
 }
 
-func BenchmarkLinuxMqNonBlock(b *testing.B) {
-	params := &prioBenchmarkParams{readers: 4, writers: 4, mqSize: 8, msgSize: 1024, flag: O_NONBLOCK}
-	benchmarkPrioMq1(b, linuxMqCtorPrio, linuxMqOpenerPrio, linuxMqDtor, params)
+func TestLinuxMqSync(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 5, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	a.Error(mq.Sync(0))
+	if !a.NoError(mq.EnableCounters(0666)) {
+		return
+	}
+	a.NoError(mq.Sync(0))
+	for i := 0; i < 2; i++ {
+		a.NoError(mq.Send(make([]byte, 16)))
+	}
+	err = mq.Sync(time.Millisecond * 50)
+	a.Error(err)
+	a.True(IsTemporary(err))
+	mq2, err := OpenLinuxMessageQueue(testMqName, os.O_RDWR)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq2.Close()
+	if !a.NoError(mq2.EnableCounters(0666)) {
+		return
+	}
+	go func() {
+		<-time.After(time.Millisecond * 50)
+		// a message sent after the call to Sync must not be waited for.
+		a.NoError(mq2.Send(make([]byte, 16)))
+		data := make([]byte, 16)
+		for i := 0; i < 2; i++ {
+			_, err := mq2.Receive(data)
+			a.NoError(err)
+		}
+	}()
+	a.NoError(mq.Sync(time.Second * 2))
+	attrs, err := mq.getAttrs()
+	if a.NoError(err) {
+		a.Equal(1, attrs.Curmsgs)
+	}
 }
 
-func BenchmarkLinuxMqBlock(b *testing.B) {
-	params := &prioBenchmarkParams{readers: 4, writers: 4, mqSize: 8, msgSize: 1024, flag: 0}
-	benchmarkPrioMq1(b, linuxMqCtorPrio, linuxMqOpenerPrio, linuxMqDtor, params)
+func TestLinuxMqCountersOptIn(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0600, 5, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	// the counters are not created by default.
+	_, err = shm.NewMemoryObject(linuxMqCountersName(testMqName), os.O_RDONLY, 0)
+	a.True(os.IsNotExist(errors.Cause(err)))
+	if !a.NoError(mq.EnableCounters(0600)) {
+		return
+	}
+	obj, err := shm.NewMemoryObject(linuxMqCountersName(testMqName), os.O_RDONLY, 0)
+	if a.NoError(err) {
+		a.Equal(os.FileMode(0600), fileMode(t, obj.Fd()))
+		a.NoError(obj.Close())
+	}
+	a.NoError(mq.Send(make([]byte, 16)))
+	// an instance without counters works, but does not count messages.
+	mq2, err := OpenLinuxMessageQueue(testMqName, os.O_RDWR)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq2.Close()
+	a.Error(mq2.Sync(0))
+	_, err = mq2.Receive(make([]byte, 16))
+	a.NoError(err)
+	a.Error(mq.Sync(0))
+	// emulate removal of the queue by other means, so that the counters are left behind.
+	a.NoError(mq_unlink(testMqName))
+	mq3, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0600, 5, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq3.Close()
+	// stale counters of the previous queue are discarded.
+	if !a.NoError(mq3.EnableCounters(0600)) {
+		return
+	}
+	a.NoError(mq3.Sync(0))
+}
+
+func fileMode(t *testing.T, fd uintptr) os.FileMode {
+	var st unix.Stat_t
+	if err := unix.Fstat(int(fd), &st); err != nil {
+		t.Fatal(err)
+	}
+	return os.FileMode(st.Mode & 0777)
+}
+
+func TestLinuxMqSyncAnotherProcess(t *testing.T) {
+	const count = 20
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 5, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	if !a.NoError(mq.EnableCounters(0666)) {
+		return
+	}
+	args := argsForMqDrainCommand(testMqName, "linux", count)
+	resultChan := testutil.RunTestAppAsync(args, nil)
+	for i := 0; i < count; i++ {
+		if !a.NoError(mq.SendTimeout(make([]byte, 16), time.Second*10)) {
+			return
+		}
+	}
+	a.NoError(mq.Sync(time.Second * 10))
+	attrs, err := mq.getAttrs()
+	if a.NoError(err) {
+		a.Equal(0, attrs.Curmsgs)
+	}
+	result := <-resultChan
+	if !a.NoError(result.Err) {
+		t.Logf("program output is %q", result.Output)
+	}
+}
+
+func TestLinuxMqRemap(t *testing.T) {
+	// application priorities are 'low', 'normal', 'high'.
+	const (
+		low = iota
+		normal
+		high
+	)
+	toRaw := func(prio int) int {
+		return prio * 10
+	}
+	fromRaw := func(raw int) int {
+		return raw / 10
+	}
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 5, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	a.NoError(mq.SendRemap([]byte{1}, normal, toRaw))
+	a.NoError(mq.SendRemap([]byte{2}, high, toRaw))
+	a.NoError(mq.SendPriority([]byte{3}, 5))
+	data := make([]byte, 16)
+	l, prio, err := mq.ReceiveRemap(data, fromRaw)
+	if a.NoError(err) {
+		a.Equal(1, l)
+		a.Equal(byte(2), data[0])
+		a.Equal(high, prio)
+	}
+	l, prio, err = mq.ReceiveRemap(data, fromRaw)
+	if a.NoError(err) {
+		a.Equal(byte(1), data[0])
+		a.Equal(normal, prio)
+	}
+	l, prio, err = mq.ReceiveRemap(data, fromRaw)
+	if a.NoError(err) {
+		a.Equal(byte(3), data[0])
+		a.Equal(low, prio)
+	}
+}
+
+func TestLinuxMqCoalescing(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 5, 64)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	a.Error(mq.EnableCoalescing(128, 0))
+	if !a.NoError(mq.EnableCoalescing(32, 0)) {
+		return
+	}
+	a.Error(mq.Send(make([]byte, 29)))
+	// 3 records of 4 + 6 bytes fill the buffer and are flushed, the 4th one stays buffered.
+	for i := 0; i < 4; i++ {
+		a.NoError(mq.Send(bytes.Repeat([]byte{byte(i)}, 6)))
+	}
+	attrs, err := mq.getAttrs()
+	if a.NoError(err) {
+		a.Equal(1, attrs.Curmsgs)
+	}
+	a.NoError(mq.Flush())
+	data := make([]byte, 64)
+	var records [][]byte
+	for i := 0; i < 2; i++ {
+		l, err := mq.ReceiveTimeout(data, 0)
+		if !a.NoError(err) {
+			return
+		}
+		unpacked, err := UnpackCoalesced(data[:l])
+		if !a.NoError(err) {
+			return
+		}
+		for _, rec := range unpacked {
+			records = append(records, append([]byte(nil), rec...))
+		}
+	}
+	if a.Len(records, 4) {
+		for i, rec := range records {
+			a.Equal(bytes.Repeat([]byte{byte(i)}, 6), rec)
+		}
+	}
+	// time-based flush.
+	a.NoError(mq.EnableCoalescing(64, time.Millisecond*50))
+	a.NoError(mq.Send([]byte{1, 2}))
+	a.NoError(mq.Send([]byte{3}))
+	_, err = mq.ReceiveTimeout(data, 0)
+	a.True(IsTemporary(errors.Cause(err)))
+	l, err := mq.ReceiveTimeout(data, time.Second)
+	if a.NoError(err) {
+		unpacked, err := UnpackCoalesced(data[:l])
+		if a.NoError(err) {
+			a.Equal([][]byte{{1, 2}, {3}}, unpacked)
+		}
+	}
+	// other sends flush the buffer first.
+	a.NoError(mq.Send([]byte{4}))
+	a.NoError(mq.SendPriority([]byte{5}, 0))
+	l, err = mq.ReceiveTimeout(data, 0)
+	if a.NoError(err) {
+		unpacked, err := UnpackCoalesced(data[:l])
+		if a.NoError(err) {
+			a.Equal([][]byte{{4}}, unpacked)
+		}
+	}
+	l, err = mq.ReceiveTimeout(data, 0)
+	if a.NoError(err) {
+		a.Equal([]byte{5}, data[:l])
+	}
+	a.NoError(mq.EnableCoalescing(0, 0))
+	_, err = UnpackCoalesced([]byte{10, 0, 0, 0, 1})
+	a.Error(err)
+}
+
+func TestLinuxMqWaitAny(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 5, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	if !a.NoError(ipc_sync.DestroyEvent(testMqName)) {
+		return
+	}
+	ev, err := ipc_sync.NewEvent(testMqName, os.O_CREATE|os.O_EXCL, 0666, false)
+	if !a.NoError(err) {
+		return
+	}
+	defer ev.Destroy()
+	a.False(mq.Readable().WaitTimeout(time.Millisecond * 10))
+	objs := []ipc_sync.Waitable{ev, mq.Readable()}
+	idx, err := ipc_sync.WaitAny(objs, time.Millisecond*50)
+	a.Equal(-1, idx)
+	a.Error(err)
+	go func() {
+		<-time.After(time.Millisecond * 50)
+		a.NoError(mq.Send([]byte{1}))
+	}()
+	idx, err = ipc_sync.WaitAny(objs, time.Second*2)
+	a.NoError(err)
+	a.Equal(1, idx)
+	// the message must stay in the queue.
+	attrs, err := mq.getAttrs()
+	if a.NoError(err) {
+		a.Equal(1, attrs.Curmsgs)
+	}
+	a.False(ev.WaitTimeout(0))
+	a.True(mq.Readable().WaitTimeout(0))
+	// the queue alone is waited for with epoll.
+	idx, err = ipc_sync.WaitAny([]ipc_sync.Waitable{mq.Readable()}, 0)
+	a.NoError(err)
+	a.Equal(0, idx)
+}
+
+func TestLinuxMqReceiveBatch(t *testing.T) {
+	type batchItem struct {
+		ID    int32
+		Value int32
+	}
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 5, 8)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	for i := 0; i < 5; i++ {
+		data, err := allocator.ObjectData(&batchItem{ID: int32(i), Value: int32(i * 10)})
+		if a.NoError(err) {
+			a.NoError(mq.SendPriority(data, 1))
+		}
+	}
+	var items []batchItem
+	prios := make([]int, 3)
+	n, err := mq.ReceiveBatch(&items, prios, 3)
+	a.NoError(err)
+	a.Equal(3, n)
+	if a.Len(items, 3) {
+		for i, item := range items {
+			a.Equal(batchItem{ID: int32(i), Value: int32(i * 10)}, item)
+			a.Equal(1, prios[i])
+		}
+	}
+	// partial fill.
+	n, err = mq.ReceiveBatch(&items, nil, 10)
+	a.NoError(err)
+	a.Equal(2, n)
+	if a.Len(items, 2) {
+		a.Equal(int32(3), items[0].ID)
+		a.Equal(int32(4), items[1].ID)
+	}
+	a.NoError(mq.SetBlocking(false))
+	n, err = mq.ReceiveBatch(&items, nil, 10)
+	a.Equal(0, n)
+	a.True(IsTemporary(errors.Cause(err)))
+	a.Len(items, 0)
+	_, err = mq.ReceiveBatch(items, nil, 10)
+	a.Error(err)
+	_, err = mq.ReceiveBatch(&items, prios, 10)
+	a.Error(err)
+}
+
+func TestLinuxMqPeek(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 5, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	for _, msg := range []struct {
+		value int32
+		prio  int
+	}{{1, 1}, {2, 3}, {3, 3}} {
+		data, err := allocator.ObjectData(&msg.value)
+		if !a.NoError(err) || !a.NoError(mq.SendPriority(data, msg.prio)) {
+			return
+		}
+	}
+	for i := 0; i < 2; i++ {
+		var value int32
+		var prio int
+		if a.NoError(mq.Peek(&value, &prio)) {
+			a.Equal(int32(2), value)
+			a.Equal(3, prio)
+		}
+	}
+	var small int16
+	a.Error(mq.Peek(&small, nil))
+	a.Error(mq.Peek(small, nil))
+	// the order of the messages must not change.
+	for _, expected := range []int32{2, 3, 1} {
+		var value int32
+		data, err := allocator.ObjectData(&value)
+		if !a.NoError(err) {
+			return
+		}
+		_, err = mq.Receive(data)
+		if a.NoError(err) {
+			a.Equal(expected, value)
+		}
+	}
+	a.NoError(mq.SetBlocking(false))
+	var value int32
+	err = mq.Peek(&value, nil)
+	a.True(IsTemporary(errors.Cause(err)))
+	wo, err := OpenLinuxMessageQueue(testMqName, os.O_WRONLY)
+	if a.NoError(err) {
+		defer wo.Close()
+		a.Error(wo.Peek(&value, nil))
+	}
+}
+
+func TestLinuxMqSendReceiveLen(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 5, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	a.NoError(mq.SendLen([]byte{1, 2, 3}, 2))
+	a.NoError(mq.SendLen(make([]byte, 12), 0))
+	a.Error(mq.SendLen(make([]byte, 13), 0))
+	data := make([]byte, 16)
+	var prio int
+	n, err := mq.ReceiveLen(data, &prio)
+	if a.NoError(err) {
+		a.Equal(3, n)
+		a.Equal(2, prio)
+		a.Equal([]byte{1, 2, 3}, data[:n])
+	}
+	_, err = mq.ReceiveLen(data[:11], nil)
+	a.Error(err)
+}
+
+func TestLinuxMqResize(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0644, 5, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	prios := []int{1, 3, 1, 0, 3}
+	for i, prio := range prios {
+		a.NoError(mq.SendPriority([]byte{byte(i)}, prio))
+	}
+	a.Error(mq.Resize(4, 16))
+	a.Error(mq.Resize(10, 0))
+	if !a.NoError(mq.Resize(10, 32)) {
+		return
+	}
+	a.Equal(10, mq.Cap())
+	a.NoError(mq.SendPriority(make([]byte, 32), 0))
+	attrs, err := mq.getAttrs()
+	if a.NoError(err) {
+		a.Equal(6, attrs.Curmsgs)
+	}
+	data := make([]byte, 32)
+	for _, expected := range []int{1, 4, 0, 2, 3} {
+		l, err := mq.Receive(data)
+		if a.NoError(err) {
+			a.Equal(1, l)
+			a.Equal(byte(expected), data[0])
+		}
+	}
+	ro, err := OpenLinuxMessageQueue(testMqName, os.O_RDONLY)
+	if a.NoError(err) {
+		a.Error(ro.Resize(10, 32))
+		a.NoError(ro.Close())
+	}
+}
+
+func TestLinuxMqPrio1(t *testing.T) {
+	testPrioMq1(t, linuxMqCtorPrio, linuxMqOpenerPrio, linuxMqDtor)
+}
+
+func BenchmarkLinuxMqNonBlock(b *testing.B) {
+	params := &prioBenchmarkParams{readers: 4, writers: 4, mqSize: 8, msgSize: 1024, flag: O_NONBLOCK}
+	benchmarkPrioMq1(b, linuxMqCtorPrio, linuxMqOpenerPrio, linuxMqDtor, params)
+}
+
+func BenchmarkLinuxMqBlock(b *testing.B) {
+	params := &prioBenchmarkParams{readers: 4, writers: 4, mqSize: 8, msgSize: 1024, flag: 0}
+	benchmarkPrioMq1(b, linuxMqCtorPrio, linuxMqOpenerPrio, linuxMqDtor, params)
+}
+
+func TestLinuxMqDrainBudget(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 5, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	messages, total, err := mq.DrainBudget(64)
+	a.NoError(err)
+	a.Len(messages, 0)
+	a.Equal(0, total)
+	for i := 0; i < 5; i++ {
+		a.NoError(mq.SendPriority(bytes.Repeat([]byte{byte(i)}, 8+i), i))
+	}
+	messages, total, err = mq.DrainBudget(38)
+	if !a.NoError(err) {
+		return
+	}
+	if a.Len(messages, 3) {
+		a.Equal(Message{Data: bytes.Repeat([]byte{4}, 12), Prio: 4}, messages[0])
+		a.Equal(Message{Data: bytes.Repeat([]byte{3}, 11), Prio: 3}, messages[1])
+		a.Equal(Message{Data: bytes.Repeat([]byte{2}, 10), Prio: 2}, messages[2])
+	}
+	a.Equal(33, total)
+	// the next message is larger, than the budget, and must stay in the queue.
+	messages, total, err = mq.DrainBudget(8)
+	a.NoError(err)
+	a.Len(messages, 0)
+	a.Equal(0, total)
+	messages, total, err = mq.DrainBudget(1024)
+	a.NoError(err)
+	if a.Len(messages, 2) {
+		a.Equal(Message{Data: bytes.Repeat([]byte{1}, 9), Prio: 1}, messages[0])
+		a.Equal(Message{Data: bytes.Repeat([]byte{0}, 8), Prio: 0}, messages[1])
+	}
+	a.Equal(17, total)
+}
+
+func TestLinuxMqReceiveModeKeep(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 5, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	message := []byte("0123456789abcdef")
+	a.NoError(mq.SendPriority(message, 3))
+	small := make([]byte, 8)
+	_, _, _, err = mq.ReceiveTimeoutPriorityMode(small, 0, ReceiveKeep)
+	a.Error(err)
+	// the message must stay in the queue, so that any instance can receive it.
+	cnt, err := mq.Len()
+	if a.NoError(err) {
+		a.Equal(1, cnt)
+	}
+	data := make([]byte, 16)
+	n, prio, err := mq.ReceiveTimeoutPriority(data, 0)
+	if a.NoError(err) {
+		a.Equal(16, n)
+		a.Equal(3, prio)
+		a.Equal(message, data)
+	}
+	_, err = mq.ReceiveTimeout(data, 0)
+	a.Error(err)
+	a.True(IsTemporary(errors.Cause(err)))
+}
+
+func TestLinuxMqReceiveModeTruncate(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 5, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	message := []byte("0123456789abcdef")
+	a.NoError(mq.SendPriority(message, 3))
+	small := make([]byte, 8)
+	n, prio, truncated, err := mq.ReceiveTimeoutPriorityMode(small, 0, ReceiveTruncate)
+	if a.NoError(err) {
+		a.Equal(8, n)
+		a.Equal(3, prio)
+		a.True(truncated)
+		a.Equal(message[:8], small)
+	}
+	a.NoError(mq.SendPriority(message[:4], 1))
+	n, prio, truncated, err = mq.ReceiveTimeoutPriorityMode(small, 0, ReceiveTruncate)
+	if a.NoError(err) {
+		a.Equal(4, n)
+		a.Equal(1, prio)
+		a.False(truncated)
+		a.Equal(message[:4], small[:4])
+	}
+	_, _, _, err = mq.ReceiveTimeoutPriorityMode(make([]byte, 16), 0, ReceiveTruncate)
+	a.Error(err)
+	a.True(IsTemporary(errors.Cause(err)))
+	_, _, _, err = mq.ReceiveTimeoutPriorityMode(small, 0, ReceiveMode(5))
+	a.Error(err)
+}
+
+func TestListLinuxMessageQueues(t *testing.T) {
+	a := assert.New(t)
+	if _, err := mqueueDirectory(); err != nil {
+		t.Skip("mqueue filesystem is not mounted")
+	}
+	names := []string{testMqName + ".l1", testMqName + ".l2"}
+	for _, name := range names {
+		mq, err := CreateLinuxMessageQueue(name, os.O_CREATE|os.O_RDWR, 0666, 1, 16)
+		if !a.NoError(err) {
+			return
+		}
+		a.NoError(mq.Close())
+		defer func(name string) {
+			a.NoError(DestroyLinuxMessageQueue(name))
+		}(name)
+	}
+	list, err := ListLinuxMessageQueues()
+	if !a.NoError(err) {
+		return
+	}
+	for _, name := range names {
+		a.Contains(list, name)
+	}
+}
+
+func TestLinuxMqNamePrefix(t *testing.T) {
+	a := assert.New(t)
+	defer common.SetNamePrefix("")
+	prefixes := []string{"app1", "app2"}
+	var queues []*LinuxMessageQueue
+	for _, prefix := range prefixes {
+		common.SetNamePrefix(prefix)
+		if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+			return
+		}
+		mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 1, 16)
+		if !a.NoError(err) {
+			return
+		}
+		defer func(prefix string, mq *LinuxMessageQueue) {
+			common.SetNamePrefix(prefix)
+			a.NoError(mq.Destroy())
+		}(prefix, mq)
+		queues = append(queues, mq)
+	}
+	for i, mq := range queues {
+		a.NoError(mq.Send([]byte(prefixes[i])))
+	}
+	for i, prefix := range prefixes {
+		common.SetNamePrefix(prefix)
+		mq, err := OpenLinuxMessageQueue(testMqName, os.O_RDWR)
+		if !a.NoError(err) {
+			return
+		}
+		data := make([]byte, 16)
+		n, err := mq.Receive(data)
+		a.NoError(err)
+		a.Equal(prefixes[i], string(data[:n]))
+		a.NoError(mq.Close())
+	}
+	common.SetNamePrefix("")
+	a.NoError(DestroyLinuxMessageQueue(testMqName))
+	_, err := OpenLinuxMessageQueue(testMqName, os.O_RDWR)
+	a.True(os.IsNotExist(errors.Cause(err)))
+}
+
+func TestLinuxMqErrors(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 1, 8)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	a.NoError(mq.SetBlocking(false))
+	buff := make([]byte, 8)
+	_, err = mq.Receive(buff)
+	a.True(IsEmpty(err))
+	a.True(IsTimeout(err))
+	a.False(IsFull(err))
+	a.NoError(mq.Send(buff))
+	err = mq.Send(buff)
+	if a.Error(err) {
+		a.True(IsFull(err))
+		a.True(IsTimeout(err))
+		a.False(IsEmpty(err))
+		a.False(IsTooBig(err))
+		a.IsType(&MqError{}, err)
+	}
+	a.NoError(mq.SetBlocking(true))
+	err = mq.SendTimeout(buff, 10*time.Millisecond)
+	a.True(IsFull(err))
+	a.True(IsTimeout(err))
+	_, err = mq.Receive(buff)
+	a.NoError(err)
+	err = mq.Send(make([]byte, 9))
+	a.True(IsTooBig(err))
+	a.False(IsTimeout(err))
+	a.False(IsFull(err))
+}
+
+func TestLinuxMqClear(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 4, 8)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	for i := 0; i < 4; i++ {
+		if !a.NoError(mq.SendPriority([]byte{byte(i)}, i)) {
+			return
+		}
+	}
+	n, err := mq.Clear()
+	a.NoError(err)
+	a.Equal(4, n)
+	l, err := mq.Len()
+	a.NoError(err)
+	a.Equal(0, l)
+	n, err = mq.Clear()
+	a.NoError(err)
+	a.Equal(0, n)
+	// the queue is still blocking.
+	_, err = mq.ReceiveTimeout(make([]byte, 8), 10*time.Millisecond)
+	a.True(IsEmpty(err))
+	a.Equal(0, mq.flags&O_NONBLOCK)
+}
+
+func TestLinuxMqForward(t *testing.T) {
+	a := assert.New(t)
+	const dstName = testMqName + ".dst"
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) || !a.NoError(DestroyLinuxMessageQueue(dstName)) {
+		return
+	}
+	src, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 8, 8)
+	if !a.NoError(err) {
+		return
+	}
+	defer src.Destroy()
+	dst, err := CreateLinuxMessageQueue(dstName, os.O_EXCL|os.O_RDWR, 0666, 8, 8)
+	if !a.NoError(err) {
+		return
+	}
+	defer dst.Destroy()
+	prios := []int{1, 4, 0, 3, 2}
+	for _, prio := range prios {
+		if !a.NoError(src.SendPriority([]byte{byte(prio)}, prio)) {
+			return
+		}
+	}
+	n, err := Forward(src, dst, 2, 0)
+	a.NoError(err)
+	a.Equal(2, n)
+	n, err = Forward(src, dst, 10, 10*time.Millisecond)
+	a.True(IsTimeout(err))
+	a.Equal(3, n)
+	buff := make([]byte, 8)
+	for expected := 4; expected >= 0; expected-- {
+		n, prio, err := dst.ReceiveTimeoutPriority(buff, 0)
+		if !a.NoError(err) {
+			return
+		}
+		a.Equal(expected, prio)
+		a.Equal([]byte{byte(expected)}, buff[:n])
+	}
+}
+
+func TestLinuxMqCloseOnExec(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 1, 8)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	// the shell prints the path of the descriptor, if it was inherited.
+	args := []string{"-c", fmt.Sprintf("readlink /proc/$$/fd/%d", mq.Fd())}
+	result := testutil.RunApp("sh", args, nil)
+	a.Error(result.Err)
+	if !a.NoError(mq.SetCloseOnExec(false)) {
+		return
+	}
+	result = testutil.RunApp("sh", args, nil)
+	if a.NoError(result.Err) {
+		a.Equal("/"+testMqName, strings.TrimSpace(result.Output))
+	}
+	if !a.NoError(mq.SetCloseOnExec(true)) {
+		return
+	}
+	result = testutil.RunApp("sh", args, nil)
+	a.Error(result.Err)
+}
+
+func TestLinuxMqReceiveTo(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 4, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	a.NoError(mq.SendPriority([]byte("first"), 1))
+	a.NoError(mq.SendPriority([]byte("second"), 2))
+	var buff bytes.Buffer
+	var prio int
+	n, err := mq.ReceiveTo(&buff, &prio)
+	a.NoError(err)
+	a.Equal(6, n)
+	a.Equal(2, prio)
+	n, err = mq.ReceiveTo(&buff, nil)
+	a.NoError(err)
+	a.Equal(5, n)
+	a.Equal("secondfirst", buff.String())
+}
+
+func benchmarkLinuxMqReceive(b *testing.B, receive func(mq *LinuxMessageQueue) error) {
+	if err := DestroyLinuxMessageQueue(testMqName); err != nil {
+		b.Fatal(err)
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 1, 1024)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer mq.Destroy()
+	data := make([]byte, 1024)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err = mq.Send(data); err != nil {
+			b.Fatal(err)
+		}
+		if err = receive(mq); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLinuxMqReceive(b *testing.B) {
+	benchmarkLinuxMqReceive(b, func(mq *LinuxMessageQueue) error {
+		buff := make([]byte, len(mq.inputBuff))
+		n, err := mq.Receive(buff)
+		ioutil.Discard.Write(buff[:n])
+		return err
+	})
+}
+
+func BenchmarkLinuxMqReceiveTo(b *testing.B) {
+	benchmarkLinuxMqReceive(b, func(mq *LinuxMessageQueue) error {
+		_, err := mq.ReceiveTo(ioutil.Discard, nil)
+		return err
+	})
+}
+
+func TestLinuxMqIsBlocking(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 1, 8)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	block, err := mq.IsBlocking()
+	a.NoError(err)
+	a.True(block)
+	a.NoError(mq.SetBlocking(false))
+	block, err = mq.IsBlocking()
+	a.NoError(err)
+	a.False(block)
+	// the mode is set for the instance only.
+	mq2, err := OpenLinuxMessageQueue(testMqName, os.O_RDWR)
+	if !a.NoError(err) {
+		return
+	}
+	block, err = mq2.IsBlocking()
+	a.NoError(err)
+	a.True(block)
+	a.NoError(mq2.Close())
+	a.NoError(mq.SetBlocking(true))
+	block, err = mq.IsBlocking()
+	a.NoError(err)
+	a.True(block)
+	mq3, err := OpenLinuxMessageQueue(testMqName, os.O_RDWR|O_NONBLOCK)
+	if !a.NoError(err) {
+		return
+	}
+	block, err = mq3.IsBlocking()
+	a.NoError(err)
+	a.False(block)
+	a.NoError(mq3.Close())
+}
+
+func TestLinuxMqDrainAndClose(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	defer DestroyLinuxMessageQueue(testMqName)
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 5, 16)
+	if !a.NoError(err) {
+		return
+	}
+	consumer, err := OpenLinuxMessageQueue(testMqName, os.O_RDONLY)
+	if !a.NoError(err) {
+		mq.Close()
+		return
+	}
+	defer consumer.Close()
+	for i := 0; i < 3; i++ {
+		a.NoError(mq.Send(make([]byte, 16)))
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		data := make([]byte, 16)
+		for i := 0; i < 3; i++ {
+			<-time.After(time.Millisecond * 20)
+			_, err := consumer.Receive(data)
+			a.NoError(err)
+		}
+	}()
+	a.NoError(mq.DrainAndClose(time.Second * 2))
+	<-done
+	// no consumer.
+	mq, err = OpenLinuxMessageQueue(testMqName, os.O_WRONLY)
+	if !a.NoError(err) {
+		return
+	}
+	a.NoError(mq.Send(make([]byte, 16)))
+	err = mq.DrainAndClose(time.Millisecond * 50)
+	a.True(IsTimeout(err))
+	l, err := consumer.Len()
+	a.NoError(err)
+	a.Equal(1, l)
+}
+
+type linuxMqTypedTestStruct struct {
+	ID     int64
+	Values [4]int32
+	Inner  struct {
+		A, B int16
+		C    [2]float64
+	}
+}
+
+func TestLinuxMqTypeCache(t *testing.T) {
+	type withRef struct {
+		Name string
+	}
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 1, 64)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	for i := 0; i < 2; i++ {
+		var obj linuxMqTypedTestStruct
+		data, err := mq.objectData(&obj)
+		a.NoError(err)
+		a.Len(data, int(unsafe.Sizeof(obj)))
+		_, err = mq.objectData(&withRef{})
+		a.Error(err)
+	}
+	a.Len(mq.checkedTypes, 2)
+	var items []withRef
+	_, err = mq.ReceiveBatch(&items, nil, 1)
+	a.Error(err)
+}
+
+func BenchmarkLinuxMqObjectData(b *testing.B) {
+	if err := DestroyLinuxMessageQueue(testMqName); err != nil {
+		b.Fatal(err)
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 1, 64)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer mq.Destroy()
+	var obj linuxMqTypedTestStruct
+	b.Run("cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := mq.objectData(&obj); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := allocator.ObjectData(&obj); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkLinuxMqReceiveBatch(b *testing.B) {
+	if err := DestroyLinuxMessageQueue(testMqName); err != nil {
+		b.Fatal(err)
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 1, 64)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer mq.Destroy()
+	data, err := allocator.ObjectData(&linuxMqTypedTestStruct{ID: 1})
+	if err != nil {
+		b.Fatal(err)
+	}
+	items := make([]linuxMqTypedTestStruct, 0, 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err = mq.Send(data); err != nil {
+			b.Fatal(err)
+		}
+		if _, err = mq.ReceiveBatch(&items, nil, 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestLinuxMqTrySendReceive(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	var obj linuxMqTypedTestStruct
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 1, int(unsafe.Sizeof(obj)))
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	ok, err := mq.TryReceive(&obj, nil)
+	a.NoError(err)
+	a.False(ok)
+	block, err := mq.IsBlocking()
+	a.NoError(err)
+	a.True(block)
+	a.Equal(0, mq.flags&O_NONBLOCK)
+	sent := linuxMqTypedTestStruct{ID: 7, Values: [4]int32{1, 2, 3, 4}}
+	ok, err = mq.TrySend(sent, 3)
+	a.NoError(err)
+	a.True(ok)
+	ok, err = mq.TrySend(&sent, 3)
+	a.NoError(err)
+	a.False(ok)
+	var prio int
+	ok, err = mq.TryReceive(&obj, &prio)
+	a.NoError(err)
+	a.True(ok)
+	a.Equal(sent, obj)
+	a.Equal(3, prio)
+	_, err = mq.TryReceive(obj, nil)
+	a.Error(err)
+	block, err = mq.IsBlocking()
+	a.NoError(err)
+	a.True(block)
+}
+
+func TestLinuxMqDeadline(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	var obj linuxMqTypedTestStruct
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 1, int(unsafe.Sizeof(obj)))
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	sent := linuxMqTypedTestStruct{ID: 5, Values: [4]int32{5, 6, 7, 8}}
+
+	// a deadline in the past fails without an attempt to send or receive.
+	past := time.Now().Add(-time.Second)
+	err = mq.SendDeadline(sent, 1, past)
+	a.True(IsTimeout(err))
+	a.True(IsFull(err))
+	err = mq.ReceiveDeadline(&obj, nil, past)
+	a.True(IsTimeout(err))
+	a.True(IsEmpty(err))
+	l, err := mq.Len()
+	a.NoError(err)
+	a.Equal(0, l)
+
+	// a future deadline, which is not met.
+	start := time.Now()
+	err = mq.ReceiveDeadline(&obj, nil, start.Add(50*time.Millisecond))
+	a.True(IsEmpty(err))
+	a.True(time.Since(start) >= 50*time.Millisecond)
+
+	// a future deadline, which is met by a concurrent send.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		mq.SendDeadline(&sent, 4, time.Now().Add(time.Second))
+	}()
+	var prio int
+	if !a.NoError(mq.ReceiveDeadline(&obj, &prio, time.Now().Add(5*time.Second))) {
+		return
+	}
+	a.Equal(sent, obj)
+	a.Equal(4, prio)
+}
+
+func TestLinuxMqMaxPriority(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 1, 8)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	maxPrio, err := mq.MaxPriority()
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(32767, maxPrio)
+	for _, prio := range []int{maxPrio + 1, -1} {
+		err = mq.SendPriority([]byte{1}, prio)
+		if a.Error(err) {
+			a.Contains(err.Error(), fmt.Sprintf("[0, %d]", maxPrio))
+			_, isMqErr := errors.Cause(err).(*MqError)
+			a.False(isMqErr)
+		}
+	}
+	if !a.NoError(mq.SendPriority([]byte{1}, maxPrio)) {
+		return
+	}
+	_, prio, err := mq.ReceivePriority(make([]byte, 8))
+	a.NoError(err)
+	a.Equal(maxPrio, prio)
+}
+
+func TestLinuxMqClone(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 2, 8)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	if !a.NoError(mq.EnableCounters(0666)) {
+		return
+	}
+	clone, err := mq.Clone()
+	if !a.NoError(err) {
+		return
+	}
+	a.NotEqual(mq.ID(), clone.ID())
+	// the clone shares the kernel queue.
+	if !a.NoError(clone.SendPriority([]byte{1}, 1)) {
+		return
+	}
+	data := make([]byte, 8)
+	n, err := mq.Receive(data)
+	a.NoError(err)
+	a.Equal([]byte{1}, data[:n])
+	a.NoError(clone.Close())
+	// closing the clone does not affect the original.
+	if !a.NoError(mq.Send([]byte{2})) {
+		return
+	}
+	n, err = mq.Receive(data)
+	a.NoError(err)
+	a.Equal([]byte{2}, data[:n])
+	// the clone has updated the shared counters too.
+	a.NoError(mq.Sync(0))
 }
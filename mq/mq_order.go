@@ -0,0 +1,51 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"fmt"
+	"sync"
+)
+
+var (
+	orderHandlerMu sync.Mutex
+	orderHandler   func(err *OrderViolationError)
+)
+
+// OrderViolationError describes a message, which was delivered out of order.
+// Within a priority messages from a single sender must be received in the same order they were sent.
+type OrderViolationError struct {
+	// Prio is the priority of the message.
+	Prio int
+	// Sender is an id of the queue instance, which sent the message.
+	Sender uint64
+	// Last is the sequence number of the last message received from the sender.
+	Last uint64
+	// Got is the sequence number of the received message.
+	Got uint64
+}
+
+func (e *OrderViolationError) Error() string {
+	return fmt.Sprintf("mq order violation: prio %d, sender %x: got message #%d after #%d", e.Prio, e.Sender, e.Got, e.Last)
+}
+
+// SetOrderViolationHandler sets a function, which is called, when a message is received out of order.
+// If the handler is nil (default), the receiver panics with *OrderViolationError.
+// Order checks are performed only if the package is built with 'mq_debug' tag.
+// In this mode linux mq stamps every message with a sequence number on send and verifies it on receive.
+// Without the tag the checks are compiled out, and the handler is never called.
+func SetOrderViolationHandler(handler func(err *OrderViolationError)) {
+	orderHandlerMu.Lock()
+	orderHandler = handler
+	orderHandlerMu.Unlock()
+}
+
+func reportOrderViolation(err *OrderViolationError) {
+	orderHandlerMu.Lock()
+	handler := orderHandler
+	orderHandlerMu.Unlock()
+	if handler == nil {
+		panic(err)
+	}
+	handler(err)
+}
@@ -0,0 +1,94 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build linux,mq_debug
+
+package mq
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+const (
+	orderStampSize = int(unsafe.Sizeof(orderStamp{}))
+)
+
+var (
+	orderInstanceCounter uint32
+)
+
+// orderStamp is written at the beginning of every message.
+type orderStamp struct {
+	sender uint64
+	seq    uint64
+}
+
+type orderKey struct {
+	sender uint64
+	prio   int
+}
+
+// orderChecker stamps outgoing messages with per-priority sequence numbers
+// and verifies, that incoming messages from every sender have increasing numbers within a priority.
+type orderChecker struct {
+	*orderState
+}
+
+type orderState struct {
+	sendMu sync.Mutex
+	mu     sync.Mutex
+	id     uint64
+	sent   map[int]uint64
+	recv   map[orderKey]uint64
+}
+
+func newOrderChecker() orderChecker {
+	return orderChecker{&orderState{
+		id:   uint64(os.Getpid())<<32 | uint64(atomic.AddUint32(&orderInstanceCounter, 1)),
+		sent: make(map[int]uint64),
+		recv: make(map[orderKey]uint64),
+	}}
+}
+
+// beginSend returns stamped data. Sends are serialized until endSend is called,
+// so that messages from one instance reach the queue in the order of their numbers.
+func (c orderChecker) beginSend(data []byte, prio int) []byte {
+	st := c.orderState
+	st.sendMu.Lock()
+	st.mu.Lock()
+	st.sent[prio]++
+	stamp := orderStamp{sender: st.id, seq: st.sent[prio]}
+	st.mu.Unlock()
+	result := make([]byte, orderStampSize+len(data))
+	*(*orderStamp)(unsafe.Pointer(&result[0])) = stamp
+	copy(result[orderStampSize:], data)
+	return result
+}
+
+func (c orderChecker) endSend() {
+	c.sendMu.Unlock()
+}
+
+// verify checks message's stamp and removes it from the data.
+// Returns the len of the message without the stamp.
+func (c orderChecker) verify(data []byte, prio int) int {
+	if len(data) < orderStampSize {
+		return len(data)
+	}
+	stamp := *(*orderStamp)(unsafe.Pointer(&data[0]))
+	st := c.orderState
+	key := orderKey{sender: stamp.sender, prio: prio}
+	st.mu.Lock()
+	last := st.recv[key]
+	if stamp.seq > last {
+		st.recv[key] = stamp.seq
+	}
+	st.mu.Unlock()
+	if stamp.seq <= last {
+		reportOrderViolation(&OrderViolationError{Prio: prio, Sender: stamp.sender, Last: last, Got: stamp.seq})
+	}
+	copy(data, data[orderStampSize:])
+	return len(data) - orderStampSize
+}
@@ -0,0 +1,85 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build linux,mq_debug
+
+package mq
+
+import (
+	"os"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinuxMqOrderCheckInOrder(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 8, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	var violations []*OrderViolationError
+	SetOrderViolationHandler(func(err *OrderViolationError) {
+		violations = append(violations, err)
+	})
+	defer SetOrderViolationHandler(nil)
+	for i := 0; i < 8; i++ {
+		a.NoError(mq.SendPriority([]byte{byte(i)}, i%2))
+	}
+	data := make([]byte, 16)
+	for _, expected := range []byte{1, 3, 5, 7, 0, 2, 4, 6} {
+		l, _, err := mq.ReceivePriority(data)
+		if a.NoError(err) {
+			a.Equal(1, l)
+			a.Equal(expected, data[0])
+		}
+	}
+	a.Empty(violations)
+}
+
+func TestLinuxMqOrderCheckViolation(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 8, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	// simulate a kernel, which reorders messages, sending raw stamped data.
+	send := func(seq uint64) {
+		data := make([]byte, orderStampSize+1)
+		*(*orderStamp)(unsafe.Pointer(&data[0])) = orderStamp{sender: 42, seq: seq}
+		a.NoError(mq_timedsend(mq.ID(), data, 1, nil))
+	}
+	send(2)
+	send(1)
+	data := make([]byte, 16)
+	_, _, err = mq.ReceivePriority(data)
+	a.NoError(err)
+	a.Panics(func() {
+		mq.ReceivePriority(data)
+	})
+	send(4)
+	send(3)
+	var violation *OrderViolationError
+	SetOrderViolationHandler(func(err *OrderViolationError) {
+		violation = err
+	})
+	defer SetOrderViolationHandler(nil)
+	_, _, err = mq.ReceivePriority(data)
+	a.NoError(err)
+	_, _, err = mq.ReceivePriority(data)
+	a.NoError(err)
+	if a.NotNil(violation) {
+		a.Equal(1, violation.Prio)
+		a.Equal(uint64(42), violation.Sender)
+		a.Equal(uint64(4), violation.Last)
+		a.Equal(uint64(3), violation.Got)
+	}
+}
@@ -4,11 +4,14 @@ package mq
 
 import (
 	"fmt"
+	"math"
 	"os"
+	"sync"
 	"syscall"
 	"unsafe"
 
 	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/common"
 
 	"golang.org/x/sys/unix"
 )
@@ -18,9 +21,64 @@ const (
 	cSIGEV_NONE        = 1
 	cSIGEV_THREAD      = 2
 	cNOTIFY_COOKIE_LEN = 32
+	// cMQ_PRIO_MAX is the number of message priorities from linux/mqueue.h.
+	// it is used, if the value can't be queried from the kernel.
+	cMQ_PRIO_MAX = 32768
 )
 
-func initLinuxMqNotifications(ch chan<- int) (notifySocket int, cancelSocket int, err error) {
+var (
+	mqPrioMaxOnce  sync.Once
+	mqPrioMaxValue int
+)
+
+// mqPrioMax returns the number of message priorities, which is sysconf(_SC_MQ_PRIO_MAX).
+// The value is queried once and then cached.
+func mqPrioMax() int {
+	mqPrioMaxOnce.Do(func() {
+		mqPrioMaxValue = queryMqPrioMax()
+	})
+	return mqPrioMaxValue
+}
+
+// queryMqPrioMax finds the number of message priorities supported by the kernel.
+// libc does not make a syscall for sysconf(_SC_MQ_PRIO_MAX), so the value is found with a binary search:
+// mq_timedsend checks the priority before the descriptor, so, with an invalid descriptor,
+// it fails with EINVAL for an invalid priority, and with EBADF for a valid one.
+func queryMqPrioMax() int {
+	validPrio := func(prio int) (valid bool, ok bool) {
+		err := mq_timedsend(-1, nil, prio, nil)
+		switch {
+		case common.SyscallErrHasCode(err, unix.EBADF):
+			return true, true
+		case common.SyscallErrHasCode(err, unix.EINVAL):
+			return false, true
+		}
+		return false, false
+	}
+	// lo is always a valid priority, hi is always an invalid one.
+	lo, hi := 0, math.MaxInt32
+	if valid, ok := validPrio(lo); !valid || !ok {
+		return cMQ_PRIO_MAX
+	}
+	if valid, ok := validPrio(hi); valid || !ok {
+		return cMQ_PRIO_MAX
+	}
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		valid, ok := validPrio(mid)
+		if !ok {
+			return cMQ_PRIO_MAX
+		}
+		if valid {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi
+}
+
+func initLinuxMqNotifications(notify func(id int)) (notifySocket int, cancelSocket int, err error) {
 	notifySocket, cancelSocket = -1, -1
 	defer func() {
 		if err != nil {
@@ -48,12 +106,12 @@ func initLinuxMqNotifications(ch chan<- int) (notifySocket int, cancelSocket int
 		return
 	}
 	if err = unix.Listen(cancelSocket, 1); err == nil {
-		go listenLinuxMqNotifications(ch, notifySocket, cancelSocket)
+		go listenLinuxMqNotifications(notify, notifySocket, cancelSocket)
 	}
 	return
 }
 
-func listenLinuxMqNotifications(ch chan<- int, notifySocket int, cancelSocket int) {
+func listenLinuxMqNotifications(notify func(id int), notifySocket int, cancelSocket int) {
 	var data [cNOTIFY_COOKIE_LEN]byte
 	r := &unix.FdSet{}
 	defer func() {
@@ -74,7 +132,7 @@ func listenLinuxMqNotifications(ch chan<- int, notifySocket int, cancelSocket in
 			n, _, err := unix.Recvfrom(notifySocket, data[:], unix.MSG_NOSIGNAL|unix.MSG_WAITALL)
 			if n == cNOTIFY_COOKIE_LEN && err == nil {
 				ndata := (*notify_data)(allocator.ByteSliceData(data[:]))
-				ch <- ndata.mq_id
+				notify(ndata.mq_id)
 			}
 		}
 	}
@@ -132,7 +190,7 @@ type sigevent struct {
 }
 
 func mq_open(name string, flags int, mode uint32, attrs *linuxMqAttr) (int, error) {
-	nameBytes, err := unix.BytePtrFromString(name)
+	nameBytes, err := unix.BytePtrFromString(common.PrefixedName(name))
 	if err != nil {
 		return -1, err
 	}
@@ -220,7 +278,7 @@ func mq_getsetattr(id int, attrs, oldAttrs *linuxMqAttr) error {
 }
 
 func mq_unlink(name string) error {
-	nameBytes, err := unix.BytePtrFromString(name)
+	nameBytes, err := unix.BytePtrFromString(common.PrefixedName(name))
 	if err != nil {
 		return err
 	}
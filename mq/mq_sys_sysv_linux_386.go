@@ -53,7 +53,8 @@ func msgsnd(id int, typ int, data []byte, flags int) error {
 	return nil
 }
 
-func msgrcv(id int, data []byte, typ int, flags int) (int, error) {
+// msgrcv receives a message and returns its len and type.
+func msgrcv(id int, data []byte, typ int, flags int) (int, int, error) {
 	messageLen := typeDataSize + len(data)
 	message := make([]byte, messageLen)
 	rawData := allocator.ByteSliceData(message)
@@ -67,9 +68,9 @@ func msgrcv(id int, data []byte, typ int, flags int) (int, error) {
 	allocator.Use(rawData)
 	copy(data, message[typeDataSize:])
 	if err != syscall.Errno(0) {
-		return 0, os.NewSyscallError("MSGRCV", err)
+		return 0, 0, os.NewSyscallError("MSGRCV", err)
 	}
-	return int(len), nil
+	return int(len), *(*int)(rawData), nil
 }
 
 func msgctl(id int, cmd int, buf *msqidDs) error {
@@ -6,16 +6,24 @@ package mq
 
 import (
 	"os"
+	"time"
 	"unsafe"
 
 	"github.com/nxgtw/go-ipc/internal/common"
 
 	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
 )
 
 const (
-	cDefaultMessageType = 1
-	cSysVAnyMessage     = 0
+	// SystemVMqMaxPrio is the maximum priority of a message in a System V queue.
+	// Priorities are mapped to message types, so that messages with higher priorities have lower types,
+	// as msgrcv returns a message with the lowest type first.
+	// Priority 0 is mapped to type SystemVMqMaxPrio+1, and SystemVMqMaxPrio is mapped to type 1.
+	// Earlier versions of the package sent all messages with type 1, so such messages,
+	// as well as messages sent with type 1 by other programs, are received before any other messages,
+	// and their priority is SystemVMqMaxPrio.
+	SystemVMqMaxPrio = 32767
 
 	typeDataSize = int(unsafe.Sizeof(int(0)))
 )
@@ -24,7 +32,8 @@ const (
 type SystemVMessageQueue struct {
 	flags int
 	id    int
-	name  string
+	// name is empty, if the queue was created or opened with a key.
+	name string
 }
 
 // msqidDs is for msgctl syscall, but it is not currently used
@@ -34,7 +43,9 @@ type msqidDs struct {
 // this is to ensure, that system V implementation of ipc mq
 // satisfies the minimal queue interface
 var (
-	_ Messenger = (*SystemVMessageQueue)(nil)
+	_ Messenger      = (*SystemVMessageQueue)(nil)
+	_ Blocker        = (*SystemVMessageQueue)(nil)
+	_ TimedMessenger = (*SystemVMessageQueue)(nil)
 )
 
 // CreateSystemVMessageQueue creates new queue with the given name and permissions.
@@ -76,38 +87,114 @@ func OpenSystemVMessageQueue(name string, flags int) (*SystemVMessageQueue, erro
 	return result, nil
 }
 
-// Send sends a message. It blocks if the queue is full.
+// CreateSystemVMessageQueueKey creates new queue with the given System V ipc key and permissions.
+// It allows to share the queue with programs, which are not built with this package and use the same key.
+// It fails, if a queue with this key already exists.
+//	key - System V ipc key of the queue.
+//	perm - object's permission bits.
+func CreateSystemVMessageQueueKey(key int, perm os.FileMode) (*SystemVMessageQueue, error) {
+	if !checkMqPerm(perm) {
+		return nil, errors.New("invalid mq permissions")
+	}
+	id, err := msgget(common.Key(key), int(perm)|common.IpcCreate|common.IpcExcl)
+	if err != nil {
+		return nil, errors.Wrap(err, "msgget failed")
+	}
+	return &SystemVMessageQueue{id: id}, nil
+}
+
+// OpenSystemVMessageQueueKey opens existing message queue with the given key.
+//	key - System V ipc key of the queue.
+//	flags - 0 and O_NONBLOCK.
+func OpenSystemVMessageQueueKey(key int, flags int) (*SystemVMessageQueue, error) {
+	id, err := msgget(common.Key(key), 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "msgget failed")
+	}
+	return &SystemVMessageQueue{id: id, flags: flags}, nil
+}
+
+// Send sends a message with a default (0) priority. It blocks if the queue is full.
 func (mq *SystemVMessageQueue) Send(data []byte) error {
-	var sysFlags int
-	if mq.flags&O_NONBLOCK != 0 {
-		sysFlags |= common.IpcNoWait
+	return mq.SendPriority(data, 0)
+}
+
+// SendPriority sends a message with a given priority. It blocks if the queue is full.
+// Messages with higher priorities are received first.
+//	prio - message priority in range [0, SystemVMqMaxPrio].
+func (mq *SystemVMessageQueue) SendPriority(data []byte, prio int) error {
+	return mq.SendTimeoutPriority(data, prio, mq.timeout())
+}
+
+// SendTimeout sends a message with a default (0) priority.
+// It blocks if the queue is full, waiting for not longer, than timeout.
+func (mq *SystemVMessageQueue) SendTimeout(data []byte, timeout time.Duration) error {
+	return mq.SendTimeoutPriority(data, 0, timeout)
+}
+
+// SendTimeoutPriority sends a message with a given priority.
+// It blocks if the queue is full, waiting for not longer, than timeout.
+// As System V queues do not support timeouts, the queue is polled with an increasing interval.
+func (mq *SystemVMessageQueue) SendTimeoutPriority(data []byte, prio int, timeout time.Duration) error {
+	if prio < 0 || prio > SystemVMqMaxPrio {
+		return errors.Errorf("invalid priority %d", prio)
 	}
-	f := func() error { return msgsnd(mq.id, cDefaultMessageType, data, sysFlags) }
-	return common.UninterruptedSyscall(f)
+	typ := sysVTypeForPrio(prio)
+	return sysVCallTimeout(func(sysFlags int) error {
+		return common.UninterruptedSyscall(func() error { return msgsnd(mq.id, typ, data, sysFlags) })
+	}, timeout, "MSGSND")
 }
 
 // Receive receives a message. It blocks if the queue is empty.
+// Messages with higher priorities are received first.
 func (mq *SystemVMessageQueue) Receive(data []byte) (int, error) {
-	var sysFlags int
-	if mq.flags&O_NONBLOCK != 0 {
-		sysFlags |= common.IpcNoWait
-	}
-	var len int
-	f := func() error {
-		var err error
-		len, err = msgrcv(mq.id, data, cSysVAnyMessage, sysFlags)
-		return err
+	len, _, err := mq.ReceiveTimeoutPriority(data, mq.timeout())
+	return len, err
+}
+
+// ReceivePriority receives a message, returning its len and priority. It blocks if the queue is empty.
+func (mq *SystemVMessageQueue) ReceivePriority(data []byte) (int, int, error) {
+	return mq.ReceiveTimeoutPriority(data, mq.timeout())
+}
+
+// ReceiveTimeout receives a message. It blocks if the queue is empty, waiting for not longer, than timeout.
+func (mq *SystemVMessageQueue) ReceiveTimeout(data []byte, timeout time.Duration) (int, error) {
+	len, _, err := mq.ReceiveTimeoutPriority(data, timeout)
+	return len, err
+}
+
+// ReceiveTimeoutPriority receives a message, returning its len and priority.
+// It blocks if the queue is empty, waiting for not longer, than timeout.
+// As System V queues do not support timeouts, the queue is polled with an increasing interval.
+func (mq *SystemVMessageQueue) ReceiveTimeoutPriority(data []byte, timeout time.Duration) (int, int, error) {
+	var len, typ int
+	err := sysVCallTimeout(func(sysFlags int) error {
+		return common.UninterruptedSyscall(func() error {
+			var err error
+			// a negative type makes msgrcv return the message with the lowest type,
+			// which corresponds to the highest priority.
+			len, typ, err = msgrcv(mq.id, data, -sysVTypeForPrio(0), sysFlags)
+			return err
+		})
+	}, timeout, "MSGRCV")
+	if err != nil {
+		return 0, 0, err
 	}
-	return len, common.UninterruptedSyscall(f)
+	return len, sysVPrioForType(typ), nil
 }
 
 // Destroy closes the queue and removes it permanently.
 func (mq *SystemVMessageQueue) Destroy() error {
+	id := mq.id
 	if err := mq.Close(); err != nil {
 		return errors.Wrap(err, "mq close failed")
 	}
-	err := msgctl(mq.id, common.IpcRmid, nil)
+	err := msgctl(id, common.IpcRmid, nil)
 	if err == nil {
+		if mq.name == "" {
+			// the queue was created with a key, there is no temporary file.
+			return nil
+		}
 		if err = os.Remove(common.TmpFilename(mq.name)); os.IsNotExist(err) {
 			err = nil
 		} else {
@@ -121,10 +208,12 @@ func (mq *SystemVMessageQueue) Destroy() error {
 	return err
 }
 
-// Close closes the queue.
-// As there is no need to close SystemV mq, this function returns nil.
+// Close closes the queue, so that it can't be used via this instance any more.
+// As there is no need to close SystemV mq, nothing is released, and this function returns nil.
 // It was added to satisfy io.Closer
 func (mq *SystemVMessageQueue) Close() error {
+	common.UnregisterFromCleanup(mq)
+	mq.id = -1
 	return nil
 }
 
@@ -138,7 +227,55 @@ func (mq *SystemVMessageQueue) SetBlocking(block bool) error {
 	return nil
 }
 
-// DestroySystemVMessageQueue permanently removes queue with a given name.
+func (mq *SystemVMessageQueue) timeout() time.Duration {
+	if mq.flags&O_NONBLOCK != 0 {
+		return 0
+	}
+	return -1
+}
+
+// sysVCallTimeout calls f until it succeeds or the timeout expires.
+// f is called with IpcNoWait flag, unless the timeout is infinite.
+func sysVCallTimeout(f func(sysFlags int) error, timeout time.Duration, op string) error {
+	const maxPollInterval = 50 * time.Millisecond
+	if timeout < 0 {
+		return f(0)
+	}
+	done, err := common.PollTimeout(func() (bool, error) {
+		err := f(common.IpcNoWait)
+		if err != nil && isSysVNoWaitErr(err) {
+			return false, nil
+		}
+		return true, err
+	}, timeout, maxPollInterval, nil)
+	if err != nil {
+		return err
+	}
+	if !done {
+		return common.NewTimeoutError(op)
+	}
+	return nil
+}
+
+// isSysVNoWaitErr returns true, if the error means, that the operation would block.
+func isSysVNoWaitErr(err error) bool {
+	return common.IsTimeoutErr(err) || common.SyscallErrHasCode(err, unix.ENOMSG)
+}
+
+func sysVTypeForPrio(prio int) int {
+	return SystemVMqMaxPrio + 1 - prio
+}
+
+func sysVPrioForType(typ int) int {
+	if typ < 1 || typ > SystemVMqMaxPrio+1 {
+		// a message was sent by some other means.
+		return 0
+	}
+	return SystemVMqMaxPrio + 1 - typ
+}
+
+// DestroySystemVMessageQueue permanently removes queue with a given name,
+// which was created with CreateSystemVMessageQueue.
 func DestroySystemVMessageQueue(name string) error {
 	mq, err := OpenSystemVMessageQueue(name, 0)
 	if err != nil {
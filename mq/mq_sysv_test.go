@@ -6,7 +6,18 @@ package mq
 
 import (
 	"os"
+	"strconv"
 	"testing"
+	"time"
+
+	testutil "github.com/nxgtw/go-ipc/internal/test"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testSysVMqKey = 0x7e57
 )
 
 func sysVMqCtor(name string, flag int, perm os.FileMode) (Messenger, error) {
@@ -21,6 +32,17 @@ func sysVMqDtor(name string) error {
 	return DestroySystemVMessageQueue(name)
 }
 
+func destroySysVMqKey(key int) error {
+	mq, err := OpenSystemVMessageQueueKey(key, 0)
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			return nil
+		}
+		return err
+	}
+	return mq.Destroy()
+}
+
 func TestCreateSysVMq(t *testing.T) {
 	testCreateMq(t, sysVMqCtor, sysVMqDtor)
 }
@@ -64,3 +86,165 @@ func TestSysVMqReceiveNonBlock(t *testing.T) {
 func TestSysVMqReceiveFromAnotherProcess(t *testing.T) {
 	testMqReceiveFromAnotherProcess(t, sysVMqCtor, sysVMqDtor, "sysv")
 }
+
+func TestSysVMqReceiveTimeout(t *testing.T) {
+	testMqReceiveTimeout(t, sysVMqCtor, sysVMqDtor)
+}
+
+func TestSysVMqSendTimeout(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroySystemVMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateSystemVMessageQueue(testMqName, os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	data := make([]byte, 1024)
+	// fill the queue.
+	for {
+		if err = mq.SendTimeout(data, 0); err != nil {
+			break
+		}
+	}
+	a.True(IsTemporary(err))
+	tm := time.Millisecond * 100
+	now := time.Now()
+	err = mq.SendTimeout(data, tm)
+	a.True(IsTemporary(err))
+	a.True(time.Since(now) >= tm)
+}
+
+func TestSysVMqPriority(t *testing.T) {
+	prios := [...]int{8, 4, 7, 1, 0, 15, 2, 4, SystemVMqMaxPrio}
+	a := assert.New(t)
+	if !a.NoError(DestroySystemVMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateSystemVMessageQueue(testMqName, os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	a.Error(mq.SendPriority([]byte{0}, -1))
+	a.Error(mq.SendPriority([]byte{0}, SystemVMqMaxPrio+1))
+	for i, prio := range prios {
+		a.NoError(mq.SendPriority([]byte{byte(i)}, prio))
+	}
+	expected := []int{SystemVMqMaxPrio, 15, 8, 7, 4, 4, 2, 1, 0}
+	// messages with the same priority are received in fifo order.
+	expectedIdx := []byte{8, 5, 0, 2, 1, 7, 6, 3, 4}
+	data := make([]byte, 1)
+	for i, expectedPrio := range expected {
+		_, prio, err := mq.ReceivePriority(data)
+		if a.NoError(err) {
+			a.Equal(expectedPrio, prio)
+			a.Equal(expectedIdx[i], data[0])
+		}
+	}
+	_, err = mq.ReceiveTimeout(data, 0)
+	a.True(IsTemporary(err))
+}
+
+func TestSysVMqKey(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(destroySysVMqKey(testSysVMqKey)) {
+		return
+	}
+	_, err := CreateSystemVMessageQueueKey(testSysVMqKey, 0777)
+	a.Error(err)
+	mq, err := CreateSystemVMessageQueueKey(testSysVMqKey, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	_, err = CreateSystemVMessageQueueKey(testSysVMqKey, 0666)
+	a.Error(err)
+	mq2, err := OpenSystemVMessageQueueKey(testSysVMqKey, O_NONBLOCK)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq2.Close()
+	a.NoError(mq.SendPriority([]byte{1, 2, 3}, 5))
+	data := make([]byte, 3)
+	l, prio, err := mq2.ReceivePriority(data)
+	if a.NoError(err) {
+		a.Equal(3, l)
+		a.Equal(5, prio)
+		a.Equal([]byte{1, 2, 3}, data)
+	}
+	_, err = mq2.Receive(data)
+	a.True(IsTemporary(err))
+}
+
+func TestSysVMqKeySendToAnotherProcess(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(destroySysVMqKey(testSysVMqKey)) {
+		return
+	}
+	mq, err := CreateSystemVMessageQueueKey(testSysVMqKey, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	data := make([]byte, 2048)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	args := argsForMqTestCommand(strconv.Itoa(testSysVMqKey), -1, "sysvkey", "", data)
+	go func() {
+		a.NoError(mq.Send(data))
+	}()
+	result := testutil.RunTestApp(args, nil)
+	if !a.NoError(result.Err) {
+		t.Logf("program output is: '%s'", result.Output)
+	}
+}
+
+func TestSysVMqKeyReceiveFromAnotherProcess(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(destroySysVMqKey(testSysVMqKey)) {
+		return
+	}
+	mq, err := CreateSystemVMessageQueueKey(testSysVMqKey, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	data := make([]byte, 2048)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	args := argsForMqSendCommand(strconv.Itoa(testSysVMqKey), -1, "sysvkey", "", data)
+	result := testutil.RunTestApp(args, nil)
+	if !a.NoError(result.Err) {
+		t.Logf("program output is %s", result.Output)
+	}
+	received := make([]byte, 2048)
+	l, err := mq.Receive(received)
+	a.NoError(err)
+	a.Equal(len(data), l)
+	a.Equal(data, received)
+}
+
+func TestSysVMqLegacyType(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(destroySysVMqKey(testSysVMqKey)) {
+		return
+	}
+	mq, err := CreateSystemVMessageQueueKey(testSysVMqKey, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	a.NoError(mq.SendPriority([]byte{1}, 0))
+	// a message sent by an older version of the package has type 1.
+	a.NoError(msgsnd(mq.id, 1, []byte{2}, 0))
+	data := make([]byte, 1)
+	_, prio, err := mq.ReceivePriority(data)
+	if a.NoError(err) {
+		a.Equal(SystemVMqMaxPrio, prio)
+		a.Equal(byte(2), data[0])
+	}
+}
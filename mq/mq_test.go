@@ -476,3 +476,12 @@ func argsForMqNotifyWaitCommand(name string, timeout int, typ, options string) [
 		"notifywait",
 	)
 }
+
+func argsForMqDrainCommand(name, typ string, n int) []string {
+	return append(mqProgArgs,
+		"-object="+name,
+		"-type="+typ,
+		"drain",
+		strconv.Itoa(n),
+	)
+}
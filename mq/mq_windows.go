@@ -0,0 +1,332 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"encoding/binary"
+	"os"
+	"time"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/common"
+	"github.com/nxgtw/go-ipc/internal/helper"
+	"bitbucket.org/avd/go-ipc/mmf"
+	"bitbucket.org/avd/go-ipc/shm"
+	ipc_sync "bitbucket.org/avd/go-ipc/sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultWindowsMqMaxSize is the default windows mq queue size.
+	DefaultWindowsMqMaxSize = 8
+	// DefaultWindowsMqMessageSize is the default windows mq message size.
+	DefaultWindowsMqMessageSize = 8192
+
+	windowsMqHdrSize     = int(unsafe.Sizeof(windowsMqHdr{}))
+	windowsMqSlotHdrSize = 4
+)
+
+// this is to ensure, that WindowsMessageQueue satisfies queue interfaces.
+var (
+	_ Messenger      = (*WindowsMessageQueue)(nil)
+	_ TimedMessenger = (*WindowsMessageQueue)(nil)
+	_ Buffered       = (*WindowsMessageQueue)(nil)
+	_ Blocker        = (*WindowsMessageQueue)(nil)
+)
+
+// windowsMqHdr is placed at the beginning of the shared memory region.
+// It is followed by maxQueueSize slots, each of them is a 4-byte message length
+// and maxMsgSize bytes of data.
+type windowsMqHdr struct {
+	maxQueueSize int32
+	maxMsgSize   int32
+	// head is the index of the oldest message.
+	head int32
+	// count is the number of messages in the queue.
+	count int32
+}
+
+// WindowsMessageQueue is a fifo message queue based on a ring buffer in shared memory.
+// Two named semaphores count free and used slots, so senders and receivers wait for them
+// with WaitForSingleObject, and a named mutex guards the ring buffer.
+// If a process crashes in the middle of a send or receive operation, the slot it was using may be lost.
+type WindowsMessageQueue struct {
+	name   string
+	flag   int
+	region *mmf.MemoryRegion
+	hdr    *windowsMqHdr
+	slots  unsafe.Pointer
+	locker ipc_sync.IPCLocker
+	// free is the number of free slots.
+	free *ipc_sync.Semaphore
+	// used is the number of messages.
+	used *ipc_sync.Semaphore
+}
+
+// CreateWindowsMessageQueue creates new queue with the given name and permissions.
+//	name - unique mq name. implementation will create a shm object with this name.
+//	flag - flag is a combination of os.O_EXCL and O_NONBLOCK.
+//	perm - object's permission bits.
+//	maxQueueSize - queue capacity.
+//	maxMsgSize - maximum message size.
+func CreateWindowsMessageQueue(name string, flag int, perm os.FileMode, maxQueueSize, maxMsgSize int) (*WindowsMessageQueue, error) {
+	return openWindowsMq(name, flag|os.O_CREATE, perm, maxQueueSize, maxMsgSize)
+}
+
+// OpenWindowsMessageQueue opens an existing message queue. It returns an error, if it does not exist.
+//	name - unique mq name.
+//	flag - 0 or O_NONBLOCK.
+func OpenWindowsMessageQueue(name string, flag int) (*WindowsMessageQueue, error) {
+	maxQueueSize, maxMsgSize, err := windowsMqAttrs(name)
+	if err != nil {
+		return nil, err
+	}
+	return openWindowsMq(name, flag&O_NONBLOCK, 0666, maxQueueSize, maxMsgSize)
+}
+
+// DestroyWindowsMessageQueue permanently removes a queue.
+// The semaphores are destroyed, when all the instances of the queue are closed.
+func DestroyWindowsMessageQueue(name string) error {
+	errMutex := ipc_sync.DestroyMutex(windowsMqLockerName(name))
+	errFree := ipc_sync.DestroySemaphore(windowsMqSemaName(name, "f"))
+	errUsed := ipc_sync.DestroySemaphore(windowsMqSemaName(name, "u"))
+	if err := shm.DestroyMemoryObject(windowsMqStateName(name)); err != nil {
+		return errors.Wrap(err, "failed to destroy memory object")
+	}
+	if errMutex != nil {
+		return errors.Wrap(errMutex, "failed to destroy ipc locker")
+	}
+	if errFree != nil {
+		return errors.Wrap(errFree, "failed to destroy free slots semaphore")
+	}
+	if errUsed != nil {
+		return errors.Wrap(errUsed, "failed to destroy used slots semaphore")
+	}
+	return nil
+}
+
+func openWindowsMq(name string, flag int, perm os.FileMode, maxQueueSize, maxMsgSize int) (*WindowsMessageQueue, error) {
+	if !checkMqPerm(perm) {
+		return nil, errors.New("invalid mq permissions")
+	}
+	if maxQueueSize <= 0 || maxMsgSize <= 0 {
+		return nil, errors.New("invalid mq size")
+	}
+	openFlags := common.FlagsForOpen(flag)
+	region, created, err := helper.CreateWritableRegion(windowsMqStateName(name), openFlags, perm, windowsMqSize(maxQueueSize, maxMsgSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create shared state")
+	}
+	raw := allocator.ByteSliceData(region.Data())
+	result := &WindowsMessageQueue{
+		name:   name,
+		flag:   flag,
+		region: region,
+		hdr:    (*windowsMqHdr)(raw),
+		slots:  allocator.AdvancePointer(raw, uintptr(windowsMqHdrSize)),
+	}
+	if created {
+		result.hdr.maxQueueSize, result.hdr.maxMsgSize = int32(maxQueueSize), int32(maxMsgSize)
+		result.hdr.head, result.hdr.count = 0, 0
+	} else if int(result.hdr.maxQueueSize) != maxQueueSize || int(result.hdr.maxMsgSize) != maxMsgSize {
+		region.Close()
+		return nil, errors.New("the queue exists and has different parameters")
+	}
+	if err = result.openSyncObjects(perm, created); err != nil {
+		result.Close()
+		if created {
+			DestroyWindowsMessageQueue(name)
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// openSyncObjects opens the mutex and the semaphores of the queue.
+// Windows kernel objects are destroyed, when their last handle is closed, while the messages
+// are kept in the memory object, so the semaphores are created with the counters,
+// which correspond to the current state of the ring buffer.
+func (mq *WindowsMessageQueue) openSyncObjects(perm os.FileMode, created bool) error {
+	var err error
+	if created {
+		// cleanup previous mutex instances. it could be useful in a case,
+		// when previous mutex owner crashed, and the mutex is in incosistient state.
+		if err = ipc_sync.DestroyMutex(windowsMqLockerName(mq.name)); err != nil {
+			return errors.Wrap(err, "windows mq: failed to access a locker")
+		}
+	}
+	if mq.locker, err = ipc_sync.NewMutex(windowsMqLockerName(mq.name), os.O_CREATE, perm); err != nil {
+		return errors.Wrap(err, "windows mq: failed to create a locker")
+	}
+	mq.locker.Lock()
+	defer mq.locker.Unlock()
+	count := int(mq.hdr.count)
+	mq.free, err = ipc_sync.NewSemaphore(windowsMqSemaName(mq.name, "f"), os.O_CREATE, perm, int(mq.hdr.maxQueueSize)-count)
+	if err != nil {
+		return errors.Wrap(err, "windows mq: failed to create free slots semaphore")
+	}
+	mq.used, err = ipc_sync.NewSemaphore(windowsMqSemaName(mq.name, "u"), os.O_CREATE, perm, count)
+	if err != nil {
+		return errors.Wrap(err, "windows mq: failed to create used slots semaphore")
+	}
+	return nil
+}
+
+// windowsMqAttrs returns capacity and max message size of the existing mq.
+func windowsMqAttrs(name string) (int, int, error) {
+	obj, err := shm.NewMemoryObject(windowsMqStateName(name), os.O_RDONLY, 0666)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed to open shm object")
+	}
+	defer obj.Close()
+	if int(obj.Size()) < windowsMqHdrSize {
+		return 0, 0, errors.New("shm object is too small")
+	}
+	region, err := mmf.NewMemoryRegion(obj, mmf.MEM_READ_ONLY, 0, windowsMqHdrSize)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed to create new shm region")
+	}
+	defer region.Close()
+	hdr := (*windowsMqHdr)(allocator.ByteSliceData(region.Data()))
+	return int(hdr.maxQueueSize), int(hdr.maxMsgSize), nil
+}
+
+// Send sends a message. It blocks if the queue is full.
+func (mq *WindowsMessageQueue) Send(data []byte) error {
+	return mq.SendTimeout(data, mq.timeout())
+}
+
+// SendTimeout sends a message. It blocks if the queue is full,
+// waiting for not longer, than the timeout.
+func (mq *WindowsMessageQueue) SendTimeout(data []byte, timeout time.Duration) error {
+	if len(data) > int(mq.hdr.maxMsgSize) {
+		return errors.New("the message is too big")
+	}
+	if !mq.free.WaitTimeout(timeout) {
+		return mqFullError
+	}
+	mq.locker.Lock()
+	slot := mq.slot((int(mq.hdr.head) + int(mq.hdr.count)) % int(mq.hdr.maxQueueSize))
+	binary.LittleEndian.PutUint32(slot, uint32(len(data)))
+	copy(slot[windowsMqSlotHdrSize:], data)
+	mq.hdr.count++
+	mq.locker.Unlock()
+	mq.used.Signal(1)
+	return nil
+}
+
+// Receive receives a message. It blocks if the queue is empty.
+func (mq *WindowsMessageQueue) Receive(data []byte) (int, error) {
+	return mq.ReceiveTimeout(data, mq.timeout())
+}
+
+// ReceiveTimeout receives a message. It blocks if the queue is empty,
+// waiting for not longer, than the timeout.
+// If the buffer is too small for the message, an error is returned, and the message stays in the queue.
+func (mq *WindowsMessageQueue) ReceiveTimeout(data []byte, timeout time.Duration) (int, error) {
+	if !mq.used.WaitTimeout(timeout) {
+		return 0, mqEmptyError
+	}
+	mq.locker.Lock()
+	slot := mq.slot(int(mq.hdr.head))
+	size := int(binary.LittleEndian.Uint32(slot))
+	if len(data) < size {
+		mq.locker.Unlock()
+		mq.used.Signal(1)
+		return 0, errors.Errorf("the buffer of %d bytes is too small for a %d bytes message", len(data), size)
+	}
+	copy(data, slot[windowsMqSlotHdrSize:windowsMqSlotHdrSize+size])
+	mq.hdr.head = (mq.hdr.head + 1) % mq.hdr.maxQueueSize
+	mq.hdr.count--
+	mq.locker.Unlock()
+	mq.free.Signal(1)
+	return size, nil
+}
+
+// Cap returns the capacity of the queue.
+func (mq *WindowsMessageQueue) Cap() int {
+	return int(mq.hdr.maxQueueSize)
+}
+
+// SetBlocking sets whether the send/receive operations on the queue block.
+// This applies to the current instance only.
+func (mq *WindowsMessageQueue) SetBlocking(block bool) error {
+	if block {
+		mq.flag &= ^O_NONBLOCK
+	} else {
+		mq.flag |= O_NONBLOCK
+	}
+	return nil
+}
+
+// Close closes the queue instance.
+func (mq *WindowsMessageQueue) Close() error {
+	common.UnregisterFromCleanup(mq)
+	var result error
+	if mq.used != nil {
+		if err := mq.used.Close(); err != nil {
+			result = errors.Wrap(err, "failed to close used slots semaphore")
+		}
+	}
+	if mq.free != nil {
+		if err := mq.free.Close(); err != nil {
+			result = errors.Wrap(err, "failed to close free slots semaphore")
+		}
+	}
+	if mq.locker != nil {
+		if err := mq.locker.Close(); err != nil {
+			result = errors.Wrap(err, "failed to close ipc locker")
+		}
+	}
+	if err := mq.region.Close(); err != nil {
+		result = errors.Wrap(err, "failed to close memory region")
+	}
+	return result
+}
+
+// Destroy closes the queue and removes it permanently.
+func (mq *WindowsMessageQueue) Destroy() error {
+	e1, e2 := mq.Close(), DestroyWindowsMessageQueue(mq.name)
+	if e1 != nil {
+		return errors.Wrap(e1, "failed to close mq")
+	}
+	if e2 != nil {
+		return errors.Wrap(e2, "failed to destroy mq")
+	}
+	return nil
+}
+
+func (mq *WindowsMessageQueue) timeout() time.Duration {
+	if mq.flag&O_NONBLOCK != 0 {
+		return 0
+	}
+	return -1
+}
+
+func (mq *WindowsMessageQueue) slot(idx int) []byte {
+	size := windowsMqSlotSize(int(mq.hdr.maxMsgSize))
+	raw := allocator.AdvancePointer(mq.slots, uintptr(idx*size))
+	return allocator.ByteSliceFromUnsafePointer(raw, size, size)
+}
+
+func windowsMqSlotSize(maxMsgSize int) int {
+	return windowsMqSlotHdrSize + (maxMsgSize+3)&^3
+}
+
+func windowsMqSize(maxQueueSize, maxMsgSize int) int {
+	return windowsMqHdrSize + maxQueueSize*windowsMqSlotSize(maxMsgSize)
+}
+
+func windowsMqStateName(mqName string) string {
+	return mqName + ".wst"
+}
+
+func windowsMqLockerName(mqName string) string {
+	return mqName + ".wm"
+}
+
+func windowsMqSemaName(mqName, typ string) string {
+	return mqName + ".ws" + typ
+}
@@ -0,0 +1,107 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func windowsMqCtor(name string, flag int, perm os.FileMode) (Messenger, error) {
+	return CreateWindowsMessageQueue(name, flag, perm, 1, DefaultWindowsMqMessageSize)
+}
+
+func windowsMqOpener(name string, flags int) (Messenger, error) {
+	return OpenWindowsMessageQueue(name, flags)
+}
+
+func windowsMqDtor(name string) error {
+	return DestroyWindowsMessageQueue(name)
+}
+
+func TestCreateWindowsMq(t *testing.T) {
+	testCreateMq(t, windowsMqCtor, windowsMqDtor)
+}
+
+func TestCreateWindowsMqExcl(t *testing.T) {
+	testCreateMqExcl(t, windowsMqCtor, windowsMqDtor)
+}
+
+func TestCreateWindowsMqInvalidPerm(t *testing.T) {
+	testCreateMqInvalidPerm(t, windowsMqCtor, windowsMqDtor)
+}
+
+func TestOpenWindowsMq(t *testing.T) {
+	testOpenMq(t, windowsMqCtor, windowsMqOpener, windowsMqDtor)
+}
+
+func TestWindowsMqSendIntSameProcess(t *testing.T) {
+	testMqSendIntSameProcess(t, windowsMqCtor, windowsMqOpener, windowsMqDtor)
+}
+
+func TestWindowsMqSendStructSameProcess(t *testing.T) {
+	testMqSendStructSameProcess(t, windowsMqCtor, windowsMqOpener, windowsMqDtor)
+}
+
+func TestWindowsMqSendMessageLessThenBuffer(t *testing.T) {
+	testMqSendMessageLessThenBuffer(t, windowsMqCtor, windowsMqOpener, windowsMqDtor)
+}
+
+func TestWindowsMqSendNonBlock(t *testing.T) {
+	testMqSendNonBlock(t, windowsMqCtor, windowsMqDtor)
+}
+
+func TestWindowsMqSendTimeout(t *testing.T) {
+	testMqSendTimeout(t, windowsMqCtor, windowsMqDtor)
+}
+
+func TestWindowsMqReceiveTimeout(t *testing.T) {
+	testMqReceiveTimeout(t, windowsMqCtor, windowsMqDtor)
+}
+
+func TestWindowsMqReceiveNonBlock(t *testing.T) {
+	testMqReceiveNonBlock(t, windowsMqCtor, windowsMqDtor)
+}
+
+func TestWindowsMqSendToAnotherProcess(t *testing.T) {
+	testMqSendToAnotherProcess(t, windowsMqCtor, windowsMqDtor, "windows")
+}
+
+func TestWindowsMqReceiveFromAnotherProcess(t *testing.T) {
+	testMqReceiveFromAnotherProcess(t, windowsMqCtor, windowsMqDtor, "windows")
+}
+
+func TestWindowsMqOrder(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyWindowsMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateWindowsMessageQueue(testMqName, os.O_EXCL, 0666, 3, 4)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	a.Error(mq.Send(make([]byte, 5)))
+	data := make([]byte, 4)
+	// wrap around the ring several times.
+	for i := 0; i < 10; i++ {
+		a.NoError(mq.SendTimeout([]byte{byte(i)}, 0))
+		a.NoError(mq.SendTimeout([]byte{byte(i), byte(i)}, 0))
+		l, err := mq.ReceiveTimeout(data, 0)
+		if a.NoError(err) {
+			a.Equal([]byte{byte(i)}, data[:l])
+		}
+		// the message is kept, if the buffer is too small.
+		_, err = mq.ReceiveTimeout(data[:1], 0)
+		a.Error(err)
+		l, err = mq.ReceiveTimeout(data, time.Millisecond*10)
+		if a.NoError(err) {
+			a.Equal([]byte{byte(i), byte(i)}, data[:l])
+		}
+	}
+	_, err = mq.ReceiveTimeout(data, 0)
+	a.True(IsTemporary(err))
+}
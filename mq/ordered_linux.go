@@ -0,0 +1,57 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// SendOrdered encodes the object with encoding/binary in the given byte order and sends it with the given priority.
+// Unlike Send, which copies the memory of the object as is, it allows the receiver to run on a machine
+// with a different native byte order.
+//	object - a fixed-size value, a pointer to it, or a slice of fixed-size values, as supported by encoding/binary.
+//		its encoded size must not exceed the maximum message size of the queue.
+func (mq *LinuxMessageQueue) SendOrdered(object interface{}, prio int, order binary.ByteOrder) error {
+	size := binary.Size(object)
+	if size < 0 {
+		return errors.Errorf("%T is not a fixed-size type", object)
+	}
+	buff := bytes.NewBuffer(make([]byte, 0, size))
+	if err := binary.Write(buff, order, object); err != nil {
+		return errors.Wrap(err, "failed to encode the object")
+	}
+	return mq.SendPriority(buff.Bytes(), prio)
+}
+
+// ReceiveOrdered receives an object sent with SendOrdered and decodes it with encoding/binary.
+// The size of the message must be equal to the encoded size of the object.
+//	object - a pointer to a fixed-size value, or a slice of fixed-size values to decode into.
+//	order - the byte order, which was used by the sender.
+//	prio - if not nil, the priority of the message is stored here.
+func (mq *LinuxMessageQueue) ReceiveOrdered(object interface{}, prio *int, order binary.ByteOrder) error {
+	if err := checkReceiveObject(object); err != nil {
+		return err
+	}
+	size := binary.Size(object)
+	if size < 0 {
+		return errors.Errorf("%T is not a fixed-size type", object)
+	}
+	data := make([]byte, size)
+	n, msgPrio, err := mq.ReceivePriority(data)
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return errors.Errorf("the message of %d bytes does not match the encoded size of %T (%d bytes)", n, object, size)
+	}
+	if err = binary.Read(bytes.NewReader(data), order, object); err != nil {
+		return errors.Wrap(err, "failed to decode the object")
+	}
+	if prio != nil {
+		*prio = msgPrio
+	}
+	return nil
+}
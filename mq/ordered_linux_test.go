@@ -0,0 +1,61 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type orderedTestStruct struct {
+	ID     uint32
+	Value  int64
+	Ratio  float64
+	Flags  [3]uint16
+	Active bool
+}
+
+func TestLinuxMqOrdered(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 2, 64)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	sent := orderedTestStruct{ID: 0x01020304, Value: -42, Ratio: 0.5, Flags: [3]uint16{1, 0x0a0b, 3}, Active: true}
+	if !a.NoError(mq.SendOrdered(&sent, 3, binary.BigEndian)) {
+		return
+	}
+	// check the wire format.
+	data := make([]byte, 64)
+	n, err := mq.Receive(data)
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(binary.Size(sent), n)
+	a.Equal([]byte{1, 2, 3, 4}, data[:4])
+	if !a.NoError(mq.SendOrdered(sent, 3, binary.BigEndian)) {
+		return
+	}
+	var received orderedTestStruct
+	var prio int
+	if !a.NoError(mq.ReceiveOrdered(&received, &prio, binary.BigEndian)) {
+		return
+	}
+	a.Equal(sent, received)
+	a.Equal(3, prio)
+	// a message of another size is rejected.
+	if !a.NoError(mq.Send([]byte{1, 2, 3})) {
+		return
+	}
+	a.Error(mq.ReceiveOrdered(&received, nil, binary.BigEndian))
+	// types with references are not supported.
+	a.Error(mq.SendOrdered("string", 0, binary.BigEndian))
+	a.Error(mq.ReceiveOrdered(received, nil, binary.BigEndian))
+}
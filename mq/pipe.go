@@ -0,0 +1,134 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// PipeA is the first end of a pipe. It sends messages to PipeB.
+	PipeA = 0
+	// PipeB is the second end of a pipe. It sends messages to PipeA.
+	PipeB = 1
+)
+
+// this is to ensure, that PipeEnd satisfies the minimal queue interface.
+var (
+	_ Messenger = (*PipeEnd)(nil)
+)
+
+// PipeEnd is one end of a bidirectional pipe made of two message queues.
+// Messages sent via one end are received from another one.
+type PipeEnd struct {
+	in  Messenger
+	out Messenger
+}
+
+// Pipe creates a pair of connected queues using the default mq implementation.
+// It creates two queues: baseName+".ab", which is written by 'a' and read by 'b',
+// and baseName+".ba", which is written by 'b' and read by 'a'.
+// The queues must not exist. Another process can open any end with OpenPipeEnd.
+// The caller owns the queues: cleanup closes both ends and destroys the queues.
+//	baseName - a base name for the queues.
+//	perm - permissions for the new queues.
+func Pipe(baseName string, perm os.FileMode) (a, b *PipeEnd, cleanup func() error, err error) {
+	ab, err := New(pipeQueueName(baseName, PipeA), os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to create a->b queue")
+	}
+	ba, err := New(pipeQueueName(baseName, PipeB), os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		ab.Close()
+		Destroy(pipeQueueName(baseName, PipeA))
+		return nil, nil, nil, errors.Wrap(err, "failed to create b->a queue")
+	}
+	a, err = OpenPipeEnd(baseName, PipeA)
+	if err == nil {
+		if b, err = OpenPipeEnd(baseName, PipeB); err != nil {
+			a.Close()
+		}
+	}
+	ab.Close()
+	ba.Close()
+	if err != nil {
+		DestroyPipe(baseName)
+		return nil, nil, nil, err
+	}
+	cleanup = func() error {
+		e1, e2 := a.Close(), b.Close()
+		if err := DestroyPipe(baseName); err != nil {
+			return err
+		}
+		if e1 != nil {
+			return errors.Wrap(e1, "failed to close 'a' end")
+		}
+		if e2 != nil {
+			return errors.Wrap(e2, "failed to close 'b' end")
+		}
+		return nil
+	}
+	return a, b, cleanup, nil
+}
+
+// OpenPipeEnd opens an end of a pipe created by Pipe.
+//	baseName - a base name of the pipe.
+//	end - PipeA or PipeB.
+func OpenPipeEnd(baseName string, end int) (*PipeEnd, error) {
+	if end != PipeA && end != PipeB {
+		return nil, errors.Errorf("invalid pipe end %d", end)
+	}
+	out, err := Open(pipeQueueName(baseName, end), 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open outgoing queue")
+	}
+	in, err := Open(pipeQueueName(baseName, 1-end), 0)
+	if err != nil {
+		out.Close()
+		return nil, errors.Wrap(err, "failed to open incoming queue")
+	}
+	return &PipeEnd{in: in, out: out}, nil
+}
+
+// DestroyPipe permanently removes both queues of a pipe.
+func DestroyPipe(baseName string) error {
+	e1, e2 := Destroy(pipeQueueName(baseName, PipeA)), Destroy(pipeQueueName(baseName, PipeB))
+	if e1 != nil {
+		return errors.Wrap(e1, "failed to destroy a->b queue")
+	}
+	if e2 != nil {
+		return errors.Wrap(e2, "failed to destroy b->a queue")
+	}
+	return nil
+}
+
+// Send sends the data to the other end of the pipe.
+func (p *PipeEnd) Send(data []byte) error {
+	return p.out.Send(data)
+}
+
+// Receive receives the data sent by the other end of the pipe.
+func (p *PipeEnd) Receive(data []byte) (int, error) {
+	return p.in.Receive(data)
+}
+
+// Close closes both queues of this end.
+func (p *PipeEnd) Close() error {
+	e1, e2 := p.out.Close(), p.in.Close()
+	if e1 != nil {
+		return errors.Wrap(e1, "failed to close outgoing queue")
+	}
+	if e2 != nil {
+		return errors.Wrap(e2, "failed to close incoming queue")
+	}
+	return nil
+}
+
+func pipeQueueName(baseName string, writer int) string {
+	if writer == PipeA {
+		return baseName + ".ab"
+	}
+	return baseName + ".ba"
+}
@@ -0,0 +1,77 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"testing"
+
+	testutil "github.com/nxgtw/go-ipc/internal/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipeSameProcess(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyPipe(testMqName)) {
+		return
+	}
+	pa, pb, cleanup, err := Pipe(testMqName, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(cleanup())
+	}()
+	_, _, _, err = Pipe(testMqName, 0666)
+	a.Error(err)
+	data := []byte{1, 2, 3, 4}
+	received := make([]byte, 4)
+	if a.NoError(pa.Send(data)) {
+		l, err := pb.Receive(received)
+		a.NoError(err)
+		a.Equal(data, received[:l])
+	}
+	data[0] = 5
+	if a.NoError(pb.Send(data)) {
+		l, err := pa.Receive(received)
+		a.NoError(err)
+		a.Equal(data, received[:l])
+	}
+	_, err = OpenPipeEnd(testMqName, 2)
+	a.Error(err)
+}
+
+func TestPipeAnotherProcess(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyPipe(testMqName)) {
+		return
+	}
+	pa, pb, cleanup, err := Pipe(testMqName, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(cleanup())
+	}()
+	a.NoError(pb.Close())
+	data := make([]byte, 1024)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	resultChan := testutil.RunTestAppAsync(argsForPipeEchoCommand(testMqName), nil)
+	if !a.NoError(pa.Send(data)) {
+		return
+	}
+	received := make([]byte, 1024)
+	l, err := pa.Receive(received)
+	a.NoError(err)
+	a.Equal(data, received[:l])
+	result := <-resultChan
+	if !a.NoError(result.Err) {
+		t.Logf("program output is %q", result.Output)
+	}
+}
+
+func argsForPipeEchoCommand(name string) []string {
+	return append(mqProgArgs, "-object="+name, "pipeecho")
+}
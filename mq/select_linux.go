@@ -0,0 +1,80 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"time"
+
+	"github.com/nxgtw/go-ipc/internal/common"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// Select waits until any of the queues has a message and receives it.
+// It uses a single epoll set built from the queues' descriptors.
+// Returns the index of the queue, the message, and its priority.
+// If several queues are ready, the one with the lowest index is chosen.
+// If a message is taken by another receiver between the notification and the receive,
+// Select continues waiting.
+//	timeout - max time to wait. negative value makes the timeout infinite.
+func Select(queues []*LinuxMessageQueue, timeout time.Duration) (int, []byte, int, error) {
+	if len(queues) == 0 {
+		return -1, nil, 0, errors.New("no queues to select from")
+	}
+	epfd, err := unix.EpollCreate1(unix.EPOLL_CLOEXEC)
+	if err != nil {
+		return -1, nil, 0, errors.Wrap(err, "epoll_create failed")
+	}
+	defer unix.Close(epfd)
+	for i, mq := range queues {
+		ev := unix.EpollEvent{Events: unix.EPOLLIN, Fd: int32(i)}
+		if err = unix.EpollCtl(epfd, unix.EPOLL_CTL_ADD, mq.ID(), &ev); err != nil {
+			return -1, nil, 0, errors.Wrap(err, "epoll_ctl failed")
+		}
+	}
+	events := make([]unix.EpollEvent, len(queues))
+	start := time.Now()
+	for {
+		msec := -1
+		if timeout >= 0 {
+			left := timeout - time.Since(start)
+			if left < 0 {
+				left = 0
+			}
+			msec = int((left + time.Millisecond - 1) / time.Millisecond)
+		}
+		n, err := unix.EpollWait(epfd, events, msec)
+		if err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			return -1, nil, 0, errors.Wrap(err, "epoll_wait failed")
+		}
+		if n > 0 {
+			ready := -1
+			for _, ev := range events[:n] {
+				if idx := int(ev.Fd); ready < 0 || idx < ready {
+					ready = idx
+				}
+			}
+			idx, data, prio, err := selectReceive(queues, ready)
+			if err == nil || !IsTemporary(errors.Cause(err)) {
+				return idx, data, prio, err
+			}
+		}
+		if timeout >= 0 && time.Since(start) >= timeout {
+			return -1, nil, 0, common.NewTimeoutError("SELECT")
+		}
+	}
+}
+
+func selectReceive(queues []*LinuxMessageQueue, idx int) (int, []byte, int, error) {
+	mq := queues[idx]
+	data := make([]byte, len(mq.inputBuff))
+	n, prio, err := mq.ReceiveTimeoutPriority(data, 0)
+	if err != nil {
+		return -1, nil, 0, err
+	}
+	return idx, data[:n], prio, nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinuxMqSelect(t *testing.T) {
+	a := assert.New(t)
+	var queues []*LinuxMessageQueue
+	for i := 0; i < 3; i++ {
+		name := testMqName + strconv.Itoa(i)
+		if !a.NoError(DestroyLinuxMessageQueue(name)) {
+			return
+		}
+		mq, err := CreateLinuxMessageQueue(name, os.O_EXCL|os.O_RDWR, 0666, 5, 16)
+		if !a.NoError(err) {
+			return
+		}
+		defer mq.Destroy()
+		queues = append(queues, mq)
+	}
+	idx, _, _, err := Select(queues, time.Millisecond*50)
+	a.Equal(-1, idx)
+	a.True(IsTemporary(err))
+	go func() {
+		<-time.After(time.Millisecond * 50)
+		a.NoError(queues[1].SendPriority([]byte{1, 2, 3}, 2))
+	}()
+	idx, data, prio, err := Select(queues, time.Second*2)
+	if a.NoError(err) {
+		a.Equal(1, idx)
+		a.Equal([]byte{1, 2, 3}, data)
+		a.Equal(2, prio)
+	}
+	for _, mq := range queues {
+		attrs, err := mq.getAttrs()
+		if a.NoError(err) {
+			a.Equal(0, attrs.Curmsgs)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+type linuxMqReader struct {
+	mq   *LinuxMessageQueue
+	buff []byte
+	// pending is the unread part of the last received message.
+	pending []byte
+}
+
+// NewMqReader returns a reader, which treats the queue as a stream of bytes.
+// It receives one message at a time and hands out its bytes across successive Read calls.
+// Read blocks, if there are no pending bytes and the queue is empty,
+// unless the queue is in non-blocking mode.
+// Message boundaries are not preserved. Zero-length messages are skipped.
+func NewMqReader(mq *LinuxMessageQueue) io.Reader {
+	return &linuxMqReader{mq: mq}
+}
+
+func (r *linuxMqReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for len(r.pending) == 0 {
+		if r.buff == nil {
+			attrs, err := r.mq.getAttrs()
+			if err != nil {
+				return 0, errors.Wrap(err, "failed to get mq attrs")
+			}
+			r.buff = make([]byte, attrs.Msgsize)
+		}
+		n, err := r.mq.Receive(r.buff)
+		if err != nil {
+			return 0, err
+		}
+		r.pending = r.buff[:n]
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+type linuxMqWriter struct {
+	mq   *LinuxMessageQueue
+	prio int
+}
+
+// NewMqWriter returns a writer, which treats the queue as a stream of bytes.
+// Each Write call is split into chunks no larger than the max message size of the queue,
+// and each chunk is sent as a message with the given priority.
+// If the queue is in non-blocking mode, Write returns the number of bytes sent before the queue became full.
+func NewMqWriter(mq *LinuxMessageQueue, prio int) io.Writer {
+	return &linuxMqWriter{mq: mq, prio: prio}
+}
+
+func (w *linuxMqWriter) Write(p []byte) (int, error) {
+	attrs, err := w.mq.getAttrs()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get mq attrs")
+	}
+	chunkSize := attrs.Msgsize - orderStampSize
+	var written int
+	for written < len(p) {
+		chunk := p[written:]
+		if len(chunk) > chunkSize {
+			chunk = chunk[:chunkSize]
+		}
+		if err = w.mq.SendPriority(chunk, w.prio); err != nil {
+			return written, err
+		}
+		written += len(chunk)
+	}
+	return written, nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinuxMqStreamSmallReads(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 8, 16)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	data := make([]byte, 40)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	n, err := NewMqWriter(mq, 0).Write(data)
+	a.NoError(err)
+	a.Equal(len(data), n)
+	attrs, err := mq.getAttrs()
+	if a.NoError(err) {
+		a.Equal(3, attrs.Curmsgs)
+	}
+	r := NewMqReader(mq)
+	var result []byte
+	buff := make([]byte, 7)
+	for len(result) < len(data) {
+		n, err := r.Read(buff)
+		if !a.NoError(err) {
+			return
+		}
+		a.True(n <= 7)
+		result = append(result, buff[:n]...)
+	}
+	a.Equal(data, result)
+}
+
+func TestLinuxMqStreamGzip(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 4, 128)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	data := make([]byte, 16384)
+	for i := range data {
+		data[i] = byte(rand.Intn(16))
+	}
+	go func() {
+		gz := gzip.NewWriter(NewMqWriter(mq, 0))
+		_, err := gz.Write(data)
+		a.NoError(err)
+		a.NoError(gz.Close())
+	}()
+	gz, err := gzip.NewReader(NewMqReader(mq))
+	if !a.NoError(err) {
+		return
+	}
+	gz.Multistream(false)
+	result, err := ioutil.ReadAll(gz)
+	a.NoError(err)
+	a.Equal(data, result)
+}
@@ -0,0 +1,310 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"reflect"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"bitbucket.org/avd/go-ipc/mmf"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	typedQueueHdrSize     = int(unsafe.Sizeof(typedQueueHdr{}))
+	typedQueueCellHdrSize = int(unsafe.Sizeof(typedQueueCellHdr{}))
+)
+
+var (
+	// ErrNotPointer is returned, if an object to receive data into is not a non-nil pointer or a slice.
+	ErrNotPointer = errors.New("the object must be a non-nil pointer or a slice")
+	// ErrPointer is returned, if an object to send is a pointer. Objects are sent by value.
+	ErrPointer = errors.New("the object must be a value, not a pointer")
+)
+
+// Codec converts queue elements into their binary representation and back.
+type Codec interface {
+	// Encode writes object's representation into data and returns the number of bytes used.
+	Encode(data []byte, object interface{}) (int, error)
+	// Decode reads object from data.
+	Decode(data []byte, object interface{}) error
+}
+
+// RawCodec is a Codec, which copies objects byte by byte.
+// Objects must not contain any references, see allocator.Alloc for details.
+// Decode requires a pointer or a slice.
+type RawCodec struct{}
+
+// Encode copies object's data into data.
+func (RawCodec) Encode(data []byte, object interface{}) (int, error) {
+	if err := allocator.Alloc(data, object); err != nil {
+		return 0, err
+	}
+	value := reflect.ValueOf(object)
+	for value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	return allocator.ObjectSize(value), nil
+}
+
+// Decode copies data into the object pointed by object.
+func (RawCodec) Decode(data []byte, object interface{}) error {
+	if err := checkReceiveObject(object); err != nil {
+		return err
+	}
+	objData, err := allocator.ObjectData(object)
+	if err != nil {
+		return err
+	}
+	if len(objData) < len(data) {
+		return errors.Errorf("the object of %d bytes is too small for %d bytes of data", len(objData), len(data))
+	}
+	copy(objData, data)
+	allocator.UseValue(object)
+	return nil
+}
+
+type typedQueueHdr struct {
+	capacity int64
+	elemSize int64
+	enqPos   uint64
+	deqPos   uint64
+}
+
+type typedQueueCellHdr struct {
+	seq  uint64
+	size int64
+}
+
+// TypedQueue is a bounded lock-free multi-producer multi-consumer queue placed in a memory region.
+// It stores elements of a fixed maximum size, converting them with a Codec.
+// As it doesn't use any locks, it can be accessed by several processes simultaneously.
+// Send and receive operations never block. If the queue is full or empty,
+// a temporary error is returned, and IsTemporary(err) will return true.
+// The algorithm is based on Dmitry Vyukov's bounded mpmc queue.
+// The package supports Go versions without type parameters, so elements are passed
+// as interface{} values: Enqueue takes a value, and Dequeue decodes into a pointer,
+// returning an error instead of a boolean flag, if the queue is empty.
+// The queue becomes invalid, if the region is closed or remapped. Use OpenTypedQueue to open it again.
+type TypedQueue struct {
+	region   *mmf.MemoryRegion
+	hdr      *typedQueueHdr
+	cells    unsafe.Pointer
+	cellSize int
+	codec    Codec
+}
+
+// TypedQueueSize returns the size of a memory region needed to store a queue
+// with given capacity and element size.
+func TypedQueueSize(capacity, elemSize int) int {
+	return typedQueueHdrSize + capacity*typedQueueCellSize(elemSize)
+}
+
+// NewTypedQueue initializes a new queue in the given region.
+// All the data in the region is overwritten.
+//	region - memory region. it must be at least TypedQueueSize(capacity, elemSize) bytes long.
+//	capacity - maximum number of elements in the queue.
+//	elemSize - maximum size of an encoded element.
+//	codec - element codec. if nil, RawCodec is used.
+func NewTypedQueue(region *mmf.MemoryRegion, capacity, elemSize int, codec Codec) (*TypedQueue, error) {
+	result, err := newTypedQueue(region.Data(), capacity, elemSize, codec)
+	if err != nil {
+		return nil, err
+	}
+	// keep the region alive, as the queue refers to its memory.
+	result.region = region
+	return result, nil
+}
+
+// OpenTypedQueue opens a queue, which was previously initialized in the given region with NewTypedQueue.
+//	codec - element codec. if nil, RawCodec is used.
+func OpenTypedQueue(region *mmf.MemoryRegion, codec Codec) (*TypedQueue, error) {
+	result, err := openTypedQueue(region.Data(), codec)
+	if err != nil {
+		return nil, err
+	}
+	result.region = region
+	return result, nil
+}
+
+func newTypedQueue(data []byte, capacity, elemSize int, codec Codec) (*TypedQueue, error) {
+	if capacity <= 0 || elemSize <= 0 {
+		return nil, errors.New("queue capacity and element size must be positive")
+	}
+	if len(data) < TypedQueueSize(capacity, elemSize) {
+		return nil, errors.Errorf("the region is too small. need %d bytes", TypedQueueSize(capacity, elemSize))
+	}
+	result := typedQueueAt(data, codec)
+	result.hdr.capacity = int64(capacity)
+	result.hdr.elemSize = int64(elemSize)
+	result.cellSize = typedQueueCellSize(elemSize)
+	for i := 0; i < capacity; i++ {
+		cell := result.cellAt(uint64(i))
+		cell.size = 0
+		atomic.StoreUint64(&cell.seq, uint64(i))
+	}
+	atomic.StoreUint64(&result.hdr.deqPos, 0)
+	atomic.StoreUint64(&result.hdr.enqPos, 0)
+	return result, nil
+}
+
+func openTypedQueue(data []byte, codec Codec) (*TypedQueue, error) {
+	if len(data) < typedQueueHdrSize {
+		return nil, errors.New("the region is too small")
+	}
+	result := typedQueueAt(data, codec)
+	capacity, elemSize := int(result.hdr.capacity), int(result.hdr.elemSize)
+	if capacity <= 0 || elemSize <= 0 || len(data) < TypedQueueSize(capacity, elemSize) {
+		return nil, errors.New("the region does not contain a valid queue")
+	}
+	result.cellSize = typedQueueCellSize(elemSize)
+	return result, nil
+}
+
+func typedQueueAt(data []byte, codec Codec) *TypedQueue {
+	if codec == nil {
+		codec = RawCodec{}
+	}
+	raw := allocator.ByteSliceData(data)
+	return &TypedQueue{
+		hdr:   (*typedQueueHdr)(raw),
+		cells: allocator.AdvancePointer(raw, uintptr(typedQueueHdrSize)),
+		codec: codec,
+	}
+}
+
+// Enqueue encodes the object and puts it into the queue.
+// It returns a temporary error, if the queue is full, and ErrPointer, if the object is a pointer.
+func (q *TypedQueue) Enqueue(object interface{}) error {
+	if err := checkSendObject(object); err != nil {
+		return err
+	}
+	pos := atomic.LoadUint64(&q.hdr.enqPos)
+	var cell *typedQueueCellHdr
+	for {
+		cell = q.cellAt(pos)
+		seq := atomic.LoadUint64(&cell.seq)
+		diff := int64(seq - pos)
+		if diff == 0 {
+			if atomic.CompareAndSwapUint64(&q.hdr.enqPos, pos, pos+1) {
+				break
+			}
+		} else if diff < 0 {
+			return mqFullError
+		} else {
+			pos = atomic.LoadUint64(&q.hdr.enqPos)
+		}
+	}
+	size, err := q.codec.Encode(q.cellData(cell), object)
+	if err != nil {
+		size = -1
+	}
+	cell.size = int64(size)
+	atomic.StoreUint64(&cell.seq, pos+1)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the object")
+	}
+	return nil
+}
+
+// Dequeue takes the oldest element from the queue and decodes it into the object.
+// It returns a temporary error, if the queue is empty,
+// and ErrNotPointer, if the object is not a non-nil pointer or a slice.
+func (q *TypedQueue) Dequeue(object interface{}) error {
+	if err := checkReceiveObject(object); err != nil {
+		return err
+	}
+	pos := atomic.LoadUint64(&q.hdr.deqPos)
+	var cell *typedQueueCellHdr
+	for {
+		cell = q.cellAt(pos)
+		seq := atomic.LoadUint64(&cell.seq)
+		diff := int64(seq - (pos + 1))
+		if diff == 0 {
+			if atomic.CompareAndSwapUint64(&q.hdr.deqPos, pos, pos+1) {
+				break
+			}
+		} else if diff < 0 {
+			return mqEmptyError
+		} else {
+			pos = atomic.LoadUint64(&q.hdr.deqPos)
+		}
+	}
+	var err error
+	if size := int(cell.size); size >= 0 {
+		err = q.codec.Decode(q.cellData(cell)[:size], object)
+	} else {
+		err = errors.New("the element was not encoded")
+	}
+	atomic.StoreUint64(&cell.seq, pos+uint64(q.hdr.capacity))
+	if err != nil {
+		return errors.Wrap(err, "failed to decode the object")
+	}
+	return nil
+}
+
+// Len returns approximate number of elements in the queue.
+func (q *TypedQueue) Len() int {
+	deq := atomic.LoadUint64(&q.hdr.deqPos)
+	enq := atomic.LoadUint64(&q.hdr.enqPos)
+	if enq < deq {
+		return 0
+	}
+	return int(enq - deq)
+}
+
+// Cap returns the capacity of the queue.
+func (q *TypedQueue) Cap() int {
+	return int(q.hdr.capacity)
+}
+
+// ElemSize returns maximum size of an encoded element.
+func (q *TypedQueue) ElemSize() int {
+	return int(q.hdr.elemSize)
+}
+
+func (q *TypedQueue) cellAt(pos uint64) *typedQueueCellHdr {
+	idx := pos % uint64(q.hdr.capacity)
+	return (*typedQueueCellHdr)(allocator.AdvancePointer(q.cells, uintptr(idx)*uintptr(q.cellSize)))
+}
+
+func (q *TypedQueue) cellData(cell *typedQueueCellHdr) []byte {
+	size := int(q.hdr.elemSize)
+	raw := allocator.AdvancePointer(unsafe.Pointer(cell), uintptr(typedQueueCellHdrSize))
+	return allocator.ByteSliceFromUnsafePointer(raw, size, size)
+}
+
+// checkSendObject ensures, that the object is not a pointer.
+func checkSendObject(object interface{}) error {
+	if object != nil && reflect.TypeOf(object).Kind() == reflect.Ptr {
+		return ErrPointer
+	}
+	return nil
+}
+
+// checkReceiveObject ensures, that the object is a non-nil pointer or a slice.
+func checkReceiveObject(object interface{}) error {
+	if object == nil {
+		return ErrNotPointer
+	}
+	value := reflect.ValueOf(object)
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return ErrNotPointer
+		}
+	case reflect.Slice:
+	default:
+		return ErrNotPointer
+	}
+	return nil
+}
+
+// typedQueueCellSize returns the size of a cell aligned to 8 bytes,
+// so that sequence numbers can be accessed atomically.
+func typedQueueCellSize(elemSize int) int {
+	return (typedQueueCellHdrSize + elemSize + 7) &^ 7
+}
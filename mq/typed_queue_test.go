@@ -0,0 +1,174 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/nxgtw/go-ipc/internal/helper"
+	testutil "github.com/nxgtw/go-ipc/internal/test"
+	"bitbucket.org/avd/go-ipc/mmf"
+	"bitbucket.org/avd/go-ipc/shm"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testTypedQueueShmName = "go-ipc.typed-queue"
+)
+
+type typedQueueTestStruct struct {
+	Idx  int64
+	Data [4]int32
+}
+
+func newTypedQueueTestStruct(i int) typedQueueTestStruct {
+	return typedQueueTestStruct{Idx: int64(i), Data: [4]int32{int32(i), int32(i + 1), int32(i + 2), int32(i + 3)}}
+}
+
+func createTypedQueueRegion(capacity, elemSize int) (*mmf.MemoryRegion, error) {
+	if err := shm.DestroyMemoryObject(testTypedQueueShmName); err != nil {
+		return nil, err
+	}
+	region, _, err := helper.CreateWritableRegion(testTypedQueueShmName, os.O_CREATE|os.O_EXCL, 0666, TypedQueueSize(capacity, elemSize))
+	return region, err
+}
+
+func TestTypedQueueFullEmpty(t *testing.T) {
+	a := assert.New(t)
+	var elem typedQueueTestStruct
+	region, err := createTypedQueueRegion(4, 24)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(region.Close())
+		a.NoError(shm.DestroyMemoryObject(testTypedQueueShmName))
+	}()
+	q, err := NewTypedQueue(region, 4, 24, nil)
+	if !a.NoError(err) {
+		return
+	}
+	err = q.Dequeue(&elem)
+	a.Error(err)
+	a.True(IsTemporary(err))
+	for i := 0; i < 4; i++ {
+		a.NoError(q.Enqueue(newTypedQueueTestStruct(i)))
+	}
+	a.Equal(4, q.Len())
+	err = q.Enqueue(newTypedQueueTestStruct(4))
+	a.Error(err)
+	a.True(IsTemporary(err))
+	for i := 0; i < 4; i++ {
+		if a.NoError(q.Dequeue(&elem)) {
+			a.Equal(newTypedQueueTestStruct(i), elem)
+		}
+	}
+	a.Equal(0, q.Len())
+	a.Error(q.Enqueue("string"))
+	a.Error(q.Dequeue(elem))
+}
+
+func TestTypedQueueObjectChecks(t *testing.T) {
+	a := assert.New(t)
+	region, err := createTypedQueueRegion(4, 24)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(region.Close())
+		a.NoError(shm.DestroyMemoryObject(testTypedQueueShmName))
+	}()
+	q, err := NewTypedQueue(region, 4, 24, nil)
+	if !a.NoError(err) {
+		return
+	}
+	elem := newTypedQueueTestStruct(1)
+	a.Equal(ErrPointer, q.Enqueue(&elem))
+	a.Equal(0, q.Len())
+	a.NoError(q.Enqueue(elem))
+	var nilPtr *typedQueueTestStruct
+	a.Equal(ErrNotPointer, q.Dequeue(elem))
+	a.Equal(ErrNotPointer, q.Dequeue(nilPtr))
+	a.Equal(ErrNotPointer, q.Dequeue(nil))
+	a.Equal(1, q.Len())
+	var received typedQueueTestStruct
+	if a.NoError(q.Dequeue(&received)) {
+		a.Equal(elem, received)
+	}
+	a.NoError(q.Enqueue([]int32{1, 2, 3}))
+	receivedSlice := make([]int32, 3)
+	if a.NoError(q.Dequeue(receivedSlice)) {
+		a.Equal([]int32{1, 2, 3}, receivedSlice)
+	}
+}
+
+func TestTypedQueueOpen(t *testing.T) {
+	a := assert.New(t)
+	region, err := createTypedQueueRegion(8, 24)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(region.Close())
+		a.NoError(shm.DestroyMemoryObject(testTypedQueueShmName))
+	}()
+	q, err := NewTypedQueue(region, 8, 24, nil)
+	if !a.NoError(err) {
+		return
+	}
+	a.NoError(q.Enqueue(newTypedQueueTestStruct(1)))
+	q2, err := OpenTypedQueue(region, nil)
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(8, q2.Cap())
+	a.Equal(24, q2.ElemSize())
+	var elem typedQueueTestStruct
+	if a.NoError(q2.Dequeue(&elem)) {
+		a.Equal(newTypedQueueTestStruct(1), elem)
+	}
+}
+
+func TestTypedQueueToAnotherProcess(t *testing.T) {
+	const count = 1000
+	a := assert.New(t)
+	region, err := createTypedQueueRegion(16, 24)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(region.Close())
+		a.NoError(shm.DestroyMemoryObject(testTypedQueueShmName))
+	}()
+	q, err := NewTypedQueue(region, 16, 24, nil)
+	if !a.NoError(err) {
+		return
+	}
+	args := argsForTypedRecvCommand(testTypedQueueShmName, count)
+	resultChan := testutil.RunTestAppAsync(args, nil)
+	for i := 0; i < count; {
+		select {
+		case result := <-resultChan:
+			t.Errorf("the app has exited early. the output is %q", result.Output)
+			return
+		default:
+		}
+		err := q.Enqueue(newTypedQueueTestStruct(i))
+		if err == nil {
+			i++
+		} else if !a.True(IsTemporary(err)) {
+			return
+		}
+	}
+	result := <-resultChan
+	if !a.NoError(result.Err) {
+		t.Logf("program output is %q", result.Output)
+	}
+}
+
+func argsForTypedRecvCommand(shmName string, n int) []string {
+	return append(mqProgArgs, "-object="+shmName, "typedrecv", shmName, strconv.Itoa(n))
+}
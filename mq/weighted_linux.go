@@ -0,0 +1,136 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"github.com/pkg/errors"
+)
+
+// WeightedQueue receives messages from a LinuxMessageQueue with weighted fair queuing.
+// The queue itself always delivers the message with the highest priority first,
+// so a steady flow of high-priority messages starves the lower ones. WeightedQueue moves pending messages
+// into per-priority bands in process memory and serves the bands in rounds: within a round
+// a band with weight w is served up to w times, higher priorities first, and a new round starts,
+// when all the bands with messages have used their share. For example, with weights {2: 3, 1: 1}
+// three messages with priority 2 are received per one message with priority 1, while both are available.
+// Messages moved into the bands are removed from the queue, and they are lost, if the WeightedQueue is dropped.
+// WeightedQueue is not safe for concurrent use.
+type WeightedQueue struct {
+	mq      *LinuxMessageQueue
+	weights map[int]int
+	// bands keep received, but not yet served messages for each priority.
+	bands   map[int][][]byte
+	pending int
+	// credits is the number of messages each band can be served in the current round.
+	credits map[int]int
+}
+
+// NewWeightedQueue returns a new weighted queue, which receives messages from mq.
+//	weights - a map of priority to its weight, which must be positive.
+//		priorities, which are not in the map, have weight 1.
+func NewWeightedQueue(mq *LinuxMessageQueue, weights map[int]int) (*WeightedQueue, error) {
+	copied := make(map[int]int, len(weights))
+	for prio, weight := range weights {
+		if weight <= 0 {
+			return nil, errors.Errorf("invalid weight %d for priority %d: it must be positive", weight, prio)
+		}
+		copied[prio] = weight
+	}
+	return &WeightedQueue{
+		mq:      mq,
+		weights: copied,
+		bands:   make(map[int][][]byte),
+		credits: make(map[int]int),
+	}, nil
+}
+
+// Receive receives a message into data according to the weights.
+// It returns the size of the message and its priority.
+// It blocks, if there are no pending messages, and the queue is empty, unless the queue is non-blocking.
+// If data is too small for the chosen message, an error is returned, and the message stays pending.
+func (wq *WeightedQueue) Receive(data []byte) (int, int, error) {
+	if err := wq.fill(); err != nil {
+		return 0, 0, err
+	}
+	prio := wq.next()
+	msg := wq.bands[prio][0]
+	if len(data) < len(msg) {
+		return 0, 0, errors.Errorf("the buffer of %d bytes is too small for the message of %d bytes", len(data), len(msg))
+	}
+	copy(data, msg)
+	wq.credits[prio]--
+	wq.bands[prio][0] = nil
+	if wq.bands[prio] = wq.bands[prio][1:]; len(wq.bands[prio]) == 0 {
+		delete(wq.bands, prio)
+	}
+	wq.pending--
+	return len(msg), prio, nil
+}
+
+// Pending returns the number of messages, which were taken from the queue, but have not been received yet.
+func (wq *WeightedQueue) Pending() int {
+	return wq.pending
+}
+
+// fill moves messages from the queue into the bands. It waits for a message,
+// if there are no pending ones, and then takes the available messages without blocking,
+// until the number of pending messages reaches the capacity of the queue.
+func (wq *WeightedQueue) fill() error {
+	if wq.pending == 0 {
+		if err := wq.receiveOne(true); err != nil {
+			return err
+		}
+	}
+	for wq.pending < wq.mq.Cap() {
+		if err := wq.receiveOne(false); err != nil {
+			if IsEmpty(err) {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (wq *WeightedQueue) receiveOne(block bool) error {
+	buff := make([]byte, len(wq.mq.inputBuff))
+	var n, prio int
+	var err error
+	if block {
+		n, prio, err = wq.mq.ReceivePriority(buff)
+	} else {
+		n, prio, err = wq.mq.ReceiveTimeoutPriority(buff, 0)
+	}
+	if err != nil {
+		return err
+	}
+	wq.bands[prio] = append(wq.bands[prio], buff[:n])
+	wq.pending++
+	return nil
+}
+
+// next returns the priority of the band to be served. There must be at least one pending message.
+func (wq *WeightedQueue) next() int {
+	for {
+		best, found := 0, false
+		for prio := range wq.bands {
+			if wq.credits[prio] > 0 && (!found || prio > best) {
+				best, found = prio, true
+			}
+		}
+		if found {
+			return best
+		}
+		// all the bands with messages have used their share, start a new round.
+		for prio := range wq.bands {
+			wq.credits[prio] = wq.weight(prio)
+		}
+	}
+}
+
+func (wq *WeightedQueue) weight(prio int) int {
+	if weight, ok := wq.weights[prio]; ok {
+		return weight
+	}
+	return 1
+}
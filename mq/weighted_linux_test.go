@@ -0,0 +1,62 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package mq
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeightedQueue(t *testing.T) {
+	a := assert.New(t)
+	_, err := NewWeightedQueue(nil, map[int]int{1: 0})
+	a.Error(err)
+	if !a.NoError(DestroyLinuxMessageQueue(testMqName)) {
+		return
+	}
+	mq, err := CreateLinuxMessageQueue(testMqName, os.O_EXCL|os.O_RDWR, 0666, 10, 8)
+	if !a.NoError(err) {
+		return
+	}
+	defer mq.Destroy()
+	for i := 0; i < 6; i++ {
+		if !a.NoError(mq.SendPriority([]byte{2, byte(i)}, 2)) {
+			return
+		}
+	}
+	for i := 0; i < 4; i++ {
+		if !a.NoError(mq.SendPriority([]byte{1, byte(i)}, 1)) {
+			return
+		}
+	}
+	wq, err := NewWeightedQueue(mq, map[int]int{2: 3, 1: 1})
+	if !a.NoError(err) {
+		return
+	}
+	_, _, err = wq.Receive(make([]byte, 1))
+	a.Error(err)
+	a.Equal(10, wq.Pending())
+	var prios []int
+	data := make([]byte, 8)
+	next := map[int]byte{}
+	for i := 0; i < 10; i++ {
+		n, prio, err := wq.Receive(data)
+		if !a.NoError(err) {
+			return
+		}
+		// messages of the same priority are received in order.
+		a.Equal([]byte{byte(prio), next[prio]}, data[:n])
+		next[prio]++
+		prios = append(prios, prio)
+	}
+	a.Equal([]int{2, 2, 2, 1, 2, 2, 2, 1, 1, 1}, prios)
+	a.Equal(0, wq.Pending())
+	// there are no pending messages, so Receive fails on a non-blocking empty queue.
+	if !a.NoError(mq.SetBlocking(false)) {
+		return
+	}
+	_, _, err = wq.Receive(data)
+	a.True(IsEmpty(err))
+}
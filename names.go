@@ -0,0 +1,21 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package ipc
+
+import "github.com/nxgtw/go-ipc/internal/common"
+
+// SetNamePrefix sets a namespace for all the named objects of go-ipc packages.
+// The prefix is prepended to the names of the objects, so independent applications,
+// which use the same names, do not collide: with prefix 'app' shared memory object 'state'
+// is placed into /dev/shm/app.state on linux.
+// Create, open, and destroy functions apply the prefix in the same way, so it must be set
+// before any object is created, and it must be the same in all the communicating processes.
+// An empty prefix (default) disables the namespace.
+func SetNamePrefix(prefix string) {
+	common.SetNamePrefix(prefix)
+}
+
+// NamePrefix returns current namespace prefix.
+func NamePrefix() string {
+	return common.NamePrefix()
+}
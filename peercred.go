@@ -0,0 +1,16 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package ipc
+
+import "github.com/nxgtw/go-ipc/internal/common"
+
+// ErrNotSupported is returned, if an operation is not supported on the current platform or transport.
+var ErrNotSupported = common.ErrNotSupported
+
+// PeerCred returns credentials of the process on the other side of a unix-domain socket.
+// It is the building block for authorizing peers of socket-based transports.
+// It uses SO_PEERCRED on linux. On other platforms, or if fd is not a socket, it returns ErrNotSupported.
+//	fd - a descriptor of a connected unix-domain socket.
+func PeerCred(fd uintptr) (pid, uid, gid int, err error) {
+	return common.PeerCred(fd)
+}
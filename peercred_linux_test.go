@@ -0,0 +1,39 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package ipc
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestPeerCredSocketpair(t *testing.T) {
+	a := assert.New(t)
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM, 0)
+	if !a.NoError(err) {
+		return
+	}
+	defer unix.Close(fds[0])
+	defer unix.Close(fds[1])
+	pid, uid, gid, err := PeerCred(uintptr(fds[0]))
+	if a.NoError(err) {
+		a.Equal(os.Getpid(), pid)
+		a.Equal(os.Getuid(), uid)
+		a.Equal(os.Getgid(), gid)
+	}
+}
+
+func TestPeerCredNotSocket(t *testing.T) {
+	f, err := ioutil.TempFile("", "peercred")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	_, _, _, err = PeerCred(f.Fd())
+	assert.Equal(t, ErrNotSupported, err)
+}
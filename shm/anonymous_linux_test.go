@@ -0,0 +1,38 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package shm
+
+import (
+	"os"
+	"testing"
+
+	"github.com/nxgtw/go-ipc/internal/test"
+	"bitbucket.org/avd/go-ipc/mmf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnonymousMemoryRegionChildProcess(t *testing.T) {
+	a := assert.New(t)
+	region, err := mmf.NewAnonymousMemoryRegion(mmf.MEM_READWRITE, 1024)
+	if !a.NoError(err) {
+		return
+	}
+	defer region.Close()
+	file := region.AnonymousFile()
+	if !a.NotNil(file) {
+		return
+	}
+	// the file is passed to the child as descriptor 3.
+	args := append(shmProgFiles, "-object=anonymous", "fdwrite", "3", "512", "01020304")
+	result := testutil.RunTestAppFiles(args, []*os.File{file}, nil)
+	if !a.NoError(result.Err) {
+		t.Logf("program output is %q", result.Output)
+		return
+	}
+	data := region.Data()
+	a.Equal([]byte{1, 2, 3, 4}, data[512:516])
+	a.Equal(make([]byte, 512), data[:512])
+	a.NoError(region.Close())
+	a.Nil(region.AnonymousFile())
+}
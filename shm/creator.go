@@ -0,0 +1,90 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package shm
+
+import (
+	"encoding/binary"
+	"os"
+	"time"
+
+	"bitbucket.org/avd/go-ipc/mmf"
+
+	"github.com/pkg/errors"
+)
+
+// Creator information is stored in a header at the end of the object, so that the layout
+// of the object's data is not changed. The header is written by the process, which has created the object,
+// when it truncates the object for the first time. Size() and Truncate() of a MemoryObject
+// do not include the header. Its format is:
+//	magic   uint32
+//	version uint32
+//	pid     int64
+//	time    int64 (unix nanoseconds)
+const (
+	creatorHdrMagic   = 0x43504947 // 'GIPC'
+	creatorHdrVersion = 1
+	creatorHdrSize    = 24
+)
+
+type creatorInfo struct {
+	pid     int
+	created time.Time
+}
+
+// Creator returns the pid of the process, which has created the object, and the time of creation.
+// The information is available for objects created with NewMemoryObject or NewMemoryObjectSize
+// with os.O_RDWR access mode, after the creator has truncated the object.
+// It returns an error, if the object was created by some other means.
+func (obj *MemoryObject) Creator() (pid int, created time.Time, err error) {
+	if obj.creator == nil {
+		obj.readCreatorHdr()
+	}
+	if obj.creator == nil {
+		return 0, time.Time{}, errors.New("creator information is not available")
+	}
+	return obj.creator.pid, obj.creator.created, nil
+}
+
+// readCreatorHdr looks for the header at the end of the object.
+func (obj *MemoryObject) readCreatorHdr() {
+	size := obj.memoryObject.Size()
+	if size < creatorHdrSize {
+		return
+	}
+	region, err := mmf.NewMemoryRegion(obj.memoryObject, mmf.MEM_READ_ONLY, size-creatorHdrSize, creatorHdrSize)
+	if err != nil {
+		return
+	}
+	defer region.Close()
+	data := region.Data()
+	if binary.LittleEndian.Uint32(data[0:]) != creatorHdrMagic || binary.LittleEndian.Uint32(data[4:]) != creatorHdrVersion {
+		return
+	}
+	obj.creator = &creatorInfo{
+		pid:     int(binary.LittleEndian.Uint64(data[8:])),
+		created: time.Unix(0, int64(binary.LittleEndian.Uint64(data[16:]))),
+	}
+}
+
+// writeCreatorHdr writes the header at the end of the object.
+func (obj *MemoryObject) writeCreatorHdr(info *creatorInfo) error {
+	size := obj.memoryObject.Size()
+	if size < creatorHdrSize {
+		return errors.New("the object is too small for the creator header")
+	}
+	region, err := mmf.NewMemoryRegion(obj.memoryObject, mmf.MEM_READWRITE, size-creatorHdrSize, creatorHdrSize)
+	if err != nil {
+		return errors.Wrap(err, "failed to map creator header")
+	}
+	defer region.Close()
+	data := region.Data()
+	binary.LittleEndian.PutUint32(data[0:], creatorHdrMagic)
+	binary.LittleEndian.PutUint32(data[4:], creatorHdrVersion)
+	binary.LittleEndian.PutUint64(data[8:], uint64(info.pid))
+	binary.LittleEndian.PutUint64(data[16:], uint64(info.created.UnixNano()))
+	return nil
+}
+
+func newCreatorInfo() *creatorInfo {
+	return &creatorInfo{pid: os.Getpid(), created: time.Now()}
+}
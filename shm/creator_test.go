@@ -0,0 +1,59 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package shm
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryObjectCreator(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyMemoryObject(defaultObjectName)) {
+		return
+	}
+	_, err := NewMemoryObject(defaultObjectName, os.O_RDONLY, 0666)
+	a.True(os.IsNotExist(errors.Cause(err)))
+	before := time.Now()
+	obj, err := NewMemoryObject(defaultObjectName, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer obj.Destroy()
+	// the header is written, when the object is truncated.
+	_, _, err = obj.Creator()
+	a.Error(err)
+	if !a.NoError(obj.Truncate(1024)) {
+		return
+	}
+	a.Equal(int64(1024), obj.Size())
+	pid, created, err := obj.Creator()
+	if a.NoError(err) {
+		a.Equal(os.Getpid(), pid)
+		a.False(created.Before(before.Add(-time.Second)))
+		a.False(created.After(time.Now().Add(time.Second)))
+	}
+	obj2, err := NewMemoryObject(defaultObjectName, os.O_RDWR, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer obj2.Close()
+	a.Equal(int64(1024), obj2.Size())
+	pid2, created2, err := obj2.Creator()
+	if a.NoError(err) {
+		a.Equal(pid, pid2)
+		a.True(created.Equal(created2))
+	}
+	// the header is preserved, if the object is truncated by another instance.
+	if a.NoError(obj2.Truncate(2048)) {
+		a.Equal(int64(2048), obj2.Size())
+		a.Equal(int64(2048), obj.Size())
+		pid2, _, err = obj.Creator()
+		a.NoError(err)
+		a.Equal(pid, pid2)
+	}
+}
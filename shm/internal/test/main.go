@@ -3,6 +3,7 @@
 package main
 
 import (
+	"encoding/binary"
 	"flag"
 	"fmt"
 	"os"
@@ -24,6 +25,20 @@ available commands:
   read offset len
   test offset {expected values byte array}
   write offset {values byte array}
+  subscribe n
+    reads data from a region publisher until it sees n different generations, checking their consistency
+  lruput key value
+    puts a value into a shared lru cache
+  lruget key [value]
+    gets a value from a shared lru cache and checks it. if the value is omitted, checks, that the key is missing
+  heappush {values}
+    pushes uint64 values into a shared heap
+  heappop {values}
+    pops values from a shared heap and checks, that they are equal to the expected ones
+  counteradd n
+    increments a shared int64 counter at the beginning of the object n times
+  fdwrite fd offset {values byte array}
+    writes data into an inherited file descriptor, like an anonymous memory object. the object name is ignored
 byte array should be passed as a continuous string of 2-symbol hex byte values like '01020A'
 `
 
@@ -139,6 +154,232 @@ func write() error {
 	return nil
 }
 
+func subscribe() error {
+	if flag.NArg() != 2 {
+		return fmt.Errorf("subscribe: must provide exactly one argument")
+	}
+	n, err := strconv.ParseUint(flag.Arg(1), 10, 64)
+	if err != nil {
+		return err
+	}
+	object, err := newShmObject(*objName, os.O_RDWR, 0666, *objType, 0)
+	if err != nil {
+		return err
+	}
+	defer object.Close()
+	region, err := mmf.NewMemoryRegion(object, mmf.MEM_READWRITE, 0, 0)
+	if err != nil {
+		return err
+	}
+	defer region.Close()
+	s, err := mmf.NewRegionSubscriber(region)
+	if err != nil {
+		return err
+	}
+	var last, seen uint64
+	for seen < n {
+		s.View(func(gen uint64, data []byte) {
+			if err != nil || gen == 0 || gen == last {
+				return
+			}
+			if gen < last {
+				err = fmt.Errorf("generation went back from %d to %d", last, gen)
+				return
+			}
+			last = gen
+			seen++
+			if len(data) < 8 || binary.LittleEndian.Uint64(data) != gen {
+				err = fmt.Errorf("torn data at generation %d", gen)
+				return
+			}
+			for i, value := range data[8:] {
+				if value != byte(gen) {
+					err = fmt.Errorf("torn data at generation %d, offset %d", gen, i+8)
+					return
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func openLRU() (*mmf.SharedLRU, func(), error) {
+	object, err := newShmObject(*objName, os.O_RDWR, 0666, *objType, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	region, err := mmf.NewMemoryRegion(object, mmf.MEM_READWRITE, 0, 0)
+	object.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := mmf.OpenSharedLRU(region)
+	if err != nil {
+		region.Close()
+		return nil, nil, err
+	}
+	return c, func() { region.Close() }, nil
+}
+
+func lruput() error {
+	if flag.NArg() != 3 {
+		return fmt.Errorf("lruput: must provide exactly two arguments")
+	}
+	c, closer, err := openLRU()
+	if err != nil {
+		return err
+	}
+	defer closer()
+	return c.Put([]byte(flag.Arg(1)), []byte(flag.Arg(2)))
+}
+
+func lruget() error {
+	if flag.NArg() != 2 && flag.NArg() != 3 {
+		return fmt.Errorf("lruget: must provide one or two arguments")
+	}
+	c, closer, err := openLRU()
+	if err != nil {
+		return err
+	}
+	defer closer()
+	value, ok := c.Get([]byte(flag.Arg(1)))
+	if flag.NArg() == 2 {
+		if ok {
+			return fmt.Errorf("the key %q must be missing, got value %q", flag.Arg(1), value)
+		}
+		return nil
+	}
+	if !ok {
+		return fmt.Errorf("the key %q is missing", flag.Arg(1))
+	}
+	if string(value) != flag.Arg(2) {
+		return fmt.Errorf("invalid value for %q. expected %q, got %q", flag.Arg(1), flag.Arg(2), value)
+	}
+	return nil
+}
+
+func heapLess(a, b []byte) bool {
+	return binary.BigEndian.Uint64(a) < binary.BigEndian.Uint64(b)
+}
+
+func openHeap() (*mmf.SharedHeap, func(), error) {
+	object, err := newShmObject(*objName, os.O_RDWR, 0666, *objType, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	region, err := mmf.NewMemoryRegion(object, mmf.MEM_READWRITE, 0, 0)
+	object.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	h, err := mmf.OpenSharedHeap(region, heapLess)
+	if err != nil {
+		region.Close()
+		return nil, nil, err
+	}
+	return h, func() { region.Close() }, nil
+}
+
+func heappush() error {
+	h, closer, err := openHeap()
+	if err != nil {
+		return err
+	}
+	defer closer()
+	for i := 1; i < flag.NArg(); i++ {
+		value, err := strconv.ParseUint(flag.Arg(i), 10, 64)
+		if err != nil {
+			return err
+		}
+		data := make([]byte, 8)
+		binary.BigEndian.PutUint64(data, value)
+		if err = h.Push(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func heappop() error {
+	h, closer, err := openHeap()
+	if err != nil {
+		return err
+	}
+	defer closer()
+	for i := 1; i < flag.NArg(); i++ {
+		expected, err := strconv.ParseUint(flag.Arg(i), 10, 64)
+		if err != nil {
+			return err
+		}
+		data, ok := h.Pop()
+		if !ok {
+			return fmt.Errorf("the heap is empty, expected %d", expected)
+		}
+		if value := binary.BigEndian.Uint64(data); value != expected {
+			return fmt.Errorf("invalid value at %d. expected %d, got %d", i-1, expected, value)
+		}
+	}
+	return nil
+}
+
+func counteradd() error {
+	if flag.NArg() != 2 {
+		return fmt.Errorf("counteradd: must provide exactly one argument")
+	}
+	n, err := strconv.Atoi(flag.Arg(1))
+	if err != nil {
+		return err
+	}
+	object, err := newShmObject(*objName, os.O_RDWR, 0666, *objType, 0)
+	if err != nil {
+		return err
+	}
+	region, err := mmf.NewMemoryRegion(object, mmf.MEM_READWRITE, 0, 8)
+	object.Close()
+	if err != nil {
+		return err
+	}
+	defer region.Close()
+	counter, err := mmf.NewSharedInt64(region, 0)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		counter.Add(1)
+	}
+	return nil
+}
+
+func fdwrite() error {
+	if flag.NArg() != 4 {
+		return fmt.Errorf("fdwrite: must provide exactly three arguments")
+	}
+	fd, err := strconv.Atoi(flag.Arg(1))
+	if err != nil {
+		return err
+	}
+	offset, err := strconv.Atoi(flag.Arg(2))
+	if err != nil {
+		return err
+	}
+	data, err := testutil.StringToBytes(flag.Arg(3))
+	if err != nil {
+		return err
+	}
+	file := os.NewFile(uintptr(fd), "inherited")
+	defer file.Close()
+	region, err := mmf.NewMemoryRegion(file, mmf.MEM_READWRITE, int64(offset), len(data))
+	if err != nil {
+		return err
+	}
+	defer region.Close()
+	copy(region.Data(), data)
+	return nil
+}
+
 func runCommand() error {
 	command := flag.Arg(0)
 	switch command {
@@ -152,6 +393,20 @@ func runCommand() error {
 		return test()
 	case "write":
 		return write()
+	case "subscribe":
+		return subscribe()
+	case "lruput":
+		return lruput()
+	case "lruget":
+		return lruget()
+	case "heappush":
+		return heappush()
+	case "heappop":
+		return heappop()
+	case "counteradd":
+		return counteradd()
+	case "fdwrite":
+		return fdwrite()
 	default:
 		return fmt.Errorf("unknown command")
 	}
@@ -0,0 +1,66 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package shm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/nxgtw/go-ipc/internal/test"
+	"bitbucket.org/avd/go-ipc/mmf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegionPublisherAnotherProcess(t *testing.T) {
+	const (
+		generations = 1000
+		slotSize    = 512
+	)
+	a := assert.New(t)
+	if !a.NoError(DestroyMemoryObject(defaultObjectName)) {
+		return
+	}
+	size := mmf.RegionPublisherSize(3, slotSize)
+	obj, _, err := NewMemoryObjectSize(defaultObjectName, os.O_CREATE|os.O_EXCL, 0666, int64(size))
+	if !a.NoError(err) {
+		return
+	}
+	defer obj.Destroy()
+	region, err := mmf.NewMemoryRegion(obj, mmf.MEM_READWRITE, 0, size)
+	if !a.NoError(err) {
+		return
+	}
+	defer region.Close()
+	p, err := mmf.NewRegionPublisher(region, 3, slotSize)
+	if !a.NoError(err) {
+		return
+	}
+	resultChan := testutil.RunTestAppAsync(argsForShmSubscribeCommand(defaultObjectName, generations), nil)
+	data := make([]byte, slotSize)
+	// publish until the subscriber has seen enough generations.
+	for i := uint64(1); ; i++ {
+		select {
+		case result := <-resultChan:
+			if !a.NoError(result.Err) {
+				t.Logf("program output is %q", result.Output)
+			}
+			return
+		default:
+		}
+		binary.LittleEndian.PutUint64(data, i)
+		for j := 8; j < len(data); j++ {
+			data[j] = byte(i)
+		}
+		gen, err := p.Publish(data)
+		if !a.NoError(err) || !a.Equal(i, gen) {
+			return
+		}
+	}
+}
+
+func argsForShmSubscribeCommand(name string, n int) []string {
+	return append(shmProgFiles, "-object="+name, "subscribe", fmt.Sprintf("%d", n))
+}
@@ -0,0 +1,64 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package shm
+
+import (
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"github.com/nxgtw/go-ipc/internal/test"
+	"bitbucket.org/avd/go-ipc/mmf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func heapLess(a, b []byte) bool {
+	return binary.BigEndian.Uint64(a) < binary.BigEndian.Uint64(b)
+}
+
+func TestSharedHeapAnotherProcess(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyMemoryObject(defaultObjectName)) {
+		return
+	}
+	size := mmf.SharedHeapSize(8, 16)
+	obj, _, err := NewMemoryObjectSize(defaultObjectName, os.O_CREATE|os.O_EXCL, 0666, int64(size))
+	if !a.NoError(err) {
+		return
+	}
+	defer obj.Destroy()
+	region, err := mmf.NewMemoryRegion(obj, mmf.MEM_READWRITE, 0, size)
+	if !a.NoError(err) {
+		return
+	}
+	defer region.Close()
+	h, err := mmf.NewSharedHeap(region, 8, 16, heapLess)
+	if !a.NoError(err) {
+		return
+	}
+	result := testutil.RunTestApp(argsForShmHeapCommand(defaultObjectName, "heappush", "42", "7", "100", "1", "13"), nil)
+	if !a.NoError(result.Err) {
+		t.Logf("program output is %q", result.Output)
+		return
+	}
+	a.Equal(5, h.Len())
+	for _, expected := range []uint64{1, 7} {
+		value, ok := h.Pop()
+		if a.True(ok) {
+			a.Equal(expected, binary.BigEndian.Uint64(value))
+		}
+	}
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, 3)
+	a.NoError(h.Push(data))
+	result = testutil.RunTestApp(argsForShmHeapCommand(defaultObjectName, "heappop", "3", "13", "42", "100"), nil)
+	if !a.NoError(result.Err) {
+		t.Logf("program output is %q", result.Output)
+	}
+	a.Equal(0, h.Len())
+}
+
+func argsForShmHeapCommand(name, command string, values ...string) []string {
+	return append(append(shmProgFiles, "-object="+name, command), values...)
+}
@@ -0,0 +1,51 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package shm
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/nxgtw/go-ipc/internal/test"
+	"bitbucket.org/avd/go-ipc/mmf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedInt64AnotherProcess(t *testing.T) {
+	const count = 1000000
+	a := assert.New(t)
+	if !a.NoError(DestroyMemoryObject(defaultObjectName)) {
+		return
+	}
+	obj, _, err := NewMemoryObjectSize(defaultObjectName, os.O_CREATE|os.O_EXCL, 0666, 8)
+	if !a.NoError(err) {
+		return
+	}
+	defer obj.Destroy()
+	region, err := mmf.NewMemoryRegion(obj, mmf.MEM_READWRITE, 0, 8)
+	if !a.NoError(err) {
+		return
+	}
+	defer region.Close()
+	counter, err := mmf.NewSharedInt64(region, 0)
+	if !a.NoError(err) {
+		return
+	}
+	counter.Store(0)
+	resultChan := testutil.RunTestAppAsync(argsForShmCounterAddCommand(defaultObjectName, count), nil)
+	for i := 0; i < count; i++ {
+		counter.Add(1)
+	}
+	result := <-resultChan
+	if !a.NoError(result.Err) {
+		t.Logf("program output is %q", result.Output)
+		return
+	}
+	a.Equal(int64(count*2), counter.Load())
+}
+
+func argsForShmCounterAddCommand(name string, n int) []string {
+	return append(shmProgFiles, "-object="+name, "counteradd", strconv.Itoa(n))
+}
@@ -0,0 +1,64 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package shm
+
+import (
+	"os"
+	"testing"
+
+	"github.com/nxgtw/go-ipc/internal/test"
+	"bitbucket.org/avd/go-ipc/mmf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedLRUAnotherProcess(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyMemoryObject(defaultObjectName)) {
+		return
+	}
+	size := mmf.SharedLRUSize(8, 8, 3)
+	obj, _, err := NewMemoryObjectSize(defaultObjectName, os.O_CREATE|os.O_EXCL, 0666, int64(size))
+	if !a.NoError(err) {
+		return
+	}
+	defer obj.Destroy()
+	region, err := mmf.NewMemoryRegion(obj, mmf.MEM_READWRITE, 0, size)
+	if !a.NoError(err) {
+		return
+	}
+	defer region.Close()
+	c, err := mmf.NewSharedLRU(region, 8, 8, 3)
+	if !a.NoError(err) {
+		return
+	}
+	a.NoError(c.Put([]byte("k1"), []byte("v1")))
+	a.NoError(c.Put([]byte("k2"), []byte("v2")))
+	// put from another process, then make k1 the most recently used there as well.
+	steps := [][]string{
+		{"lruput", "k3", "v3"},
+		{"lruget", "k1", "v1"},
+		{"lruput", "k4", "v4"},
+		{"lruget", "k2"},
+	}
+	for _, step := range steps {
+		result := testutil.RunTestApp(argsForShmLRUCommand(defaultObjectName, step[0], step[1:]...), nil)
+		if !a.NoError(result.Err) {
+			t.Logf("program output is %q", result.Output)
+			return
+		}
+	}
+	a.Equal(3, c.Len())
+	_, ok := c.Get([]byte("k2"))
+	a.False(ok)
+	for key, expected := range map[string]string{"k1": "v1", "k3": "v3", "k4": "v4"} {
+		value, ok := c.Get([]byte(key))
+		if a.True(ok, key) {
+			a.Equal([]byte(expected), value)
+		}
+	}
+}
+
+func argsForShmLRUCommand(name, command string, params ...string) []string {
+	return append(append(shmProgFiles, "-object="+name, command), params...)
+}
@@ -15,6 +15,12 @@ var (
 	_ SharedMemoryObject = (*MemoryObject)(nil)
 )
 
+var (
+	// ErrSizeMismatch is returned by OpenMemoryObjectExpectSize, if the size of an existing object
+	// differs from the expected one.
+	ErrSizeMismatch = errors.New("the size of the memory object differs from the expected size")
+)
+
 // SharedMemoryObject is an interface, which must be implemented
 // by any implemetation of an object used for mapping into memory.
 type SharedMemoryObject interface {
@@ -29,6 +35,11 @@ type SharedMemoryObject interface {
 // map shared memory regions into the process' address space.
 type MemoryObject struct {
 	*memoryObject
+	// creator is not nil, if the object has a creator header.
+	creator *creatorInfo
+	// ownHdr is true, if the object was created by this instance,
+	// and the creator header must be written on the first Truncate.
+	ownHdr bool
 }
 
 // NewMemoryObject creates a new shared memory object.
@@ -36,18 +47,73 @@ type MemoryObject struct {
 //	size - object size.
 //	flag - flag is a combination of open flags from 'os' package.
 //	perm - object's permission bits.
+// If the object was created, the creator's pid and creation time are stored, see Creator().
 func NewMemoryObject(name string, flag int, perm os.FileMode) (*MemoryObject, error) {
-	impl, err := newMemoryObject(name, flag, perm)
+	var impl *memoryObject
+	var openErr error
+	creator := func(create bool) error {
+		creatorFlag := flag &^ (os.O_CREATE | os.O_EXCL)
+		if create {
+			creatorFlag |= (os.O_CREATE | os.O_EXCL)
+		}
+		impl, openErr = newMemoryObject(name, creatorFlag, perm)
+		return errors.Cause(openErr)
+	}
+	created, err := common.OpenOrCreate(creator, flag)
 	if err != nil {
+		if openErr != nil {
+			return nil, openErr
+		}
 		return nil, err
 	}
-	result := &MemoryObject{impl}
+	result := &MemoryObject{memoryObject: impl}
+	if created {
+		// the header can be written only if the object can be mapped for writing.
+		result.ownHdr = flag&(os.O_RDWR|os.O_WRONLY) == os.O_RDWR
+	} else {
+		result.readCreatorHdr()
+	}
 	runtime.SetFinalizer(impl, func(memObject *memoryObject) {
 		memObject.Close()
 	})
 	return result, nil
 }
 
+// OpenMemoryObjectExpectSize opens or creates a shared memory object and ensures,
+// that its size is exactly expectedSize. It allows to detect stale or incompatible objects at attach time.
+// If the object was created, it is truncated to expectedSize.
+// If an existing object has different size, ErrSizeMismatch is returned.
+//	name - a name of the object. should not contain '/' and exceed 255 symbols (30 on darwin).
+//	flag - flag is a combination of open flags from 'os' package.
+//	perm - object's permission bits.
+//	expectedSize - the size of the object.
+func OpenMemoryObjectExpectSize(name string, flag int, perm os.FileMode, expectedSize int64) (*MemoryObject, error) {
+	var obj *MemoryObject
+	creator := func(create bool) error {
+		var err error
+		creatorFlag := flag &^ (os.O_CREATE | os.O_EXCL)
+		if create {
+			creatorFlag |= (os.O_CREATE | os.O_EXCL)
+		}
+		obj, err = NewMemoryObject(name, creatorFlag, perm)
+		return errors.Cause(err)
+	}
+	created, err := common.OpenOrCreate(creator, flag)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open/create shm object")
+	}
+	if created {
+		if err = obj.Truncate(expectedSize); err != nil {
+			obj.Destroy()
+			return nil, errors.Wrap(err, "failed to truncate shm object")
+		}
+	} else if obj.Size() != expectedSize {
+		obj.Close()
+		return nil, ErrSizeMismatch
+	}
+	return obj, nil
+}
+
 // NewMemoryObjectSize opens or creates a shared memory object with the given name.
 // If the object was created, it is truncated to 'size'.
 // Otherwise, checks, that the existing object is at least 'size' bytes long.
@@ -69,9 +135,11 @@ func NewMemoryObjectSize(name string, flag int, perm os.FileMode, size int64) (S
 	}
 	if created {
 		if resultErr = obj.Truncate(size); resultErr != nil {
+			obj.Destroy()
 			return nil, false, resultErr
 		}
 	} else if obj.Size() < size {
+		obj.Close()
 		return nil, false, errors.Errorf("existing object is smaller (%d), than needed(%d)", obj.Size(), size)
 	}
 	return obj, created, nil
@@ -98,14 +166,37 @@ func (obj *MemoryObject) Close() error {
 // Darwin: it is possible to truncate an object only once after it was created.
 // Darwin: the size should be divisible by system page size,
 // otherwise the size is set to the nearest page size divider greater, then the given size.
+// The creator header, if any, is kept at the end of the object.
 func (obj *MemoryObject) Truncate(size int64) error {
-	return obj.memoryObject.Truncate(size)
+	info := obj.creator
+	if info == nil && obj.ownHdr {
+		info = newCreatorInfo()
+	}
+	if info == nil {
+		return obj.memoryObject.Truncate(size)
+	}
+	if err := obj.memoryObject.Truncate(size + creatorHdrSize); err != nil {
+		return err
+	}
+	if err := obj.writeCreatorHdr(info); err != nil {
+		return err
+	}
+	obj.creator, obj.ownHdr = info, false
+	return nil
 }
 
-// Size returns the current object size.
+// Size returns the current object size, not including the creator header.
 // Darwin: it may differ from the size passed passed to Truncate.
 func (obj *MemoryObject) Size() int64 {
-	return obj.memoryObject.Size()
+	if obj.creator == nil && !obj.ownHdr {
+		// the creator may have truncated the object after it was opened.
+		obj.readCreatorHdr()
+	}
+	size := obj.memoryObject.Size()
+	if obj.creator != nil {
+		size -= creatorHdrSize
+	}
+	return size
 }
 
 // Fd returns a descriptor of the object's underlying file object.
@@ -113,7 +204,69 @@ func (obj *MemoryObject) Fd() uintptr {
 	return obj.memoryObject.Fd()
 }
 
+// Stat returns the description of the object. Its name is the name of the object,
+// and its size is the current object size, not including the creator header, the same as Size returns.
+func (obj *MemoryObject) Stat() (os.FileInfo, error) {
+	info, err := obj.memoryObject.Stat()
+	if err != nil {
+		return nil, errors.Wrap(err, "stat failed")
+	}
+	return &memoryObjectInfo{FileInfo: info, name: obj.Name(), size: obj.Size()}, nil
+}
+
+// memoryObjectInfo overrides the name and the size of the underlying file's info.
+type memoryObjectInfo struct {
+	os.FileInfo
+	name string
+	size int64
+}
+
+func (info *memoryObjectInfo) Name() string {
+	return info.name
+}
+
+func (info *memoryObjectInfo) Size() int64 {
+	return info.size
+}
+
 // DestroyMemoryObject permanently removes given memory object.
+// It returns nil, if the object does not exist.
 func DestroyMemoryObject(name string) error {
 	return destroyMemoryObject(name)
 }
+
+// MemoryObjectExists returns true, if a memory object with the given name exists.
+// It opens the object read-only, so it returns false and an error, if the object can't be opened
+// for any reason other, than its absence, for example, because of the permissions.
+func MemoryObjectExists(name string) (bool, error) {
+	obj, err := NewMemoryObject(name, os.O_RDONLY, 0666)
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, obj.Close()
+}
+
+// MemoryObjectStat returns the description of a memory object with the given name, see MemoryObject.Stat.
+// If the object does not exist, os.IsNotExist(errors.Cause(err)) is true.
+func MemoryObjectStat(name string) (os.FileInfo, error) {
+	obj, err := NewMemoryObject(name, os.O_RDONLY, 0666)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open memory object")
+	}
+	defer obj.Close()
+	return obj.Stat()
+}
+
+// MustDestroyMemoryObject permanently removes given memory object.
+// Unlike DestroyMemoryObject, it returns an error, if the object does not exist.
+// In this case os.IsNotExist(errors.Cause(err)) is true.
+func MustDestroyMemoryObject(name string) error {
+	obj, err := NewMemoryObject(name, os.O_RDONLY, 0666)
+	if err != nil {
+		return errors.Wrap(err, "failed to open memory object")
+	}
+	return obj.Destroy()
+}
@@ -11,6 +11,7 @@ import (
 	"unsafe"
 
 	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/common"
 
 	"golang.org/x/sys/unix"
 )
@@ -27,6 +28,7 @@ func doDestroyMemoryObject(path string) error {
 
 func shmName(name string) (string, error) {
 	const maxNameLen = 30
+	name = common.PrefixedName(name)
 	// workaround from http://www.opensource.apple.com/source/Libc/Libc-320/sys/shm_open.c
 	if isDarwin {
 		newName := fmt.Sprintf("%s\t%d", name, unix.Geteuid())
@@ -7,11 +7,14 @@ package shm
 import (
 	"bufio"
 	"io"
+	"io/ioutil"
 	"os"
 	"strconv"
 	"strings"
 	"sync"
 
+	"github.com/nxgtw/go-ipc/internal/common"
+
 	"github.com/pkg/errors"
 	"golang.org/x/sys/unix"
 )
@@ -52,7 +55,7 @@ func shmOpen(path string, flag int, perm os.FileMode) (*os.File, error) {
 
 // glibc/sysdeps/posix/shm-directory.h
 func shmName(name string) (string, error) {
-	name = strings.TrimLeft(name, "/")
+	name = common.PrefixedName(strings.TrimLeft(name, "/"))
 	nameLen := len(name)
 	if nameLen == 0 || nameLen >= maxNameLen || strings.Contains(name, "/") {
 		return "", errors.New("invalid shm name")
@@ -173,3 +176,29 @@ func scanMountRecord(record string) (result *mntent) {
 	}
 	return
 }
+
+// ListMemoryObjects returns the names of all the memory objects in the system.
+// It is linux-specific for now, as it reads the directory, where the objects are placed.
+// The result includes objects created by other programs, and objects,
+// which are used internally by go-ipc primitives, like mutexes and message queues.
+// If a namespace prefix is set, only the objects from this namespace are returned, without the prefix.
+func ListMemoryObjects() ([]string, error) {
+	dir, err := shmDirectory()
+	if err != nil {
+		return nil, err
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read shm directory")
+	}
+	var result []string
+	for _, info := range infos {
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		if name, ok := common.UnprefixedName(info.Name()); ok {
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
@@ -3,8 +3,11 @@
 package shm
 
 import (
+	"os"
 	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestShmFsFromReader(t *testing.T) {
@@ -37,3 +40,25 @@ func TestShmFsFromMountPoints(t *testing.T) {
 		t.Errorf("couldn't find a correct shm path")
 	}
 }
+
+func TestListMemoryObjects(t *testing.T) {
+	a := assert.New(t)
+	names := []string{defaultObjectName + ".l1", defaultObjectName + ".l2"}
+	for _, name := range names {
+		obj, err := NewMemoryObject(name, os.O_CREATE|os.O_RDWR, 0666)
+		if !a.NoError(err) {
+			return
+		}
+		a.NoError(obj.Close())
+		defer func(name string) {
+			a.NoError(DestroyMemoryObject(name))
+		}(name)
+	}
+	list, err := ListMemoryObjects()
+	if !a.NoError(err) {
+		return
+	}
+	for _, name := range names {
+		a.Contains(list, name)
+	}
+}
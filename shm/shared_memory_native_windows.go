@@ -47,6 +47,7 @@ func NewWindowsNativeMemoryObject(name string, flag, size int) (*WindowsNativeMe
 	maxSizeHigh := uint32((int64(size)) >> 32)
 	maxSizeLow := uint32((int64(size)) & 0xFFFFFFFF)
 
+	sysName := common.PrefixedName(name)
 	var handle windows.Handle
 	creator := func(create bool) error {
 		if create {
@@ -56,12 +57,12 @@ func NewWindowsNativeMemoryObject(name string, flag, size int) (*WindowsNativeMe
 				prot,
 				maxSizeHigh,
 				maxSizeLow,
-				name)
+				sysName)
 			if os.IsExist(err) {
 				windows.CloseHandle(handle)
 			}
 		} else {
-			handle, err = sys.OpenFileMapping(sysFlags, 0, name)
+			handle, err = sys.OpenFileMapping(sysFlags, 0, sysName)
 		}
 		return err
 	}
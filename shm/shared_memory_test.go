@@ -12,6 +12,7 @@ import (
 	"github.com/nxgtw/go-ipc/internal/test"
 	"bitbucket.org/avd/go-ipc/mmf"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -136,6 +137,45 @@ func TestDestroyMemoryObject2(t *testing.T) {
 	}
 }
 
+func TestDestroyMissingMemoryObject(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyMemoryObject(defaultObjectName)) {
+		return
+	}
+	a.NoError(DestroyMemoryObject(defaultObjectName))
+	err := MustDestroyMemoryObject(defaultObjectName)
+	if a.Error(err) {
+		a.True(os.IsNotExist(errors.Cause(err)))
+	}
+	obj, err := NewMemoryObject(defaultObjectName, os.O_CREATE|os.O_RDWR, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	a.NoError(MustDestroyMemoryObject(defaultObjectName))
+	a.NoError(obj.Close())
+	a.Error(MustDestroyMemoryObject(defaultObjectName))
+}
+
+func TestMemoryObjectExists(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyMemoryObject(defaultObjectName)) {
+		return
+	}
+	exists, err := MemoryObjectExists(defaultObjectName)
+	a.NoError(err)
+	a.False(exists)
+	obj, err := NewMemoryObject(defaultObjectName, os.O_CREATE|os.O_RDWR, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(obj.Destroy())
+	}()
+	exists, err = MemoryObjectExists(defaultObjectName)
+	a.NoError(err)
+	a.True(exists)
+}
+
 func TestCreateMemoryRegionExclusive(t *testing.T) {
 	obj, err := NewMemoryObject(defaultObjectName, os.O_CREATE|os.O_RDWR, 0666)
 	if !assert.NoError(t, err) {
@@ -173,6 +213,41 @@ func TestMemoryObjectSize(t *testing.T) {
 	}
 }
 
+func TestMemoryObjectStat(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyMemoryObject(defaultObjectName)) {
+		return
+	}
+	_, err := MemoryObjectStat(defaultObjectName)
+	a.True(os.IsNotExist(errors.Cause(err)))
+	obj, err := NewMemoryObject(defaultObjectName, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0600)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(obj.Destroy())
+	}()
+	if !a.NoError(obj.Truncate(4096)) {
+		return
+	}
+	info, err := obj.Stat()
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(defaultObjectName, info.Name())
+	a.Equal(int64(4096), info.Size())
+	a.False(info.IsDir())
+	if runtime.GOOS != "windows" {
+		a.Equal(os.FileMode(0600), info.Mode().Perm())
+	}
+	info, err = MemoryObjectStat(defaultObjectName)
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(defaultObjectName, info.Name())
+	a.Equal(int64(4096), info.Size())
+}
+
 func TestMemoryObjectName(t *testing.T) {
 	a := assert.New(t)
 	obj, err := NewMemoryObject(defaultObjectName, os.O_CREATE|os.O_RDWR, 0666)
@@ -425,3 +500,24 @@ func TestMemoryRegionReaderWriter(t *testing.T) {
 	}
 	assert.Equal(t, data, actual)
 }
+
+func TestOpenMemoryObjectExpectSize(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyMemoryObject(defaultObjectName)) {
+		return
+	}
+	obj, err := OpenMemoryObjectExpectSize(defaultObjectName, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666, 1024)
+	if !a.NoError(err) {
+		return
+	}
+	defer obj.Destroy()
+	a.Equal(int64(1024), obj.Size())
+	obj2, err := OpenMemoryObjectExpectSize(defaultObjectName, os.O_RDONLY, 0666, 1024)
+	if a.NoError(err) {
+		a.NoError(obj2.Close())
+	}
+	_, err = OpenMemoryObjectExpectSize(defaultObjectName, os.O_RDONLY, 0666, 2048)
+	a.Equal(ErrSizeMismatch, err)
+	_, err = OpenMemoryObjectExpectSize(defaultObjectName, os.O_CREATE|os.O_RDWR, 0666, 512)
+	a.Equal(ErrSizeMismatch, err)
+}
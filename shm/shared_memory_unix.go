@@ -83,6 +83,10 @@ func (obj *memoryObject) Size() int64 {
 	return fileInfo.Size()
 }
 
+func (obj *memoryObject) Stat() (os.FileInfo, error) {
+	return obj.file.Stat()
+}
+
 func (obj *memoryObject) Fd() uintptr {
 	return obj.file.Fd()
 }
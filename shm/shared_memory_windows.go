@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"runtime"
 
+	"github.com/nxgtw/go-ipc/internal/common"
+
 	"github.com/pkg/errors"
 )
 
@@ -58,6 +60,10 @@ func (obj *memoryObject) Size() int64 {
 	return fileInfo.Size()
 }
 
+func (obj *memoryObject) Stat() (os.FileInfo, error) {
+	return obj.file.Stat()
+}
+
 func (obj *memoryObject) Fd() uintptr {
 	return obj.file.Fd()
 }
@@ -80,7 +86,7 @@ func shmName(name string) (string, error) {
 	if err != nil {
 		return "", errors.Wrap(err, "failed to get tmp directory name")
 	}
-	return path + "/" + name, nil
+	return path + "/" + common.PrefixedName(name), nil
 }
 
 func sharedDirName() (string, error) {
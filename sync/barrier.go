@@ -0,0 +1,200 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/helper"
+	"bitbucket.org/avd/go-ipc/mmf"
+	"bitbucket.org/avd/go-ipc/shm"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	barrierStateSize   = int(unsafe.Sizeof(barrierState{}))
+	barrierInitTimeout = time.Second
+)
+
+// barrierState is placed in shared memory.
+// ready is set by the creator, when the state and the objects of the barrier have been initialized.
+// parties does not change after that. The other fields are protected by the barrier's mutex.
+type barrierState struct {
+	ready      int32
+	parties    int32
+	count      int32
+	generation uint32
+}
+
+// Barrier makes a number of processes wait for each other at a certain point.
+// When the last of 'parties' processes calls Wait, all the waiters are released,
+// and the barrier can be used again.
+type Barrier struct {
+	name   string
+	region *mmf.MemoryRegion
+	state  *barrierState
+	m      IPCLocker
+	cond   *Cond
+}
+
+// NewBarrier creates a new barrier, or opens an existing one.
+//	name - object name.
+//	flag - flag is a combination of open flags from 'os' package.
+//	perm - object's permission bits.
+//	parties - the number of processes, which must call Wait to release the barrier.
+//		if the barrier already exists, it must be the same, as the value used to create it.
+func NewBarrier(name string, flag int, perm os.FileMode, parties int) (*Barrier, error) {
+	if err := ensureOpenFlags(flag); err != nil {
+		return nil, err
+	}
+	if parties <= 0 {
+		return nil, errors.Errorf("invalid number of parties %d", parties)
+	}
+	region, created, err := helper.CreateWritableRegion(barrierName(name), flag, perm, barrierStateSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create shared state")
+	}
+	result := &Barrier{
+		name:   name,
+		region: region,
+		state:  (*barrierState)(allocator.ByteSliceData(region.Data())),
+	}
+	if created {
+		// nobody can use the state, until it is marked as ready.
+		result.state.parties = int32(parties)
+		result.state.count = 0
+		result.state.generation = 0
+	} else if err = result.waitReady(); err != nil {
+		region.Close()
+		return nil, err
+	}
+	if err = result.init(flag, perm); err != nil {
+		region.Close()
+		if created {
+			DestroyBarrier(name)
+		}
+		return nil, err
+	}
+	if created {
+		atomic.StoreInt32(&result.state.ready, 1)
+	} else if actual := result.state.parties; actual != int32(parties) {
+		result.Close()
+		return nil, errors.Errorf("the barrier was created for %d parties, not for %d", actual, parties)
+	}
+	return result, nil
+}
+
+// waitReady waits for the barrier to be initialized by its creator.
+func (b *Barrier) waitReady() error {
+	start := time.Now()
+	for atomic.LoadInt32(&b.state.ready) == 0 {
+		if time.Since(start) > barrierInitTimeout {
+			return errors.New("the barrier has not been initialized")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return nil
+}
+
+func (b *Barrier) init(flag int, perm os.FileMode) error {
+	var err error
+	if b.m, err = NewMutex(barrierMutexName(b.name), flag, perm); err != nil {
+		return errors.Wrap(err, "failed to create a mutex")
+	}
+	if b.cond, err = NewCond(barrierCondName(b.name), flag, perm, b.m); err != nil {
+		b.m.Close()
+		return errors.Wrap(err, "failed to create a cond")
+	}
+	return nil
+}
+
+// Wait blocks, until all the parties have called Wait.
+// It returns an error, if one of the underlying primitives has failed.
+// After that the state of the barrier is undefined.
+func (b *Barrier) Wait() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			var ok bool
+			if err, ok = r.(error); !ok {
+				err = errors.Errorf("%v", r)
+			}
+		}
+	}()
+	b.m.Lock()
+	generation := b.state.generation
+	b.state.count++
+	if b.state.count == b.state.parties {
+		b.state.count = 0
+		b.state.generation++
+		b.cond.Broadcast()
+		b.m.Unlock()
+		return nil
+	}
+	// the loop protects from spurious wakeups.
+	for generation == b.state.generation {
+		b.cond.Wait()
+	}
+	b.m.Unlock()
+	return nil
+}
+
+// Close closes the barrier.
+func (b *Barrier) Close() error {
+	errObjects := b.closeObjects()
+	if err := b.region.Close(); err != nil {
+		return errors.Wrap(err, "failed to close shm region")
+	}
+	return errObjects
+}
+
+// Destroy closes the barrier and removes it permanently.
+func (b *Barrier) Destroy() error {
+	if err := b.Close(); err != nil {
+		return err
+	}
+	return DestroyBarrier(b.name)
+}
+
+func (b *Barrier) closeObjects() error {
+	errCond := b.cond.Close()
+	if err := b.m.Close(); err != nil {
+		return errors.Wrap(err, "failed to close the mutex")
+	}
+	if errCond != nil {
+		return errors.Wrap(errCond, "failed to close the cond")
+	}
+	return nil
+}
+
+// DestroyBarrier permanently removes a barrier with the given name.
+func DestroyBarrier(name string) error {
+	errCond := DestroyCond(barrierCondName(name))
+	errMutex := DestroyMutex(barrierMutexName(name))
+	if err := shm.DestroyMemoryObject(barrierName(name)); err != nil {
+		return errors.Wrap(err, "failed to destroy memory object")
+	}
+	if errMutex != nil {
+		return errors.Wrap(errMutex, "failed to destroy the mutex")
+	}
+	if errCond != nil {
+		return errors.Wrap(errCond, "failed to destroy the cond")
+	}
+	return nil
+}
+
+func barrierName(baseName string) string {
+	return baseName + ".br"
+}
+
+func barrierMutexName(baseName string) string {
+	return baseName + ".brm"
+}
+
+func barrierCondName(baseName string) string {
+	return baseName + ".brc"
+}
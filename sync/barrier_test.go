@@ -0,0 +1,148 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nxgtw/go-ipc/internal/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testBarrierName = "go-ipc.test-br"
+)
+
+func TestBarrierOpenMode(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyBarrier(testBarrierName)) {
+		return
+	}
+	_, err := NewBarrier(testBarrierName, os.O_CREATE|os.O_EXCL, 0666, 0)
+	a.Error(err)
+	b, err := NewBarrier(testBarrierName, os.O_CREATE|os.O_EXCL, 0666, 2)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(b.Destroy())
+	}()
+	_, err = NewBarrier(testBarrierName, 0, 0666, 3)
+	a.Error(err)
+	b2, err := NewBarrier(testBarrierName, 0, 0666, 2)
+	if a.NoError(err) {
+		a.NoError(b2.Close())
+	}
+}
+
+func TestBarrierConcurrentOpen(t *testing.T) {
+	const parties = 8
+	a := assert.New(t)
+	for i := 0; i < 10; i++ {
+		if !a.NoError(DestroyBarrier(testBarrierName)) {
+			return
+		}
+		// all the callers race to create the barrier, and openers must not see it half-initialized.
+		ch := make(chan error, parties)
+		barriers := make(chan *Barrier, parties)
+		for j := 0; j < parties; j++ {
+			go func() {
+				b, err := NewBarrier(testBarrierName, os.O_CREATE, 0666, parties)
+				if err == nil {
+					barriers <- b
+				}
+				ch <- err
+			}()
+		}
+		for j := 0; j < parties; j++ {
+			a.NoError(<-ch)
+		}
+		close(barriers)
+		for b := range barriers {
+			a.NoError(b.Close())
+		}
+	}
+	a.NoError(DestroyBarrier(testBarrierName))
+}
+
+func TestBarrierWait(t *testing.T) {
+	const parties = 4
+	a := assert.New(t)
+	if !a.NoError(DestroyBarrier(testBarrierName)) {
+		return
+	}
+	b, err := NewBarrier(testBarrierName, os.O_CREATE|os.O_EXCL, 0666, parties)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(b.Destroy())
+	}()
+	var passed int32
+	ch := make(chan error, parties)
+	wait := func() {
+		err := b.Wait()
+		atomic.AddInt32(&passed, 1)
+		ch <- err
+	}
+	// the barrier must be reusable, so use it twice.
+	for i := 0; i < 2; i++ {
+		atomic.StoreInt32(&passed, 0)
+		for j := 0; j < parties-1; j++ {
+			go wait()
+		}
+		time.Sleep(time.Millisecond * 100)
+		a.Equal(int32(0), atomic.LoadInt32(&passed))
+		a.NoError(b.Wait())
+		for j := 0; j < parties-1; j++ {
+			select {
+			case err := <-ch:
+				a.NoError(err)
+			case <-time.After(time.Second * 3):
+				t.Errorf("timeout")
+				return
+			}
+		}
+	}
+}
+
+func TestBarrierAnotherProcess(t *testing.T) {
+	const (
+		parties = 4
+		n       = 8
+	)
+	a := assert.New(t)
+	if !a.NoError(DestroyBarrier(testBarrierName)) {
+		return
+	}
+	b, err := NewBarrier(testBarrierName, os.O_CREATE|os.O_EXCL, 0666, parties)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(b.Destroy())
+	}()
+	var chans []<-chan testutil.TestAppResult
+	for i := 0; i < parties-1; i++ {
+		chans = append(chans, testutil.RunTestAppAsync(argsForBarrierWaitCommand(testBarrierName, parties, n), nil))
+	}
+	for i := 0; i < n; i++ {
+		if !a.NoError(b.Wait()) {
+			return
+		}
+	}
+	for _, ch := range chans {
+		select {
+		case res := <-ch:
+			if res.Err != nil {
+				t.Errorf("app error: %v. the output is %q", res.Err, res.Output)
+			}
+		case <-time.After(time.Second * 10):
+			t.Errorf("timeout")
+		}
+	}
+}
@@ -8,7 +8,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/nxgtw/go-ipc/internal/allocator"
 	"github.com/nxgtw/go-ipc/internal/test"
+	"bitbucket.org/avd/go-ipc/mmf"
+	"bitbucket.org/avd/go-ipc/shm"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -196,3 +199,47 @@ func TestCondWaitAnotherProcess(t *testing.T) {
 		t.Errorf("timeout")
 	}
 }
+
+func TestCondProducerAnotherProcess(t *testing.T) {
+	const value = int64(0x7e57)
+	a := assert.New(t)
+	if !a.NoError(shm.DestroyMemoryObject(testMemObj)) {
+		return
+	}
+	region, err := createMemoryRegionSimple(os.O_CREATE|os.O_EXCL|os.O_RDWR, mmf.MEM_READWRITE, 8, 0)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(region.Close())
+		a.NoError(shm.DestroyMemoryObject(testMemObj))
+	}()
+	cond, l, err := makeTestCond(a)
+	if err != nil {
+		return
+	}
+	defer destroyTestCond(a, cond, l)
+	ptr := (*int64)(allocator.ByteSliceData(region.Data()))
+	l.Lock()
+	args := argsForCondProduceCommand(testCondName, testCondMutName, testMemObj, value)
+	ch := testutil.RunTestAppAsync(args, nil)
+	deadline := time.Now().Add(time.Second * 3)
+	// the loop protects from spurious wakeups.
+	for *ptr == 0 {
+		left := deadline.Sub(time.Now())
+		if left <= 0 || !cond.WaitTimeout(left) {
+			break
+		}
+	}
+	result := *ptr
+	l.Unlock()
+	a.Equal(value, result)
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			t.Errorf("app error: %v. the output is %q", res.Err, res.Output)
+		}
+	case <-time.After(time.Second * 3):
+		t.Errorf("timeout")
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/helper"
+	"bitbucket.org/avd/go-ipc/mmf"
+	"bitbucket.org/avd/go-ipc/shm"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	heartbeatStateSize = 8
+)
+
+// Heartbeat is a liveness indicator, which can be shared between processes.
+// One process periodically calls Beat(), and others check, whether the last beat was recent enough.
+// Its state is a wall clock timestamp in nanoseconds stored in shared memory,
+// so the processes are affected by system clock adjustments.
+type Heartbeat struct {
+	name   string
+	region *mmf.MemoryRegion
+	value  *uint64
+}
+
+// NewHeartbeat creates a new heartbeat, or opens an existing one.
+// A new heartbeat has never been beaten, so it is not alive.
+//	name - object name.
+//	flag - flag is a combination of open flags from 'os' package.
+//	perm - object's permission bits.
+func NewHeartbeat(name string, flag int, perm os.FileMode) (*Heartbeat, error) {
+	if err := ensureOpenFlags(flag); err != nil {
+		return nil, err
+	}
+	region, _, err := helper.CreateWritableRegion(heartbeatName(name), flag, perm, heartbeatStateSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create shared state")
+	}
+	return &Heartbeat{
+		name:   name,
+		region: region,
+		value:  (*uint64)(allocator.ByteSliceData(region.Data())),
+	}, nil
+}
+
+// Beat sets the time of the last beat to the current time.
+func (h *Heartbeat) Beat() {
+	atomic.StoreUint64(h.value, uint64(time.Now().UnixNano()))
+}
+
+// LastBeat returns the time of the last beat.
+// It returns zero time, if there were no beats.
+func (h *Heartbeat) LastBeat() time.Time {
+	ns := atomic.LoadUint64(h.value)
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(ns))
+}
+
+// Alive returns true, if the last beat happened not earlier, than maxAge ago.
+func (h *Heartbeat) Alive(maxAge time.Duration) bool {
+	last := h.LastBeat()
+	if last.IsZero() {
+		return false
+	}
+	return time.Since(last) <= maxAge
+}
+
+// Close closes the heartbeat.
+func (h *Heartbeat) Close() error {
+	return h.region.Close()
+}
+
+// Destroy closes the heartbeat and removes it permanently.
+func (h *Heartbeat) Destroy() error {
+	if err := h.Close(); err != nil {
+		return errors.Wrap(err, "failed to close shm region")
+	}
+	return DestroyHeartbeat(h.name)
+}
+
+// DestroyHeartbeat permanently removes a heartbeat with the given name.
+func DestroyHeartbeat(name string) error {
+	if err := shm.DestroyMemoryObject(heartbeatName(name)); err != nil {
+		return errors.Wrap(err, "failed to destroy memory object")
+	}
+	return nil
+}
+
+func heartbeatName(baseName string) string {
+	return baseName + ".hb"
+}
@@ -0,0 +1,72 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nxgtw/go-ipc/internal/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testHeartbeatName = "go-ipc.test-hb"
+)
+
+func TestHeartbeat(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyHeartbeat(testHeartbeatName)) {
+		return
+	}
+	h, err := NewHeartbeat(testHeartbeatName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(h.Destroy())
+	}()
+	a.True(h.LastBeat().IsZero())
+	a.False(h.Alive(time.Hour))
+	before := time.Now()
+	h.Beat()
+	a.False(h.LastBeat().Before(before))
+	a.True(h.Alive(time.Second))
+	time.Sleep(time.Millisecond * 20)
+	a.False(h.Alive(time.Millisecond * 10))
+}
+
+func TestHeartbeatAnotherProcess(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyHeartbeat(testHeartbeatName)) {
+		return
+	}
+	h, err := NewHeartbeat(testHeartbeatName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(h.Destroy())
+	}()
+	const maxAge = 200 * time.Millisecond
+	resultChan := testutil.RunTestAppAsync(argsForHeartbeatBeatCommand(testHeartbeatName, 10, 500), nil)
+	if !a.True(testutil.WaitForFunc(func() {
+		for !h.Alive(maxAge) {
+			time.Sleep(time.Millisecond)
+		}
+	}, time.Second*10)) {
+		return
+	}
+	result := <-resultChan
+	if !a.NoError(result.Err) {
+		t.Logf("program output is %q", result.Output)
+		return
+	}
+	a.True(testutil.WaitForFunc(func() {
+		for h.Alive(maxAge) {
+			time.Sleep(time.Millisecond)
+		}
+	}, maxAge*2))
+}
@@ -0,0 +1,66 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"bitbucket.org/avd/go-ipc/sync"
+)
+
+const usage = `  test program for barriers.
+available commands:
+  wait barrier_name parties n
+    waits at the barrier n times.
+`
+
+func wait() error {
+	if flag.NArg() != 4 {
+		return fmt.Errorf("wait: must provide barrier name, parties and the number of waits")
+	}
+	parties, err := strconv.Atoi(flag.Arg(2))
+	if err != nil {
+		return err
+	}
+	n, err := strconv.Atoi(flag.Arg(3))
+	if err != nil {
+		return err
+	}
+	b, err := sync.NewBarrier(flag.Arg(1), 0, 0666, parties)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		if err = b.Wait(); err != nil {
+			b.Close()
+			return err
+		}
+	}
+	return b.Close()
+}
+
+func runCommand() error {
+	command := flag.Arg(0)
+	switch command {
+	case "wait":
+		return wait()
+	default:
+		return fmt.Errorf("unknown command")
+	}
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Print(usage)
+		flag.Usage()
+		os.Exit(1)
+	}
+	if err := runCommand(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
@@ -6,8 +6,13 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 	"time"
+	"unsafe"
 
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"bitbucket.org/avd/go-ipc/mmf"
+	"bitbucket.org/avd/go-ipc/shm"
 	"bitbucket.org/avd/go-ipc/sync"
 )
 
@@ -21,6 +26,8 @@ available commands:
   wait cond_name locker_name
   signal cond_name
   broadcast cond_name
+  produce cond_name locker_name shm_name value
+    writes an int64 value at the beginning of the shm_name region under the lock and signals the cond
 `
 
 func makeCond(condName, lockerName string) (cond *sync.Cond, l sync.IPCLocker, err error) {
@@ -88,6 +95,41 @@ func broadcast() error {
 	return cond.Close()
 }
 
+func produce() error {
+	if flag.NArg() != 5 {
+		return fmt.Errorf("produce: must provide cond, locker, shm name and value")
+	}
+	value, err := strconv.ParseInt(flag.Arg(4), 10, 64)
+	if err != nil {
+		return err
+	}
+	memObject, err := shm.NewMemoryObject(flag.Arg(3), os.O_RDWR, 0666)
+	if err != nil {
+		return err
+	}
+	defer memObject.Close()
+	region, err := mmf.NewMemoryRegion(memObject, mmf.MEM_READWRITE, 0, int(unsafe.Sizeof(int64(0))))
+	if err != nil {
+		return err
+	}
+	defer region.Close()
+	cond, l, err := makeCond(flag.Arg(1), flag.Arg(2))
+	if err != nil {
+		return err
+	}
+	ptr := (*int64)(allocator.ByteSliceData(region.Data()))
+	l.Lock()
+	*ptr = value
+	cond.Signal()
+	l.Unlock()
+	if err1, err2 := cond.Close(), l.Close(); err1 != nil {
+		return err1
+	} else if err2 != nil {
+		return err2
+	}
+	return nil
+}
+
 func runCommand() error {
 	command := flag.Arg(0)
 	switch command {
@@ -97,6 +139,8 @@ func runCommand() error {
 		return signal()
 	case "broadcast":
 		return broadcast()
+	case "produce":
+		return produce()
 	default:
 		return fmt.Errorf("unknown command")
 	}
@@ -0,0 +1,66 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"bitbucket.org/avd/go-ipc/sync"
+)
+
+var (
+	interval = flag.Int("interval", 10, "interval between beats, in ms.")
+)
+
+const usage = `  test program for heartbeats.
+available commands:
+  beat heartbeat_name duration
+    beats every 'interval' ms for 'duration' ms.
+`
+
+func beat() error {
+	if flag.NArg() != 3 {
+		return fmt.Errorf("beat: must provide heartbeat name and duration")
+	}
+	duration, err := strconv.Atoi(flag.Arg(2))
+	if err != nil {
+		return err
+	}
+	h, err := sync.NewHeartbeat(flag.Arg(1), 0, 0666)
+	if err != nil {
+		return err
+	}
+	end := time.Now().Add(time.Duration(duration) * time.Millisecond)
+	for time.Now().Before(end) {
+		h.Beat()
+		time.Sleep(time.Duration(*interval) * time.Millisecond)
+	}
+	return h.Close()
+}
+
+func runCommand() error {
+	command := flag.Arg(0)
+	switch command {
+	case "beat":
+		return beat()
+	default:
+		return fmt.Errorf("unknown command")
+	}
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Print(usage)
+		flag.Usage()
+		os.Exit(1)
+	}
+	if err := runCommand(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
@@ -28,6 +28,12 @@ type readLocker interface {
 	RLocker() ipc_sync.IPCLocker
 }
 
+type recursiveReadLocker interface {
+	RLockOwner(owner uint64)
+	RUnlockOwner(owner uint64)
+	SetRecursiveRead(recursive bool)
+}
+
 const usage = `  test program for synchronization primitives.
 available commands:
   create
@@ -36,6 +42,8 @@ available commands:
     increments an int64 value at the beginning of the shm_name region n times
   test shm_name n {expected values byte array}
     performs n reads from shm_name and compares the results with the expected data
+  rrlock n depth
+    takes a read lock in recursive read mode depth times and releases it, repeating it n times
 if jobs > 1, all goroutines will execute operations reads.
 byte array should be passed as a continuous string of 2-symbol hex byte values like '01020A'
 `
@@ -177,6 +185,41 @@ func testData(expected, actual []byte, locker sync.Locker, id int) error {
 	return nil
 }
 
+func rrlock() error {
+	if flag.NArg() != 3 {
+		return fmt.Errorf("rrlock: must provide exactly two arguments")
+	}
+	n, err := strconv.Atoi(flag.Arg(1))
+	if err != nil {
+		return err
+	}
+	depth, err := strconv.Atoi(flag.Arg(2))
+	if err != nil {
+		return err
+	}
+	locker, err := createLocker(*objType, *objName, 0)
+	if err != nil {
+		return err
+	}
+	rLocker, ok := locker.(recursiveReadLocker)
+	if !ok {
+		return fmt.Errorf("%q mutex type does not support recursive read mode", *objType)
+	}
+	rLocker.SetRecursiveRead(true)
+	return performParallel(func(job int) error {
+		owner := uint64(job)
+		for i := 0; i < n; i++ {
+			for j := 0; j < depth; j++ {
+				rLocker.RLockOwner(owner)
+			}
+			for j := 0; j < depth; j++ {
+				rLocker.RUnlockOwner(owner)
+			}
+		}
+		return nil
+	})
+}
+
 func runCommand() error {
 	command := flag.Arg(0)
 	if *jobs <= 0 {
@@ -191,6 +234,8 @@ func runCommand() error {
 		return inc64()
 	case "test":
 		return test()
+	case "rrlock":
+		return rrlock()
 	default:
 		return fmt.Errorf("unknown command")
 	}
@@ -0,0 +1,72 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"bitbucket.org/avd/go-ipc/mmf"
+	"bitbucket.org/avd/go-ipc/shm"
+	"bitbucket.org/avd/go-ipc/sync"
+)
+
+const usage = `  test program for once objects.
+available commands:
+  do once_name shm_name
+    increments an int32 value at the beginning of the shm_name region inside Do.
+`
+
+func do() error {
+	if flag.NArg() != 3 {
+		return fmt.Errorf("do: must provide once name and shm name")
+	}
+	o, err := sync.NewOnce(flag.Arg(1), 0, 0666)
+	if err != nil {
+		return err
+	}
+	defer o.Close()
+	obj, err := shm.NewMemoryObject(flag.Arg(2), os.O_RDWR, 0666)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+	region, err := mmf.NewMemoryRegion(obj, mmf.MEM_READWRITE, 0, 4)
+	if err != nil {
+		return err
+	}
+	defer region.Close()
+	return o.Do(func() error {
+		atomic.AddInt32((*int32)(allocator.ByteSliceData(region.Data())), 1)
+		// give other processes a chance to call Do concurrently.
+		time.Sleep(time.Millisecond * 50)
+		return nil
+	})
+}
+
+func runCommand() error {
+	command := flag.Arg(0)
+	switch command {
+	case "do":
+		return do()
+	default:
+		return fmt.Errorf("unknown command")
+	}
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Print(usage)
+		flag.Usage()
+		os.Exit(1)
+	}
+	if err := runCommand(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"bitbucket.org/avd/go-ipc/sync"
+)
+
+const usage = `  test program for sequences.
+available commands:
+  next sequence_name count
+    draws count values and prints them, one per line.
+`
+
+func next() error {
+	if flag.NArg() != 3 {
+		return fmt.Errorf("next: must provide sequence name and count")
+	}
+	count, err := strconv.Atoi(flag.Arg(2))
+	if err != nil {
+		return err
+	}
+	s, err := sync.NewSequence(flag.Arg(1), 0, 0666, 0)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		fmt.Println(s.Next())
+	}
+	return s.Close()
+}
+
+func runCommand() error {
+	command := flag.Arg(0)
+	switch command {
+	case "next":
+		return next()
+	default:
+		return fmt.Errorf("unknown command")
+	}
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Print(usage)
+		flag.Usage()
+		os.Exit(1)
+	}
+	if err := runCommand(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
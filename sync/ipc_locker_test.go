@@ -3,6 +3,7 @@
 package sync
 
 import (
+	"context"
 	"math/rand"
 	"os"
 	"runtime"
@@ -17,6 +18,7 @@ import (
 	"bitbucket.org/avd/go-ipc/mmf"
 	"bitbucket.org/avd/go-ipc/shm"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -354,6 +356,47 @@ func testLockerLockTimeout(t *testing.T, typ string, ctor lockerCtor, dtor locke
 	a.False(tl.LockTimeout(timeout))
 }
 
+func testLockerLockContext(t *testing.T, typ string, ctor lockerCtor, dtor lockerDtor) {
+	a := assert.New(t)
+	if !a.NoError(dtor(testLockerName)) {
+		return
+	}
+	m, err := ctor(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) || !a.NotNil(m) {
+		return
+	}
+	defer dtor(testLockerName)
+	defer func() {
+		a.NoError(m.Close())
+	}()
+	cl, ok := m.(ContextLocker)
+	if !ok {
+		t.Skipf("context locker of type %q is not supported on %s(%s)", typ, runtime.GOOS, runtime.GOARCH)
+		return
+	}
+	a.NoError(cl.LockContext(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	start := time.Now()
+	a.Equal(context.DeadlineExceeded, cl.LockContext(ctx))
+	a.True(time.Since(start) >= time.Millisecond*50)
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	start = time.Now()
+	a.Equal(context.Canceled, cl.LockContext(ctx))
+	a.True(time.Since(start) < time.Millisecond*10)
+	// the mutex is released by another goroutine while waiting.
+	go func() {
+		<-time.After(time.Millisecond * 50)
+		cl.Unlock()
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if a.NoError(cl.LockContext(ctx)) {
+		cl.Unlock()
+	}
+}
+
 func testLockerLockTimeout2(t *testing.T, typ string, ctor lockerCtor, dtor lockerDtor) {
 	a := assert.New(t)
 	if !a.NoError(dtor(testLockerName)) {
@@ -537,3 +580,145 @@ func BenchmarkSpinMutexAsRW(b *testing.B) {
 	defer m.Close()
 	benchmarkRWLocker(b, m, m)
 }
+
+func testLockerMustDestroy(t *testing.T, ctor lockerCtor, dtor, mustDtor lockerDtor) {
+	a := assert.New(t)
+	if !a.NoError(dtor(testLockerName)) {
+		return
+	}
+	a.NoError(dtor(testLockerName))
+	err := mustDtor(testLockerName)
+	if a.Error(err) {
+		a.True(os.IsNotExist(errors.Cause(err)))
+	}
+	lk, err := ctor(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	// on windows the object exists, while it is open.
+	a.NoError(mustDtor(testLockerName))
+	a.NoError(lk.Close())
+	a.Error(mustDtor(testLockerName))
+}
+
+func testLockerExists(t *testing.T, ctor lockerCtor, dtor lockerDtor, exists func(string) (bool, error)) {
+	a := assert.New(t)
+	if !a.NoError(dtor(testLockerName)) {
+		return
+	}
+	ok, err := exists(testLockerName)
+	a.NoError(err)
+	a.False(ok)
+	lk, err := ctor(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(lk.Close())
+		a.NoError(dtor(testLockerName))
+	}()
+	ok, err = exists(testLockerName)
+	a.NoError(err)
+	a.True(ok)
+}
+
+func testLockerLogger(t *testing.T, ctor lockerCtor, dtor lockerDtor) {
+	a := assert.New(t)
+	if dtor != nil {
+		if !a.NoError(dtor(testLockerName)) {
+			return
+		}
+	}
+	lk, err := ctor(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) || !a.NotNil(lk) {
+		return
+	}
+	defer func(lk IPCLocker) {
+		if d, ok := lk.(common.Destroyer); ok {
+			a.NoError(d.Destroy())
+		} else {
+			a.NoError(lk.Close())
+		}
+	}(lk)
+	type event struct {
+		name   string
+		fields map[string]interface{}
+	}
+	var events []event
+	common.SetLogger(func(name string, fields map[string]interface{}) {
+		events = append(events, event{name: name, fields: fields})
+	})
+	lk.Lock()
+	lk.Unlock()
+	common.SetLogger(nil)
+	lk.Lock()
+	lk.Unlock()
+	if !a.Len(events, 2) {
+		return
+	}
+	a.Equal("mutex_lock", events[0].name)
+	a.Equal("mutex_unlock", events[1].name)
+	for _, ev := range events {
+		a.Equal(testLockerName, ev.fields["name"])
+		a.Equal(os.Getpid(), ev.fields["pid"])
+	}
+}
+
+func testLockerForceReset(t *testing.T, ctor lockerCtor, dtor lockerDtor) {
+	a := assert.New(t)
+	if dtor != nil {
+		if !a.NoError(dtor(testLockerName)) {
+			return
+		}
+	}
+	lk, err := ctor(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) || !a.NotNil(lk) {
+		return
+	}
+	defer func(lk IPCLocker) {
+		if d, ok := lk.(common.Destroyer); ok {
+			a.NoError(d.Destroy())
+		} else {
+			a.NoError(lk.Close())
+		}
+	}(lk)
+	// simulate a crash of the owner: lock the mutex and never unlock it.
+	lk.Lock()
+	waiter, err := ctor(testLockerName, 0, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer waiter.Close()
+	locked := make(chan struct{})
+	go func() {
+		waiter.Lock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Error("the mutex was locked twice")
+		return
+	case <-time.After(time.Millisecond * 100):
+	}
+	recoverer, err := ctor(testLockerName, 0, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer recoverer.Close()
+	resetter, ok := recoverer.(ResettableLocker)
+	if !a.True(ok) {
+		return
+	}
+	if !a.NoError(resetter.ForceReset()) {
+		return
+	}
+	select {
+	case <-locked:
+	case <-time.After(time.Second * 5):
+		t.Error("the waiter was not woken after the reset")
+		return
+	}
+	waiter.Unlock()
+	recoverer.Lock()
+	recoverer.Unlock()
+}
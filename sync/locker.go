@@ -0,0 +1,49 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// lockerAdapter is a sync.Locker, which routes errors of an IPCLocker to a callback.
+type lockerAdapter struct {
+	l     IPCLocker
+	onErr func(error)
+}
+
+// AsLocker returns a sync.Locker, which calls l's Lock and Unlock methods,
+// so that l can be used with standard library types, like sync.Cond.
+// IPCLocker already satisfies sync.Locker, but its implementations report errors by panicking.
+// The adapter recovers such panics and passes them to onErr as errors.
+// If onErr is nil, the panic is propagated.
+func AsLocker(l IPCLocker, onErr func(error)) sync.Locker {
+	return &lockerAdapter{l: l, onErr: onErr}
+}
+
+// Lock locks the underlying locker.
+func (la *lockerAdapter) Lock() {
+	defer la.handlePanic()
+	la.l.Lock()
+}
+
+// Unlock unlocks the underlying locker.
+func (la *lockerAdapter) Unlock() {
+	defer la.handlePanic()
+	la.l.Unlock()
+}
+
+func (la *lockerAdapter) handlePanic() {
+	if la.onErr == nil {
+		return
+	}
+	if r := recover(); r != nil {
+		err, ok := r.(error)
+		if !ok {
+			err = errors.Errorf("%v", r)
+		}
+		la.onErr(err)
+	}
+}
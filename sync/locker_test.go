@@ -0,0 +1,70 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsLockerCond(t *testing.T) {
+	const count = 10
+	a := assert.New(t)
+	if !a.NoError(DestroyMutex(testLockerName)) {
+		return
+	}
+	m, err := NewMutex(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer DestroyMutex(testLockerName)
+	defer m.Close()
+	cond := sync.NewCond(AsLocker(m, nil))
+	var queue []int
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < count; i++ {
+			cond.L.Lock()
+			for len(queue) == 0 {
+				cond.Wait()
+			}
+			a.Equal(i, queue[0])
+			queue = queue[1:]
+			cond.L.Unlock()
+		}
+	}()
+	for i := 0; i < count; i++ {
+		cond.L.Lock()
+		queue = append(queue, i)
+		cond.Signal()
+		cond.L.Unlock()
+	}
+	<-done
+}
+
+func TestAsLockerOnErr(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroySpinMutex(testLockerName)) {
+		return
+	}
+	m, err := NewSpinMutex(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m.Destroy()
+	var errs []error
+	l := AsLocker(m, func(err error) {
+		errs = append(errs, err)
+	})
+	l.Lock()
+	l.Unlock()
+	a.Empty(errs)
+	// unlock of an unlocked mutex is reported, instead of panicking.
+	l.Unlock()
+	a.Len(errs, 1)
+	a.Panics(func() { AsLocker(m, nil).Unlock() })
+}
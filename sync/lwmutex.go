@@ -3,6 +3,9 @@
 package sync
 
 import (
+	"context"
+	"math"
+	"os"
 	"sync/atomic"
 	"time"
 	"unsafe"
@@ -14,6 +17,7 @@ const (
 	lwmStateSize = 4
 
 	lwmSpinCount         = 100
+	lwmCancelInterval    = 10 * time.Millisecond
 	lwmUnlocked          = int32(0)
 	lwmLockedNoWaiters   = int32(1)
 	lwmLockedHaveWaiters = int32(2)
@@ -25,10 +29,22 @@ const (
 type lwMutex struct {
 	state *int32
 	ww    waitWaker
+	name  string
+	// spins is the number of attempts to lock the mutex before going to sleep.
+	// it is accessed atomically.
+	spins int32
 }
 
-func newLightweightMutex(state unsafe.Pointer, ww waitWaker) *lwMutex {
-	return &lwMutex{state: (*int32)(state), ww: ww}
+// newLightweightMutex returns a mutex operating on the given memory location.
+// name is used for diagnostic events only.
+func newLightweightMutex(name string, state unsafe.Pointer, ww waitWaker) *lwMutex {
+	return &lwMutex{state: (*int32)(state), ww: ww, name: name, spins: lwmSpinCount}
+}
+
+// setSpinCount sets the number of attempts to lock the mutex before waiting on the waitWaker.
+// negative values are treated as zero.
+func (lwm *lwMutex) setSpinCount(n int) {
+	atomic.StoreInt32(&lwm.spins, spinCount(n))
 }
 
 // init writes initial value into mutex's memory location.
@@ -37,29 +53,65 @@ func (lwm *lwMutex) init() {
 }
 
 func (lwm *lwMutex) lock() {
-	if err := lwm.doLock(-1); err != nil {
+	if err := lwm.doLock(-1, nil); err != nil {
 		panic(err)
 	}
+	lwm.logEvent("mutex_lock")
 }
 
 func (lwm *lwMutex) tryLock() bool {
+	if !lwm.cas() {
+		return false
+	}
+	lwm.logEvent("mutex_lock")
+	return true
+}
+
+func (lwm *lwMutex) cas() bool {
 	return atomic.CompareAndSwapInt32(lwm.state, lwmUnlocked, lwmLockedNoWaiters)
 }
 
-func (lwm *lwMutex) lockTimeout(timeout time.Duration) bool {
-	err := lwm.doLock(timeout)
-	if err == nil {
-		return true
+// lockTimeout tries to lock the mutex for not longer, than timeout.
+// It returns false and no error, if the mutex was not locked in time.
+func (lwm *lwMutex) lockTimeout(timeout time.Duration) (bool, error) {
+	if err := lwm.doLock(timeout, nil); err != nil {
+		if common.IsTimeoutErr(err) {
+			return false, nil
+		}
+		return false, err
 	}
-	if common.IsTimeoutErr(err) {
-		return false
+	lwm.logEvent("mutex_lock")
+	return true, nil
+}
+
+// lockContext tries to lock the mutex, until it succeeds, or the context is done.
+func (lwm *lwMutex) lockContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	timeout := time.Duration(-1)
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = deadline.Sub(time.Now())
 	}
-	panic(err)
+	if err := lwm.doLock(timeout, ctx.Done()); err != nil {
+		if !common.IsTimeoutErr(err) {
+			return err
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		return context.DeadlineExceeded
+	}
+	lwm.logEvent("mutex_lock")
+	return nil
 }
 
-func (lwm *lwMutex) doLock(timeout time.Duration) error {
-	for i := 0; i < lwmSpinCount; i++ {
-		if lwm.tryLock() {
+// doLock locks the mutex, waiting for not longer, than timeout.
+// If done is not nil, the wait is interrupted, when it is closed.
+// It returns a timeout error, if the mutex was not locked in time, or if the wait was interrupted.
+func (lwm *lwMutex) doLock(timeout time.Duration, done <-chan struct{}) error {
+	for i, spins := int32(0), atomic.LoadInt32(&lwm.spins); i < spins; i++ {
+		if lwm.cas() {
 			return nil
 		}
 	}
@@ -67,16 +119,44 @@ func (lwm *lwMutex) doLock(timeout time.Duration) error {
 	if old != lwmLockedHaveWaiters {
 		old = atomic.SwapInt32(lwm.state, lwmLockedHaveWaiters)
 	}
+	var deadline time.Time
+	if timeout >= 0 {
+		deadline = time.Now().Add(timeout)
+	}
 	for old != lwmUnlocked {
-		if err := lwm.ww.wait(lwmLockedHaveWaiters, timeout); err != nil {
-			return err
+		// waiters can't be interrupted, so, if there is a done channel,
+		// wait for short intervals and check the channel between them.
+		waitTimeout := timeout
+		if done != nil && (waitTimeout < 0 || waitTimeout > lwmCancelInterval) {
+			waitTimeout = lwmCancelInterval
+		}
+		if err := lwm.ww.wait(lwmLockedHaveWaiters, waitTimeout); err != nil {
+			if waitTimeout == timeout || !common.IsTimeoutErr(err) {
+				return err
+			}
 		}
 		old = atomic.SwapInt32(lwm.state, lwmLockedHaveWaiters)
+		if old == lwmUnlocked {
+			break
+		}
+		select {
+		case <-done:
+			return common.NewTimeoutError("LOCK")
+		default:
+		}
+		// a waiter can return before the timeout expires, either because of a wake up,
+		// or because it does not wait at all, so the remaining time must be checked here.
+		if timeout >= 0 {
+			if timeout = deadline.Sub(time.Now()); timeout <= 0 {
+				return common.NewTimeoutError("LOCK")
+			}
+		}
 	}
 	return nil
 }
 
 func (lwm *lwMutex) unlock() {
+	lwm.logEvent("mutex_unlock")
 	if old := atomic.LoadInt32(lwm.state); old == lwmLockedHaveWaiters {
 		*lwm.state = lwmUnlocked
 	} else {
@@ -96,3 +176,40 @@ func (lwm *lwMutex) unlock() {
 	}
 	lwm.ww.wake(1)
 }
+
+// forceReset unlocks the mutex regardless of its owner.
+// if there can be waiters, one of them is woken to take the mutex.
+func (lwm *lwMutex) forceReset() error {
+	if atomic.SwapInt32(lwm.state, lwmUnlocked) == lwmLockedHaveWaiters {
+		if _, err := lwm.ww.wake(1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mustLock returns the result of a timed lock operation. It panics on an error.
+func mustLock(locked bool, err error) bool {
+	if err != nil {
+		panic(err)
+	}
+	return locked
+}
+
+// spinCount converts a user-supplied spin count into a value stored by the mutexes.
+func spinCount(n int) int32 {
+	if n < 0 {
+		return 0
+	}
+	if n > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int32(n)
+}
+
+// logEvent passes a mutex event to the logger, if it is installed.
+func (lwm *lwMutex) logEvent(event string) {
+	if logger := common.CurrentLogger(); logger != nil {
+		logger(event, map[string]interface{}{"name": lwm.name, "pid": os.Getpid()})
+	}
+}
@@ -54,6 +54,9 @@ type lwRWMutex struct {
 	rWaiter waitWaker
 	wWaiter waitWaker
 	state   *int64
+	// spins is the number of attempts to lock the mutex before going to sleep.
+	// it is zero by default and is accessed atomically.
+	spins int32
 }
 
 func newRWLightweightMutex(state unsafe.Pointer, rWaiter, wWaiter waitWaker) *lwRWMutex {
@@ -65,7 +68,26 @@ func (lwrw *lwRWMutex) init() {
 	*lwrw.state = 0
 }
 
+// setSpinCount sets the number of attempts to lock the mutex before waiting on the waitWakers.
+// negative values are treated as zero.
+func (lwrw *lwRWMutex) setSpinCount(n int) {
+	atomic.StoreInt32(&lwrw.spins, spinCount(n))
+}
+
+// spin makes up to 'spins' attempts to lock the mutex with tryLock.
+func (lwrw *lwRWMutex) spin(tryLock func() bool) bool {
+	for i, spins := int32(0), atomic.LoadInt32(&lwrw.spins); i < spins; i++ {
+		if tryLock() {
+			return true
+		}
+	}
+	return false
+}
+
 func (lwrw *lwRWMutex) lock() {
+	if lwrw.spin(lwrw.tryLock) {
+		return
+	}
 	new := (lwRWState)(atomic.AddInt64(lwrw.state, 1<<lwRWMWriterShift))
 	if new.readers() > 0 || new.writers() > 1 {
 		if err := lwrw.wWaiter.wait(0, -1); err != nil {
@@ -75,6 +97,9 @@ func (lwrw *lwRWMutex) lock() {
 }
 
 func (lwrw *lwRWMutex) rlock() {
+	if lwrw.spin(lwrw.tryRLock) {
+		return
+	}
 	var new lwRWState
 	for {
 		old := (lwRWState)(atomic.LoadInt64(lwrw.state))
@@ -105,6 +130,59 @@ func (lwrw *lwRWMutex) runlock() {
 	}
 }
 
+// snapshot atomically reads the shared state.
+func (lwrw *lwRWMutex) snapshot() lwRWState {
+	return (lwRWState)(atomic.LoadInt64(lwrw.state))
+}
+
+// tryLock locks the mutex exclusively, if it is not locked.
+// If it fails, the state is not changed.
+func (lwrw *lwRWMutex) tryLock() bool {
+	for {
+		old := (lwRWState)(atomic.LoadInt64(lwrw.state))
+		if old.readers() > 0 || old.writers() > 0 {
+			return false
+		}
+		new := old
+		new.addWriters(1)
+		if atomic.CompareAndSwapInt64(lwrw.state, (int64)(old), (int64)(new)) {
+			return true
+		}
+	}
+}
+
+// tryRLock locks the mutex for reading, if there are no writers.
+// If it fails, the state is not changed.
+func (lwrw *lwRWMutex) tryRLock() bool {
+	for {
+		old := (lwRWState)(atomic.LoadInt64(lwrw.state))
+		if old.writers() > 0 {
+			return false
+		}
+		new := old
+		new.addReaders(1)
+		if atomic.CompareAndSwapInt64(lwrw.state, (int64)(old), (int64)(new)) {
+			return true
+		}
+	}
+}
+
+// tryUpgrade converts a read lock into a write lock, if the caller is the only reader.
+func (lwrw *lwRWMutex) tryUpgrade() bool {
+	for {
+		old := (lwRWState)(atomic.LoadInt64(lwrw.state))
+		if old.readers() != 1 {
+			return false
+		}
+		new := old
+		new.addReaders(-1)
+		new.addWriters(1)
+		if atomic.CompareAndSwapInt64(lwrw.state, (int64)(old), (int64)(new)) {
+			return true
+		}
+	}
+}
+
 func (lwrw *lwRWMutex) unlock() {
 	var new lwRWState
 	for {
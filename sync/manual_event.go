@@ -0,0 +1,72 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"os"
+	"time"
+)
+
+// ManualEvent is a synchronization primitive used for notification.
+// Unlike Event, it stays in the signaled state after Set() until Reset() is called,
+// so that all the current and future waiters are released.
+type ManualEvent manualEvent
+
+// NewManualEvent creates a new interprocess manual-reset event.
+// It uses futexes on linux and freebsd, and a mutex with a condvar on other platforms.
+//	name - object name.
+//	flag - flag is a combination of open flags from 'os' package.
+//	perm - object's permission bits.
+//	initial - if true, the event will be set after creation.
+func NewManualEvent(name string, flag int, perm os.FileMode, initial bool) (*ManualEvent, error) {
+	e, err := newManualEvent(name, flag, perm, initial)
+	if err != nil {
+		return nil, err
+	}
+	return (*ManualEvent)(e), nil
+}
+
+// Set sets the event to the signaled state and wakes all the waiters.
+func (e *ManualEvent) Set() {
+	(*manualEvent)(e).set()
+}
+
+// Reset sets the event to the non-signaled state.
+func (e *ManualEvent) Reset() {
+	(*manualEvent)(e).reset()
+}
+
+// IsSet returns true, if the event is in the signaled state.
+func (e *ManualEvent) IsSet() bool {
+	return (*manualEvent)(e).isSet()
+}
+
+// Wait waits for the event to be signaled. It returns immediately, if the event is already set.
+func (e *ManualEvent) Wait() {
+	(*manualEvent)(e).waitTimeout(-1)
+}
+
+// WaitTimeout waits until the event is signaled or the timeout elapses.
+// Passing negative value as a timeout makes the timeout infinite.
+func (e *ManualEvent) WaitTimeout(timeout time.Duration) bool {
+	return (*manualEvent)(e).waitTimeout(timeout)
+}
+
+// Close closes the event.
+func (e *ManualEvent) Close() error {
+	return (*manualEvent)(e).close()
+}
+
+// Destroy permanently destroys the event.
+func (e *ManualEvent) Destroy() error {
+	return (*manualEvent)(e).destroy()
+}
+
+// DestroyManualEvent permanently destroys a manual-reset event with the given name.
+func DestroyManualEvent(name string) error {
+	return destroyManualEvent(name)
+}
+
+func manualEventName(baseName string) string {
+	return baseName + ".mev"
+}
@@ -0,0 +1,145 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build darwin windows
+
+package sync
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/helper"
+	"bitbucket.org/avd/go-ipc/mmf"
+	"bitbucket.org/avd/go-ipc/shm"
+	"github.com/pkg/errors"
+)
+
+// manualEvent keeps its state in shared memory: 1 means, that the event is set.
+// The state is changed under the mutex, and the waiters are woken with the condvar.
+type manualEvent struct {
+	name   string
+	region *mmf.MemoryRegion
+	state  *int32
+	m      IPCLocker
+	cond   *Cond
+}
+
+func newManualEvent(name string, flag int, perm os.FileMode, initial bool) (*manualEvent, error) {
+	if err := ensureOpenFlags(flag); err != nil {
+		return nil, err
+	}
+	region, created, err := helper.CreateWritableRegion(manualEventName(name), flag, perm, lweStateSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create shared state")
+	}
+	result := &manualEvent{
+		name:   name,
+		region: region,
+		state:  (*int32)(allocator.ByteSliceData(region.Data())),
+	}
+	if result.m, err = NewMutex(manualEventMutexName(name), flag, perm); err != nil {
+		err = errors.Wrap(err, "failed to create a mutex")
+	} else if result.cond, err = NewCond(manualEventCondName(name), flag, perm, result.m); err != nil {
+		result.m.Close()
+		err = errors.Wrap(err, "failed to create a cond")
+	}
+	if err != nil {
+		region.Close()
+		if created {
+			destroyManualEvent(name)
+		}
+		return nil, err
+	}
+	if created && initial {
+		atomic.StoreInt32(result.state, 1)
+	}
+	return result, nil
+}
+
+func (e *manualEvent) set() {
+	e.m.Lock()
+	atomic.StoreInt32(e.state, 1)
+	e.cond.Broadcast()
+	e.m.Unlock()
+}
+
+func (e *manualEvent) reset() {
+	e.m.Lock()
+	atomic.StoreInt32(e.state, 0)
+	e.m.Unlock()
+}
+
+func (e *manualEvent) isSet() bool {
+	return atomic.LoadInt32(e.state) == 1
+}
+
+func (e *manualEvent) waitTimeout(timeout time.Duration) bool {
+	if e.isSet() {
+		return true
+	}
+	var deadline time.Time
+	if timeout >= 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	e.m.Lock()
+	defer e.m.Unlock()
+	for !e.isSet() {
+		if timeout < 0 {
+			e.cond.Wait()
+			continue
+		}
+		left := deadline.Sub(time.Now())
+		if left <= 0 {
+			return false
+		}
+		e.cond.WaitTimeout(left)
+	}
+	return true
+}
+
+func (e *manualEvent) close() error {
+	errCond := e.cond.Close()
+	errMutex := e.m.Close()
+	if err := e.region.Close(); err != nil {
+		return errors.Wrap(err, "failed to close shm region")
+	}
+	if errMutex != nil {
+		return errors.Wrap(errMutex, "failed to close the mutex")
+	}
+	if errCond != nil {
+		return errors.Wrap(errCond, "failed to close the cond")
+	}
+	return nil
+}
+
+func (e *manualEvent) destroy() error {
+	if err := e.close(); err != nil {
+		return errors.Wrap(err, "failed to close the event")
+	}
+	return destroyManualEvent(e.name)
+}
+
+func destroyManualEvent(name string) error {
+	errCond := DestroyCond(manualEventCondName(name))
+	errMutex := DestroyMutex(manualEventMutexName(name))
+	if err := shm.DestroyMemoryObject(manualEventName(name)); err != nil {
+		return errors.Wrap(err, "failed to destroy memory object")
+	}
+	if errMutex != nil {
+		return errors.Wrap(errMutex, "failed to destroy the mutex")
+	}
+	if errCond != nil {
+		return errors.Wrap(errCond, "failed to destroy the cond")
+	}
+	return nil
+}
+
+func manualEventMutexName(baseName string) string {
+	return baseName + ".mevm"
+}
+
+func manualEventCondName(baseName string) string {
+	return baseName + ".mevc"
+}
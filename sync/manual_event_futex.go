@@ -0,0 +1,100 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build freebsd linux
+
+package sync
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/common"
+	"github.com/nxgtw/go-ipc/internal/helper"
+	"bitbucket.org/avd/go-ipc/mmf"
+	"bitbucket.org/avd/go-ipc/shm"
+	"github.com/pkg/errors"
+)
+
+// manualEvent keeps its state in a futex word: 1 means, that the event is set.
+type manualEvent struct {
+	name   string
+	region *mmf.MemoryRegion
+	state  *int32
+	f      *futex
+}
+
+func newManualEvent(name string, flag int, perm os.FileMode, initial bool) (*manualEvent, error) {
+	if err := ensureOpenFlags(flag); err != nil {
+		return nil, err
+	}
+	region, created, err := helper.CreateWritableRegion(manualEventName(name), flag, perm, lweStateSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create shared state")
+	}
+	state := allocator.ByteSliceData(region.Data())
+	result := &manualEvent{
+		name:   name,
+		region: region,
+		state:  (*int32)(state),
+		f:      &futex{ptr: state},
+	}
+	if created && initial {
+		atomic.StoreInt32(result.state, 1)
+	}
+	return result, nil
+}
+
+func (e *manualEvent) set() {
+	if atomic.SwapInt32(e.state, 1) == 1 {
+		return
+	}
+	if _, err := e.f.wakeAll(); err != nil {
+		panic(err)
+	}
+}
+
+func (e *manualEvent) reset() {
+	atomic.StoreInt32(e.state, 0)
+}
+
+func (e *manualEvent) isSet() bool {
+	return atomic.LoadInt32(e.state) == 1
+}
+
+func (e *manualEvent) waitTimeout(timeout time.Duration) bool {
+	var deadline time.Time
+	if timeout >= 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	for !e.isSet() {
+		if timeout >= 0 {
+			if timeout = deadline.Sub(time.Now()); timeout <= 0 {
+				return false
+			}
+		}
+		if err := e.f.wait(0, timeout); err != nil && !common.IsTimeoutErr(err) {
+			panic(err)
+		}
+	}
+	return true
+}
+
+func (e *manualEvent) close() error {
+	return e.region.Close()
+}
+
+func (e *manualEvent) destroy() error {
+	if err := e.close(); err != nil {
+		return errors.Wrap(err, "failed to close shm region")
+	}
+	return destroyManualEvent(e.name)
+}
+
+func destroyManualEvent(name string) error {
+	if err := shm.DestroyMemoryObject(manualEventName(name)); err != nil {
+		return errors.Wrap(err, "failed to destroy memory object")
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testManualEventName = "go-ipc.test-mev"
+)
+
+func TestManualEventOpenMode(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyManualEvent(testManualEventName)) {
+		return
+	}
+	_, err := NewManualEvent(testManualEventName, 0, 0666, false)
+	a.Error(err)
+	ev, err := NewManualEvent(testManualEventName, os.O_CREATE|os.O_EXCL, 0666, true)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(ev.Destroy())
+	}()
+	_, err = NewManualEvent(testManualEventName, os.O_CREATE|os.O_EXCL, 0666, false)
+	a.Error(err)
+	ev2, err := NewManualEvent(testManualEventName, os.O_CREATE, 0666, false)
+	if !a.NoError(err) {
+		return
+	}
+	// the initial value is used only on creation.
+	a.True(ev2.IsSet())
+	a.NoError(ev2.Close())
+}
+
+func TestManualEventSetReset(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyManualEvent(testManualEventName)) {
+		return
+	}
+	ev, err := NewManualEvent(testManualEventName, os.O_CREATE|os.O_EXCL, 0666, false)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(ev.Destroy())
+	}()
+	a.False(ev.IsSet())
+	a.False(ev.WaitTimeout(time.Millisecond * 50))
+	ev.Set()
+	a.True(ev.IsSet())
+	// the event must stay set after waits.
+	a.True(ev.WaitTimeout(0))
+	a.True(ev.WaitTimeout(time.Millisecond * 50))
+	ev.Wait()
+	ev.Reset()
+	a.False(ev.IsSet())
+	a.False(ev.WaitTimeout(0))
+}
+
+func TestManualEventWakeAll(t *testing.T) {
+	const waiters = 8
+	a := assert.New(t)
+	if !a.NoError(DestroyManualEvent(testManualEventName)) {
+		return
+	}
+	ev, err := NewManualEvent(testManualEventName, os.O_CREATE|os.O_EXCL, 0666, false)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(ev.Destroy())
+	}()
+	ch := make(chan bool, waiters)
+	for i := 0; i < waiters; i++ {
+		go func() {
+			ch <- ev.WaitTimeout(time.Second * 3)
+		}()
+	}
+	time.Sleep(time.Millisecond * 50)
+	ev.Set()
+	for i := 0; i < waiters; i++ {
+		select {
+		case ok := <-ch:
+			a.True(ok)
+		case <-time.After(time.Second * 5):
+			t.Errorf("timeout")
+			return
+		}
+	}
+}
@@ -3,10 +3,15 @@
 package sync
 
 import (
+	"context"
 	"io"
 	"os"
 	"sync"
 	"time"
+
+	"github.com/nxgtw/go-ipc/internal/common"
+
+	"github.com/pkg/errors"
 )
 
 // IPCLocker is a minimal interface, which must be satisfied by any synchronization primitive on any platform.
@@ -22,20 +27,72 @@ type TimedIPCLocker interface {
 	LockTimeout(timeout time.Duration) bool
 }
 
+// ContextLocker is a locker, whose lock operation can be cancelled with a context.
+type ContextLocker interface {
+	IPCLocker
+	// LockContext tries to lock the locker, until it succeeds, or the context is done.
+	// It returns ctx.Err(), if the locker was not locked.
+	LockContext(ctx context.Context) error
+}
+
+// ResettableLocker is a locker, whose shared state can be reset, if its owner has died without unlocking it.
+type ResettableLocker interface {
+	IPCLocker
+	// ForceReset forcibly returns the shared state of the locker to unlocked, regardless of its owner.
+	// It is intended for recovery, when the owner of the locker is known to have died without unlocking it.
+	// One of the waiters, if any, is woken to take the locker.
+	// Warning: if the locker is held by a live owner, it will be unlocked behind its back,
+	// and several owners will access the protected data simultaneously, causing data races.
+	// The owner's subsequent Unlock may also panic or release the locker held by someone else.
+	ForceReset() error
+}
+
 // NewMutex creates a new interprocess mutex.
 // It uses the default implementation on the current platform.
 //	name - object name.
 //	flag - flag is a combination of open flags from 'os' package.
 //	perm - object's permission bits.
 func NewMutex(name string, flag int, perm os.FileMode) (TimedIPCLocker, error) {
-	return newMutex(name, flag, perm)
+	m, err := newMutex(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	common.AutoRegisterForCleanup(m)
+	return m, nil
 }
 
 // DestroyMutex permanently removes mutex with the given name.
+// It returns nil, if the mutex does not exist.
 func DestroyMutex(name string) error {
 	return destroyMutex(name)
 }
 
+// MutexExists returns true, if a mutex with the given name exists.
+// It returns false and an error, if the mutex can't be opened
+// for any reason other, than its absence, for example, because of the permissions.
+func MutexExists(name string) (bool, error) {
+	m, err := NewMutex(name, 0, 0666)
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, m.Close()
+}
+
+// MustDestroyMutex permanently removes mutex with the given name.
+// Unlike DestroyMutex, it returns an error, if the mutex does not exist.
+// In this case os.IsNotExist(errors.Cause(err)) is true.
+func MustDestroyMutex(name string) error {
+	m, err := NewMutex(name, 0, 0666)
+	if err != nil {
+		return errors.Wrap(err, "failed to open the mutex")
+	}
+	m.Close()
+	return DestroyMutex(name)
+}
+
 func mutexSharedStateName(name, typ string) string {
 	return name + ".s" + typ
 }
@@ -3,6 +3,7 @@
 package sync
 
 import (
+	"context"
 	"os"
 	"time"
 
@@ -57,7 +58,7 @@ func NewEventMutex(name string, flag int, perm os.FileMode) (*EventMutex, error)
 		handle: handle,
 		state:  region,
 		name:   name,
-		lwm:    newLightweightMutex(allocator.ByteSliceData(region.Data()), &eventWaiter{handle: handle}),
+		lwm:    newLightweightMutex(name, allocator.ByteSliceData(region.Data()), &eventWaiter{handle: handle}),
 	}
 	if created {
 		result.lwm.init()
@@ -76,8 +77,23 @@ func (m *EventMutex) TryLock() bool {
 }
 
 // LockTimeout tries to lock the locker, waiting for not more, than timeout.
+// It panics on an error other, than a timeout.
 func (m *EventMutex) LockTimeout(timeout time.Duration) bool {
-	return m.lwm.lockTimeout(timeout)
+	return mustLock(m.lwm.lockTimeout(timeout))
+}
+
+// LockContext tries to lock the mutex, until it succeeds, or the context is done.
+// It returns ctx.Err(), if the mutex was not locked.
+func (m *EventMutex) LockContext(ctx context.Context) error {
+	return m.lwm.lockContext(ctx)
+}
+
+// SetSpinCount sets the number of attempts to lock the mutex, which are made before waiting on an event.
+// Larger values reduce latency on lightly contended mutexes, smaller ones save CPU time.
+// The default value is 100. Negative values are treated as zero.
+// It affects only this instance of the mutex.
+func (m *EventMutex) SetSpinCount(n int) {
+	m.lwm.setSpinCount(n)
 }
 
 // Unlock releases the mutex. It panics on an error.
@@ -85,8 +101,14 @@ func (m *EventMutex) Unlock() {
 	m.lwm.unlock()
 }
 
+// ForceReset forcibly unlocks the mutex regardless of its owner. See ResettableLocker for details.
+func (m *EventMutex) ForceReset() error {
+	return m.lwm.forceReset()
+}
+
 // Close closes event's handle.
 func (m *EventMutex) Close() error {
+	common.UnregisterFromCleanup(m)
 	m.state.Close()
 	return windows.CloseHandle(m.handle)
 }
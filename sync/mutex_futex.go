@@ -5,10 +5,12 @@
 package sync
 
 import (
+	"context"
 	"os"
 	"time"
 
 	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/common"
 	"github.com/nxgtw/go-ipc/internal/helper"
 	"bitbucket.org/avd/go-ipc/mmf"
 	"bitbucket.org/avd/go-ipc/shm"
@@ -47,7 +49,7 @@ func NewFutexMutex(name string, flag int, perm os.FileMode) (*FutexMutex, error)
 	result := &FutexMutex{
 		region: region,
 		name:   name,
-		lwm:    newLightweightMutex(data, &futex{ptr: data}),
+		lwm:    newLightweightMutex(name, data, &futex{ptr: data}),
 	}
 	if created {
 		result.lwm.init()
@@ -66,8 +68,23 @@ func (f *FutexMutex) TryLock() bool {
 }
 
 // LockTimeout tries to lock the locker, waiting for not more, than timeout.
+// It panics on an error other, than a timeout.
 func (f *FutexMutex) LockTimeout(timeout time.Duration) bool {
-	return f.lwm.lockTimeout(timeout)
+	return mustLock(f.lwm.lockTimeout(timeout))
+}
+
+// LockContext tries to lock the mutex, until it succeeds, or the context is done.
+// It returns ctx.Err(), if the mutex was not locked.
+func (f *FutexMutex) LockContext(ctx context.Context) error {
+	return f.lwm.lockContext(ctx)
+}
+
+// SetSpinCount sets the number of attempts to lock the mutex, which are made before waiting on a futex.
+// Larger values reduce latency on lightly contended mutexes, smaller ones save CPU time.
+// The default value is 100. Negative values are treated as zero.
+// It affects only this instance of the mutex.
+func (f *FutexMutex) SetSpinCount(n int) {
+	f.lwm.setSpinCount(n)
 }
 
 // Unlock releases the mutex. It panics on an error, or if the mutex is not locked.
@@ -75,9 +92,15 @@ func (f *FutexMutex) Unlock() {
 	f.lwm.unlock()
 }
 
+// ForceReset forcibly unlocks the mutex regardless of its owner. See ResettableLocker for details.
+func (f *FutexMutex) ForceReset() error {
+	return f.lwm.forceReset()
+}
+
 // Close indicates, that the object is no longer in use,
 // and that the underlying resources can be freed.
 func (f *FutexMutex) Close() error {
+	common.UnregisterFromCleanup(f)
 	return f.region.Close()
 }
 
@@ -11,6 +11,22 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func TestFutexMutexLogger(t *testing.T) {
+	testLockerLogger(t, func(name string, mode int, perm os.FileMode) (IPCLocker, error) {
+		return NewFutexMutex(name, mode, perm)
+	}, DestroyFutexMutex)
+}
+
+func TestFutexMutexSpinCount(t *testing.T) {
+	testLockerLock(t, func(name string, mode int, perm os.FileMode) (IPCLocker, error) {
+		m, err := NewFutexMutex(name, mode, perm)
+		if err == nil {
+			m.SetSpinCount(0)
+		}
+		return m, err
+	}, DestroyFutexMutex)
+}
+
 func BenchmarkFutexMutex(b *testing.B) {
 	benchmarkLocker(b, func(name string, mode int, perm os.FileMode) (IPCLocker, error) {
 		return NewFutexMutex(name, mode, perm)
@@ -8,7 +8,9 @@ import "os"
 
 // this is to ensure, that all implementations of ipc mutex satisfy the same minimal interface.
 var (
-	_ TimedIPCLocker = (*FutexMutex)(nil)
+	_ TimedIPCLocker   = (*FutexMutex)(nil)
+	_ ContextLocker    = (*FutexMutex)(nil)
+	_ ResettableLocker = (*FutexMutex)(nil)
 )
 
 func newMutex(name string, flag int, perm os.FileMode) (TimedIPCLocker, error) {
@@ -9,7 +9,9 @@ import "os"
 // this is to ensure, that all implementations of ipc mutex
 // satisfy the same minimal interface
 var (
-	_ TimedIPCLocker = (*SemaMutex)(nil)
+	_ TimedIPCLocker   = (*SemaMutex)(nil)
+	_ ContextLocker    = (*SemaMutex)(nil)
+	_ ResettableLocker = (*SemaMutex)(nil)
 )
 
 func newMutex(name string, flag int, perm os.FileMode) (TimedIPCLocker, error) {
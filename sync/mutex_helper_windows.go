@@ -6,7 +6,9 @@ import "os"
 
 // this is to ensure, that all implementations of ipc mutex satisfy the same minimal interface.
 var (
-	_ TimedIPCLocker = (*EventMutex)(nil)
+	_ TimedIPCLocker   = (*EventMutex)(nil)
+	_ ContextLocker    = (*EventMutex)(nil)
+	_ ResettableLocker = (*EventMutex)(nil)
 )
 
 func newMutex(name string, flag int, perm os.FileMode) (TimedIPCLocker, error) {
@@ -0,0 +1,30 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build linux,!sysv_mutex_linux
+
+package sync
+
+import (
+	"strings"
+
+	"bitbucket.org/avd/go-ipc/shm"
+)
+
+// ListMutexes returns the names of all the mutexes created by NewMutex.
+// It is linux-specific and works with the default futex-based implementation only,
+// so it is not available, if the package is built with the 'sysv_mutex_linux' tag.
+// Mutexes used internally by other primitives of this package are listed too.
+func ListMutexes() ([]string, error) {
+	objects, err := shm.ListMemoryObjects()
+	if err != nil {
+		return nil, err
+	}
+	suffix := mutexSharedStateName("", "f")
+	var result []string
+	for _, name := range objects {
+		if strings.HasSuffix(name, suffix) {
+			result = append(result, strings.TrimSuffix(name, suffix))
+		}
+	}
+	return result, nil
+}
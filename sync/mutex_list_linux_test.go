@@ -0,0 +1,34 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build linux,!sysv_mutex_linux
+
+package sync
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListMutexes(t *testing.T) {
+	a := assert.New(t)
+	names := []string{testLockerName + ".l1", testLockerName + ".l2"}
+	for _, name := range names {
+		m, err := NewMutex(name, os.O_CREATE, 0666)
+		if !a.NoError(err) {
+			return
+		}
+		a.NoError(m.Close())
+		defer func(name string) {
+			a.NoError(DestroyMutex(name))
+		}(name)
+	}
+	list, err := ListMutexes()
+	if !a.NoError(err) {
+		return
+	}
+	for _, name := range names {
+		a.Contains(list, name)
+	}
+}
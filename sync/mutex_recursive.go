@@ -0,0 +1,149 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"os"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/helper"
+	"bitbucket.org/avd/go-ipc/mmf"
+	"bitbucket.org/avd/go-ipc/shm"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	recursiveMutexStateSize = int(unsafe.Sizeof(recursiveMutexState{}))
+)
+
+// all implementations must satisfy IPCLocker interface.
+var (
+	_ IPCLocker = (*RecursiveMutex)(nil)
+)
+
+// recursiveMutexState is placed in shared memory.
+// Owner fields are changed only by the owner of the underlying mutex.
+type recursiveMutexState struct {
+	tid   uint64
+	pid   int32
+	depth int32
+}
+
+// RecursiveMutex is a mutex, which can be locked several times by the same OS thread.
+// It must be unlocked the same number of times to be released.
+// As the owner is an OS thread, goroutines must call runtime.LockOSThread before locking the mutex
+// and must not unlock it until the mutex is released.
+type RecursiveMutex struct {
+	m      TimedIPCLocker
+	state  *recursiveMutexState
+	pid    int32
+	region *mmf.MemoryRegion
+	name   string
+}
+
+// NewRecursiveMutex creates a new recursive mutex.
+// It uses the default mutex implementation on the current platform and stores
+// the owner thread and the recursion depth in shared memory.
+//	name - object name.
+//	flag - flag is a combination of open flags from 'os' package.
+//	perm - object's permission bits.
+func NewRecursiveMutex(name string, flag int, perm os.FileMode) (IPCLocker, error) {
+	if err := ensureOpenFlags(flag); err != nil {
+		return nil, err
+	}
+	region, created, err := helper.CreateWritableRegion(mutexSharedStateName(name, "rc"), flag, perm, recursiveMutexStateSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create shared state")
+	}
+	m, err := NewMutex(name, flag, perm)
+	if err != nil {
+		region.Close()
+		if created {
+			shm.DestroyMemoryObject(mutexSharedStateName(name, "rc"))
+		}
+		return nil, errors.Wrap(err, "failed to create a mutex")
+	}
+	result := &RecursiveMutex{
+		m:      m,
+		state:  (*recursiveMutexState)(allocator.ByteSliceData(region.Data())),
+		pid:    int32(os.Getpid()),
+		region: region,
+		name:   name,
+	}
+	if created {
+		result.setOwner(0, 0)
+		result.state.depth = 0
+	}
+	return result, nil
+}
+
+// Lock locks the mutex. If the mutex is already locked by the calling thread, the recursion depth is increased.
+func (rm *RecursiveMutex) Lock() {
+	tid := currentThreadID()
+	if rm.ownedBy(rm.pid, tid) {
+		rm.state.depth++
+		return
+	}
+	rm.m.Lock()
+	rm.setOwner(rm.pid, tid)
+	rm.state.depth = 1
+}
+
+// Unlock decreases the recursion depth and releases the mutex, when it becomes zero.
+// It panics, if the mutex is not locked by the calling thread.
+func (rm *RecursiveMutex) Unlock() {
+	if !rm.ownedBy(rm.pid, currentThreadID()) {
+		panic(errors.New("unlock of a recursive mutex, which is not locked by the thread"))
+	}
+	rm.state.depth--
+	if rm.state.depth > 0 {
+		return
+	}
+	rm.setOwner(0, 0)
+	rm.m.Unlock()
+}
+
+// Close indicates, that the object is no longer in use,
+// and that the underlying resources can be freed.
+func (rm *RecursiveMutex) Close() error {
+	errMutex := rm.m.Close()
+	if err := rm.region.Close(); err != nil {
+		return errors.Wrap(err, "failed to close shm region")
+	}
+	if errMutex != nil {
+		return errors.Wrap(errMutex, "failed to close the mutex")
+	}
+	return nil
+}
+
+// Destroy removes the mutex object.
+func (rm *RecursiveMutex) Destroy() error {
+	if err := rm.Close(); err != nil {
+		return err
+	}
+	return DestroyRecursiveMutex(rm.name)
+}
+
+func (rm *RecursiveMutex) ownedBy(pid int32, tid uint64) bool {
+	return atomic.LoadInt32(&rm.state.pid) == pid && atomic.LoadUint64(&rm.state.tid) == tid
+}
+
+func (rm *RecursiveMutex) setOwner(pid int32, tid uint64) {
+	atomic.StoreInt32(&rm.state.pid, pid)
+	atomic.StoreUint64(&rm.state.tid, tid)
+}
+
+// DestroyRecursiveMutex permanently removes mutex with the given name.
+func DestroyRecursiveMutex(name string) error {
+	errMutex := DestroyMutex(name)
+	if err := shm.DestroyMemoryObject(mutexSharedStateName(name, "rc")); err != nil {
+		return errors.Wrap(err, "failed to destroy memory object")
+	}
+	if errMutex != nil {
+		return errors.Wrap(errMutex, "failed to destroy the mutex")
+	}
+	return nil
+}
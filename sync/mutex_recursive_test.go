@@ -0,0 +1,94 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"os"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func recursiveCtor(name string, mode int, perm os.FileMode) (IPCLocker, error) {
+	return NewRecursiveMutex(name, mode, perm)
+}
+
+func recursiveDtor(name string) error {
+	return DestroyRecursiveMutex(name)
+}
+
+// threadLockedMutex wires the calling goroutine to its OS thread while the mutex is locked,
+// so that it can be used by generic locker tests, which lock it from different goroutines.
+type threadLockedMutex struct {
+	*RecursiveMutex
+}
+
+func (m threadLockedMutex) Lock() {
+	runtime.LockOSThread()
+	m.RecursiveMutex.Lock()
+}
+
+func (m threadLockedMutex) Unlock() {
+	m.RecursiveMutex.Unlock()
+	runtime.UnlockOSThread()
+}
+
+func threadLockedRecursiveCtor(name string, mode int, perm os.FileMode) (IPCLocker, error) {
+	m, err := NewRecursiveMutex(name, mode, perm)
+	if err != nil {
+		return nil, err
+	}
+	return threadLockedMutex{m.(*RecursiveMutex)}, nil
+}
+
+func TestRecursiveMutexOpenMode(t *testing.T) {
+	testLockerOpenMode(t, recursiveCtor, recursiveDtor)
+}
+
+func TestRecursiveMutexLock(t *testing.T) {
+	testLockerLock(t, threadLockedRecursiveCtor, recursiveDtor)
+}
+
+func TestRecursiveMutexRecursion(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyRecursiveMutex(testLockerName)) {
+		return
+	}
+	m, err := NewRecursiveMutex(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m.(*RecursiveMutex).Destroy()
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	for i := 0; i < 3; i++ {
+		m.Lock()
+	}
+	var locked int32
+	done := make(chan struct{})
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		// unlock by a non-owner thread must panic.
+		a.Panics(func() { m.Unlock() })
+		m.Lock()
+		atomic.StoreInt32(&locked, 1)
+		m.Unlock()
+		close(done)
+	}()
+	for i := 0; i < 3; i++ {
+		<-time.After(time.Millisecond * 50)
+		a.Equal(int32(0), atomic.LoadInt32(&locked))
+		m.Unlock()
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("the mutex was not released")
+	}
+	a.Equal(int32(1), atomic.LoadInt32(&locked))
+	a.Panics(func() { m.Unlock() })
+}
@@ -0,0 +1,202 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build darwin freebsd linux
+
+package sync
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/common"
+	"github.com/nxgtw/go-ipc/internal/helper"
+	"bitbucket.org/avd/go-ipc/mmf"
+	"bitbucket.org/avd/go-ipc/shm"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	robustMutexStateSize = 4
+
+	robustMutexSpinCount   = 100
+	robustMutexMaxInterval = 10 * time.Millisecond
+)
+
+var (
+	// ErrOwnerDead is returned by RobustMutex, when the mutex was locked by a process,
+	// which died without unlocking it. The mutex is locked by the caller,
+	// but the state protected by the mutex may be inconsistent.
+	ErrOwnerDead = errors.New("the owner of the mutex has died")
+)
+
+// all implementations must satisfy IPCLocker interface.
+var (
+	_ TimedIPCLocker   = (*RobustMutex)(nil)
+	_ ContextLocker    = (*RobustMutex)(nil)
+	_ ResettableLocker = (*RobustMutex)(nil)
+)
+
+// RobustMutex is a mutex, which can be recovered, if its owner process dies.
+// It stores the pid of the owner process in shared memory. If the mutex is locked,
+// waiters poll it with an increasing interval and check, whether the owner is still alive.
+// If it is not, the mutex is reclaimed, and ErrOwnerDead is returned.
+// As the owner is a process, the mutex can be unlocked by any goroutine of this process.
+// If the pid of a dead owner is reused by another process, the mutex can't be recovered,
+// until that process exits.
+type RobustMutex struct {
+	owner  *int32
+	pid    int32
+	region *mmf.MemoryRegion
+	name   string
+}
+
+// NewRobustMutex creates a new robust mutex.
+//	name - object name.
+//	flag - flag is a combination of open flags from 'os' package.
+//	perm - object's permission bits.
+func NewRobustMutex(name string, flag int, perm os.FileMode) (*RobustMutex, error) {
+	if err := ensureOpenFlags(flag); err != nil {
+		return nil, err
+	}
+	region, created, err := helper.CreateWritableRegion(mutexSharedStateName(name, "r"), flag, perm, robustMutexStateSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create shared state")
+	}
+	result := &RobustMutex{
+		owner:  (*int32)(allocator.ByteSliceData(region.Data())),
+		pid:    int32(os.Getpid()),
+		region: region,
+		name:   name,
+	}
+	if created {
+		atomic.StoreInt32(result.owner, 0)
+	}
+	return result, nil
+}
+
+// Lock locks the mutex. If the owner of the mutex has died, the mutex is reclaimed silently.
+// Use LockContext to find out, whether this happened.
+func (m *RobustMutex) Lock() {
+	m.lock(-1, nil)
+}
+
+// TryLock makes one attempt to lock the mutex. It return true on succeess and false otherwise.
+// If the owner of the mutex has died, the mutex is reclaimed.
+func (m *RobustMutex) TryLock() bool {
+	ok, _ := m.tryLock(true)
+	return ok
+}
+
+// LockTimeout tries to lock the mutex, waiting for not more, than timeout.
+// Passing negative value as a timeout makes the timeout infinite.
+// If the owner of the mutex has died, the mutex is reclaimed silently.
+func (m *RobustMutex) LockTimeout(timeout time.Duration) bool {
+	locked, _ := m.lock(timeout, nil)
+	return locked
+}
+
+// LockContext tries to lock the mutex, until it succeeds, or the context is done.
+// It returns ctx.Err(), if the mutex was not locked, and ErrOwnerDead,
+// if the mutex has been locked, but its previous owner had died without unlocking it.
+func (m *RobustMutex) LockContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	timeout := time.Duration(-1)
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = deadline.Sub(time.Now())
+	}
+	locked, err := m.lock(timeout, ctx.Done())
+	if locked {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return context.DeadlineExceeded
+}
+
+// Unlock releases the mutex. It panics, if the mutex is not locked by the current process.
+func (m *RobustMutex) Unlock() {
+	if !atomic.CompareAndSwapInt32(m.owner, m.pid, 0) {
+		panic("unlock of a mutex, which is not locked by the process")
+	}
+}
+
+// ForceReset forcibly unlocks a mutex, whose dead owner's pid has been reused. See ResettableLocker for details.
+func (m *RobustMutex) ForceReset() error {
+	atomic.StoreInt32(m.owner, 0)
+	return nil
+}
+
+// Close indicates, that the object is no longer in use,
+// and that the underlying resources can be freed.
+func (m *RobustMutex) Close() error {
+	return m.region.Close()
+}
+
+// Destroy removes the mutex object.
+func (m *RobustMutex) Destroy() error {
+	if err := m.Close(); err != nil {
+		return errors.Wrap(err, "failed to close shm region")
+	}
+	return DestroyRobustMutex(m.name)
+}
+
+// lock tries to lock the mutex, until it succeeds, the timeout expires, or cancel is closed.
+// It returns true and ErrOwnerDead, if the mutex has been reclaimed from a dead owner.
+func (m *RobustMutex) lock(timeout time.Duration, cancel <-chan struct{}) (bool, error) {
+	for i := 0; i < robustMutexSpinCount; i++ {
+		if ok, err := m.tryLock(false); ok {
+			return true, err
+		}
+		runtime.Gosched()
+	}
+	var result error
+	locked, _ := common.PollTimeout(func() (bool, error) {
+		ok, err := m.tryLock(true)
+		if ok {
+			result = err
+		}
+		return ok, nil
+	}, timeout, robustMutexMaxInterval, cancel)
+	return locked, result
+}
+
+// tryLock makes one attempt to lock the mutex.
+// If checkOwner is true, and the mutex is locked by a dead process, it is reclaimed, and ErrOwnerDead is returned.
+func (m *RobustMutex) tryLock(checkOwner bool) (bool, error) {
+	if atomic.CompareAndSwapInt32(m.owner, 0, m.pid) {
+		return true, nil
+	}
+	if !checkOwner {
+		return false, nil
+	}
+	owner := atomic.LoadInt32(m.owner)
+	if owner == 0 || processExists(int(owner)) {
+		return false, nil
+	}
+	if atomic.CompareAndSwapInt32(m.owner, owner, m.pid) {
+		return true, ErrOwnerDead
+	}
+	return false, nil
+}
+
+// DestroyRobustMutex permanently removes mutex with the given name.
+func DestroyRobustMutex(name string) error {
+	if err := shm.DestroyMemoryObject(mutexSharedStateName(name, "r")); err != nil {
+		return errors.Wrap(err, "failed to destroy memory object")
+	}
+	return nil
+}
+
+// processExists returns false, if there is no process with the given pid.
+func processExists(pid int) bool {
+	return unix.Kill(pid, 0) != unix.ESRCH
+}
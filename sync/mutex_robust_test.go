@@ -0,0 +1,94 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build darwin freebsd linux
+
+package sync
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func robustCtor(name string, mode int, perm os.FileMode) (IPCLocker, error) {
+	return NewRobustMutex(name, mode, perm)
+}
+
+func robustDtor(name string) error {
+	return DestroyRobustMutex(name)
+}
+
+func TestRobustMutexOpenMode(t *testing.T) {
+	testLockerOpenMode(t, robustCtor, robustDtor)
+}
+
+func TestRobustMutexOpenMode2(t *testing.T) {
+	testLockerOpenMode2(t, robustCtor, robustDtor)
+}
+
+func TestRobustMutexLock(t *testing.T) {
+	testLockerLock(t, robustCtor, robustDtor)
+}
+
+func TestRobustMutexMemory(t *testing.T) {
+	testLockerMemory(t, "robust", false, robustCtor, robustDtor)
+}
+
+func TestRobustMutexValueInc(t *testing.T) {
+	testLockerValueInc(t, "robust", robustCtor, robustDtor)
+}
+
+func TestRobustMutexPanicsOnDoubleUnlock(t *testing.T) {
+	testLockerTwiceUnlock(t, robustCtor, robustDtor)
+}
+
+func TestRobustMutexLockTimeout(t *testing.T) {
+	testLockerLockTimeout(t, "robust", robustCtor, robustDtor)
+}
+
+func TestRobustMutexLockContext(t *testing.T) {
+	testLockerLockContext(t, "robust", robustCtor, robustDtor)
+}
+
+func TestRobustMutexForceReset(t *testing.T) {
+	testLockerForceReset(t, robustCtor, robustDtor)
+}
+
+func TestRobustMutexOwnerDead(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyRobustMutex(testLockerName)) {
+		return
+	}
+	m, err := NewRobustMutex(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m.Destroy()
+	// get a pid of a process, which has exited.
+	cmd := exec.Command("sh", "-c", "exit 0")
+	if !a.NoError(cmd.Run()) {
+		return
+	}
+	deadPid := int32(cmd.Process.Pid)
+	atomic.StoreInt32(m.owner, deadPid)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	a.Equal(ErrOwnerDead, m.LockContext(ctx))
+	a.Equal(m.pid, atomic.LoadInt32(m.owner))
+	a.False(m.TryLock())
+	m.Unlock()
+	atomic.StoreInt32(m.owner, deadPid)
+	a.True(m.TryLock())
+	m.Unlock()
+	// the owner is alive, so the mutex is not reclaimed.
+	m.Lock()
+	a.False(m.LockTimeout(time.Millisecond * 50))
+	m.Unlock()
+	a.True(m.LockTimeout(0))
+	m.Unlock()
+}
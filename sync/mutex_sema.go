@@ -3,10 +3,12 @@
 package sync
 
 import (
+	"context"
 	"os"
 	"time"
 
 	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/common"
 	"github.com/nxgtw/go-ipc/internal/helper"
 	"bitbucket.org/avd/go-ipc/mmf"
 	"bitbucket.org/avd/go-ipc/shm"
@@ -51,7 +53,7 @@ func NewSemaMutex(name string, flag int, perm os.FileMode) (*SemaMutex, error) {
 		s:      s,
 		region: region,
 		name:   name,
-		lwm:    newLightweightMutex(allocator.ByteSliceData(region.Data()), newSemaWaiter(s)),
+		lwm:    newLightweightMutex(name, allocator.ByteSliceData(region.Data()), newSemaWaiter(s)),
 	}
 	if created {
 		result.lwm.init()
@@ -65,8 +67,15 @@ func (m *SemaMutex) Lock() {
 }
 
 // LockTimeout tries to lock the locker, waiting for not more, than timeout.
+// It panics on an error other, than a timeout.
 func (m *SemaMutex) LockTimeout(timeout time.Duration) bool {
-	return m.lwm.lockTimeout(timeout)
+	return mustLock(m.lwm.lockTimeout(timeout))
+}
+
+// LockContext tries to lock the mutex, until it succeeds, or the context is done.
+// It returns ctx.Err(), if the mutex was not locked.
+func (m *SemaMutex) LockContext(ctx context.Context) error {
+	return m.lwm.lockContext(ctx)
 }
 
 // TryLock makes one attempt to lock the mutex. It returns true on succeess and false otherwise.
@@ -74,13 +83,27 @@ func (m *SemaMutex) TryLock() bool {
 	return m.lwm.tryLock()
 }
 
+// SetSpinCount sets the number of attempts to lock the mutex, which are made before waiting on a semaphore.
+// Larger values reduce latency on lightly contended mutexes, smaller ones save CPU time.
+// The default value is 100. Negative values are treated as zero.
+// It affects only this instance of the mutex.
+func (m *SemaMutex) SetSpinCount(n int) {
+	m.lwm.setSpinCount(n)
+}
+
 // Unlock releases the mutex. It panics on an error, or if the mutex is not locked.
 func (m *SemaMutex) Unlock() {
 	m.lwm.unlock()
 }
 
+// ForceReset forcibly unlocks the mutex regardless of its owner. See ResettableLocker for details.
+func (m *SemaMutex) ForceReset() error {
+	return m.lwm.forceReset()
+}
+
 // Close closes shared state of the mutex.
 func (m *SemaMutex) Close() error {
+	common.UnregisterFromCleanup(m)
 	e1, e2 := m.s.Close(), m.region.Close()
 	if e1 != nil {
 		return errors.Wrap(e1, "failed to close semaphore")
@@ -3,6 +3,7 @@
 package sync
 
 import (
+	"context"
 	"os"
 	"runtime"
 	"time"
@@ -17,7 +18,8 @@ import (
 
 // all implementations must satisfy IPCLocker interface.
 var (
-	_ IPCLocker = (*SpinMutex)(nil)
+	_ IPCLocker        = (*SpinMutex)(nil)
+	_ ResettableLocker = (*SpinMutex)(nil)
 )
 
 // SpinMutex is a synchronization object which performs busy wait loop.
@@ -46,15 +48,15 @@ func NewSpinMutex(name string, flag int, perm os.FileMode) (*SpinMutex, error) {
 	if err := ensureOpenFlags(flag); err != nil {
 		return nil, err
 	}
-	name = spinName(name)
-	region, created, err := helper.CreateWritableRegion(name, flag, perm, lwmStateSize)
+	objName := spinName(name)
+	region, created, err := helper.CreateWritableRegion(objName, flag, perm, lwmStateSize)
 	if err != nil {
 		return nil, err
 	}
 	result := &SpinMutex{
 		region: region,
-		name:   name,
-		lwm:    newLightweightMutex(allocator.ByteSliceData(region.Data()), new(spinWW)),
+		name:   objName,
+		lwm:    newLightweightMutex(name, allocator.ByteSliceData(region.Data()), new(spinWW)),
 	}
 	if created {
 		result.lwm.init()
@@ -68,8 +70,15 @@ func (spin *SpinMutex) Lock() {
 }
 
 // LockTimeout locks the mutex waiting in a busy loop for not longer, than timeout.
+// It panics on an error other, than a timeout.
 func (spin *SpinMutex) LockTimeout(timeout time.Duration) bool {
-	return spin.lwm.lockTimeout(timeout)
+	return mustLock(spin.lwm.lockTimeout(timeout))
+}
+
+// LockContext tries to lock the mutex, until it succeeds, or the context is done.
+// It returns ctx.Err(), if the mutex was not locked.
+func (spin *SpinMutex) LockContext(ctx context.Context) error {
+	return spin.lwm.lockContext(ctx)
 }
 
 // Unlock releases the mutex. It panics, if the mutex is not locked.
@@ -82,6 +91,11 @@ func (spin *SpinMutex) TryLock() bool {
 	return spin.lwm.tryLock()
 }
 
+// ForceReset forcibly unlocks the mutex regardless of its owner. See ResettableLocker for details.
+func (spin *SpinMutex) ForceReset() error {
+	return spin.lwm.forceReset()
+}
+
 // Close indicates, that the object is no longer in use,
 // and that the underlying resources can be freed.
 func (spin *SpinMutex) Close() error {
@@ -50,3 +50,19 @@ func TestSpinMutexValueInc(t *testing.T) {
 func TestSpinMutexPanicsOnDoubleUnlock(t *testing.T) {
 	testLockerTwiceUnlock(t, spinCtor, spinDtor)
 }
+
+func TestSpinMutexLockTimeout(t *testing.T) {
+	testLockerLockTimeout(t, "spin", spinCtor, spinDtor)
+}
+
+func TestSpinMutexLockContext(t *testing.T) {
+	testLockerLockContext(t, "spin", spinCtor, spinDtor)
+}
+
+func TestSpinMutexForceReset(t *testing.T) {
+	testLockerForceReset(t, spinCtor, spinDtor)
+}
+
+func TestSpinMutexLogger(t *testing.T) {
+	testLockerLogger(t, spinCtor, spinDtor)
+}
@@ -35,6 +35,14 @@ func TestMutexOpenMode5(t *testing.T) {
 	testLockerOpenMode5(t, mutexCtor, mutexDtor)
 }
 
+func TestMutexMustDestroy(t *testing.T) {
+	testLockerMustDestroy(t, mutexCtor, mutexDtor, MustDestroyMutex)
+}
+
+func TestMutexExists(t *testing.T) {
+	testLockerExists(t, mutexCtor, mutexDtor, MutexExists)
+}
+
 func TestMutexLock(t *testing.T) {
 	testLockerLock(t, mutexCtor, mutexDtor)
 }
@@ -58,3 +66,11 @@ func TestMutexLockTimeout2(t *testing.T) {
 func TestMutexPanicsOnDoubleUnlock(t *testing.T) {
 	testLockerTwiceUnlock(t, mutexCtor, mutexDtor)
 }
+
+func TestMutexLockContext(t *testing.T) {
+	testLockerLockContext(t, defaultMutexType, mutexCtor, mutexDtor)
+}
+
+func TestMutexForceReset(t *testing.T) {
+	testLockerForceReset(t, mutexCtor, mutexDtor)
+}
@@ -0,0 +1,130 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"os"
+	"sync/atomic"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/helper"
+	"bitbucket.org/avd/go-ipc/mmf"
+	"bitbucket.org/avd/go-ipc/shm"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	onceStateSize = 4
+)
+
+// Once is an interprocess analogue of sync.Once. It performs an action exactly once
+// across all the processes, which use the object with the same name.
+// It can be used to initialize the layout of shared memory, when several processes attach to it.
+// The 'done' flag is placed in shared memory and is protected by a mutex.
+type Once struct {
+	name   string
+	region *mmf.MemoryRegion
+	done   *int32
+	m      IPCLocker
+}
+
+// NewOnce creates a new once object, or opens an existing one.
+//	name - object name.
+//	flag - flag is a combination of open flags from 'os' package.
+//	perm - object's permission bits.
+func NewOnce(name string, flag int, perm os.FileMode) (*Once, error) {
+	if err := ensureOpenFlags(flag); err != nil {
+		return nil, err
+	}
+	region, created, err := helper.CreateWritableRegion(onceName(name), flag, perm, onceStateSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create shared state")
+	}
+	m, err := NewMutex(onceMutexName(name), flag, perm)
+	if err != nil {
+		region.Close()
+		if created {
+			shm.DestroyMemoryObject(onceName(name))
+		}
+		return nil, errors.Wrap(err, "failed to create a mutex")
+	}
+	result := &Once{
+		name:   name,
+		region: region,
+		done:   (*int32)(allocator.ByteSliceData(region.Data())),
+		m:      m,
+	}
+	if created {
+		atomic.StoreInt32(result.done, 0)
+	}
+	return result, nil
+}
+
+// Do calls f, if no call of Do for this object in any process has succeeded yet.
+// Concurrent callers wait, until the running call of f returns.
+// If f succeeds, it is never called again, and all later callers get nil.
+// If f returns an error, or panics, the action is not considered done: the error is returned
+// to the caller, whose f has failed, and the next caller of Do runs its f again.
+// This allows to retry the initialization, if it fails in one of the processes.
+// f must not call Do of the same object, or it will deadlock.
+func (o *Once) Do(f func() error) error {
+	if atomic.LoadInt32(o.done) == 1 {
+		return nil
+	}
+	o.m.Lock()
+	defer o.m.Unlock()
+	if atomic.LoadInt32(o.done) == 1 {
+		return nil
+	}
+	if err := f(); err != nil {
+		return err
+	}
+	atomic.StoreInt32(o.done, 1)
+	return nil
+}
+
+// Done returns true, if the action has been successfully performed.
+func (o *Once) Done() bool {
+	return atomic.LoadInt32(o.done) == 1
+}
+
+// Close closes the object.
+func (o *Once) Close() error {
+	errMutex := o.m.Close()
+	if err := o.region.Close(); err != nil {
+		return errors.Wrap(err, "failed to close shm region")
+	}
+	if errMutex != nil {
+		return errors.Wrap(errMutex, "failed to close the mutex")
+	}
+	return nil
+}
+
+// Destroy closes the object and removes it permanently.
+func (o *Once) Destroy() error {
+	if err := o.Close(); err != nil {
+		return err
+	}
+	return DestroyOnce(o.name)
+}
+
+// DestroyOnce permanently removes a once object with the given name.
+func DestroyOnce(name string) error {
+	errMutex := DestroyMutex(onceMutexName(name))
+	if err := shm.DestroyMemoryObject(onceName(name)); err != nil {
+		return errors.Wrap(err, "failed to destroy memory object")
+	}
+	if errMutex != nil {
+		return errors.Wrap(errMutex, "failed to destroy the mutex")
+	}
+	return nil
+}
+
+func onceName(baseName string) string {
+	return baseName + ".once"
+}
+
+func onceMutexName(baseName string) string {
+	return baseName + ".oncem"
+}
@@ -0,0 +1,116 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/test"
+	"bitbucket.org/avd/go-ipc/mmf"
+	"bitbucket.org/avd/go-ipc/shm"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testOnceName = "go-ipc.test-once"
+)
+
+func TestOnceDo(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyOnce(testOnceName)) {
+		return
+	}
+	o, err := NewOnce(testOnceName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(o.Destroy())
+	}()
+	var calls int32
+	failure := errors.New("failure")
+	// a failed call does not complete the action.
+	a.Equal(failure, o.Do(func() error {
+		atomic.AddInt32(&calls, 1)
+		return failure
+	}))
+	a.False(o.Done())
+	a.Panics(func() {
+		o.Do(func() error {
+			atomic.AddInt32(&calls, 1)
+			panic("panic")
+		})
+	})
+	a.False(o.Done())
+	a.NoError(o.Do(func() error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}))
+	a.True(o.Done())
+	a.Equal(int32(3), calls)
+	// the action is done for other instances too.
+	o2, err := NewOnce(testOnceName, 0, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer o2.Close()
+	a.True(o2.Done())
+	a.NoError(o2.Do(func() error {
+		atomic.AddInt32(&calls, 1)
+		return failure
+	}))
+	a.Equal(int32(3), calls)
+}
+
+func TestOnceAnotherProcess(t *testing.T) {
+	const jobs = 4
+	a := assert.New(t)
+	if !a.NoError(DestroyOnce(testOnceName)) {
+		return
+	}
+	o, err := NewOnce(testOnceName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(o.Destroy())
+	}()
+	if !a.NoError(shm.DestroyMemoryObject(testMemObj)) {
+		return
+	}
+	region, err := createMemoryRegionSimple(os.O_CREATE|os.O_EXCL|os.O_RDWR, mmf.MEM_READWRITE, 4, 0)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(region.Close())
+		a.NoError(shm.DestroyMemoryObject(testMemObj))
+	}()
+	counter := (*int32)(allocator.ByteSliceData(region.Data()))
+	var chans []<-chan testutil.TestAppResult
+	for i := 0; i < jobs; i++ {
+		chans = append(chans, testutil.RunTestAppAsync(argsForOnceDoCommand(testOnceName, testMemObj), nil))
+	}
+	for _, ch := range chans {
+		select {
+		case res := <-ch:
+			if res.Err != nil {
+				t.Errorf("app error: %v. the output is %q", res.Err, res.Output)
+			}
+		case <-time.After(time.Second * 10):
+			t.Errorf("timeout")
+		}
+	}
+	a.True(o.Done())
+	a.NoError(o.Do(func() error {
+		atomic.AddInt32(counter, 1)
+		return nil
+	}))
+	a.Equal(int32(1), atomic.LoadInt32(counter))
+}
@@ -4,6 +4,8 @@ package sync
 
 import (
 	"os"
+	"sync"
+	"sync/atomic"
 
 	"github.com/nxgtw/go-ipc/internal/allocator"
 	"github.com/nxgtw/go-ipc/internal/helper"
@@ -19,11 +21,23 @@ var (
 )
 
 // RWMutex is a mutex, that can be held by any number of readers or one writer.
+// It prefers writers: a pending writer is counted in the shared state, and RLock consults it
+// before incrementing the number of readers, so readers, which arrive after a writer, wait until it unlocks the mutex.
+// Thus, a writer waits only for the readers, which already hold the lock, and can't be starved by new ones.
 type RWMutex struct {
 	lwm    *lwRWMutex
 	region *mmf.MemoryRegion
 	wR, wW waitWaker
 	name   string
+
+	// recursive read mode state. recursive is accessed atomically, rDepth is guarded by rMu.
+	recursive int32
+	rMu       sync.Mutex
+	rDepth    map[uint64]int
+
+	// lock statistics. statsOn is accessed atomically.
+	stats   *rwMutexStats
+	statsOn int32
 }
 
 // NewRWMutex returns new RWMutex
@@ -38,7 +52,7 @@ func NewRWMutex(name string, flag int, perm os.FileMode) (*RWMutex, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create shared state")
 	}
-	result := &RWMutex{region: region, name: name}
+	result := &RWMutex{region: region, name: name, stats: new(rwMutexStats)}
 	if result.wR, result.wW, err = makeRWMWaiters(name, flag, perm); err != nil {
 		region.Close()
 		if created {
@@ -55,6 +69,10 @@ func NewRWMutex(name string, flag int, perm os.FileMode) (*RWMutex, error) {
 
 // Lock locks the mutex exclusively. It panics on an error.
 func (rw *RWMutex) Lock() {
+	if rw.statsEnabled() {
+		rw.stats.lock(&rw.stats.locks, rw.lwm.tryLock, rw.lwm.lock)
+		return
+	}
 	rw.lwm.lock()
 }
 
@@ -63,17 +81,195 @@ func (rw *RWMutex) Unlock() {
 	rw.lwm.unlock()
 }
 
+// TryLock tries to lock the mutex exclusively without waiting.
+// It returns false, if the mutex is locked by a writer or any readers, or a writer is waiting.
+func (rw *RWMutex) TryLock() bool {
+	if rw.statsEnabled() {
+		return rw.stats.tryLock(rw.lwm.tryLock)
+	}
+	return rw.lwm.tryLock()
+}
+
+// SetSpinCount sets the number of attempts to lock the mutex, which Lock and RLock make before going to sleep.
+// Spinning reduces latency on lightly contended mutexes at the cost of CPU time.
+// The default value is 0, so the callers go to sleep at once. Negative values are treated as zero.
+// It affects only this instance of the mutex.
+func (rw *RWMutex) SetSpinCount(n int) {
+	rw.lwm.setSpinCount(n)
+}
+
 // RLock locks the mutex for reading. It panics on an error.
+// It is not affected by recursive read mode, use RLockOwner to re-enter a read lock.
 func (rw *RWMutex) RLock() {
+	rw.rlock()
+}
+
+// TryRLock tries to lock the mutex for reading without waiting.
+// It returns false, if the mutex is locked by a writer, or a writer is waiting.
+func (rw *RWMutex) TryRLock() bool {
+	return rw.tryRLock()
+}
+
+// RLockOwner locks the mutex for reading on behalf of the owner. It panics on an error.
+// In recursive read mode, if the owner already holds a read lock, it does not wait even if there are pending writers.
+// Otherwise it is the same as RLock.
+func (rw *RWMutex) RLockOwner(owner uint64) {
+	if !rw.recursiveRead() {
+		rw.rlock()
+		return
+	}
+	rw.rMu.Lock()
+	if rw.rDepth[owner] == 0 {
+		// do not block other owners while waiting.
+		rw.rMu.Unlock()
+		rw.rlock()
+		rw.rMu.Lock()
+	} else if rw.statsEnabled() {
+		atomic.AddUint64(&rw.stats.rlocks, 1)
+	}
+	rw.rDepth[owner]++
+	rw.rMu.Unlock()
+}
+
+// TryRLockOwner tries to lock the mutex for reading on behalf of the owner without waiting.
+// In recursive read mode it always succeeds, if the owner already holds a read lock.
+// Otherwise it is the same as TryRLock.
+func (rw *RWMutex) TryRLockOwner(owner uint64) bool {
+	if !rw.recursiveRead() {
+		return rw.tryRLock()
+	}
+	rw.rMu.Lock()
+	defer rw.rMu.Unlock()
+	depth := rw.rDepth[owner]
+	if depth == 0 && !rw.tryRLock() {
+		return false
+	}
+	rw.rDepth[owner] = depth + 1
+	return true
+}
+
+// rlock takes a shared read lock, accounting it, if the statistics are enabled.
+func (rw *RWMutex) rlock() {
+	if rw.statsEnabled() {
+		rw.stats.lock(&rw.stats.rlocks, rw.lwm.tryRLock, rw.lwm.rlock)
+		return
+	}
 	rw.lwm.rlock()
 }
 
+// tryRLock makes one attempt to take a shared read lock, accounting it, if the statistics are enabled.
+func (rw *RWMutex) tryRLock() bool {
+	if rw.statsEnabled() {
+		return rw.stats.tryLock(rw.lwm.tryRLock)
+	}
+	return rw.lwm.tryRLock()
+}
+
 // RUnlock desceases the number of mutex's readers. If it becomes 0, writers (if any) can proceed.
 // It panics on an error, or if the mutex is not locked.
 func (rw *RWMutex) RUnlock() {
 	rw.lwm.runlock()
 }
 
+// RUnlockOwner releases a read lock taken by RLockOwner or TryRLockOwner.
+// In recursive read mode the shared read lock is released, when the read depth of the owner becomes 0.
+// It panics on an error, or if the mutex is not locked by the owner.
+func (rw *RWMutex) RUnlockOwner(owner uint64) {
+	if !rw.recursiveRead() {
+		rw.lwm.runlock()
+		return
+	}
+	rw.rMu.Lock()
+	defer rw.rMu.Unlock()
+	depth := rw.rDepth[owner]
+	if depth == 0 {
+		panic("unlock of unlocked mutex")
+	}
+	if depth == 1 {
+		delete(rw.rDepth, owner)
+		rw.lwm.runlock()
+	} else {
+		rw.rDepth[owner] = depth - 1
+	}
+}
+
+// TryUpgrade atomically converts a read lock held by the caller into a write lock,
+// if there are no other readers. Pending writers do not prevent the upgrade.
+// On success the mutex is locked exclusively and must be released with Unlock.
+// Otherwise it returns false, and the caller still holds its read lock.
+func (rw *RWMutex) TryUpgrade() bool {
+	return rw.lwm.tryUpgrade()
+}
+
+// TryUpgradeOwner is the same as TryUpgrade for a read lock taken by RLockOwner or TryRLockOwner.
+// In recursive read mode the upgrade succeeds only if the owner holds exactly one read lock.
+func (rw *RWMutex) TryUpgradeOwner(owner uint64) bool {
+	if !rw.recursiveRead() {
+		return rw.lwm.tryUpgrade()
+	}
+	rw.rMu.Lock()
+	defer rw.rMu.Unlock()
+	if rw.rDepth[owner] != 1 || !rw.lwm.tryUpgrade() {
+		return false
+	}
+	delete(rw.rDepth, owner)
+	return true
+}
+
+// SetRecursiveRead turns recursive read mode on or off.
+// By default, a reader, that calls RLock again while a writer is waiting, deadlocks,
+// as the writer waits for the reader, and the new read lock waits for the writer.
+// In recursive mode RLockOwner, TryRLockOwner, RUnlockOwner and TryUpgradeOwner track the read depth of an owner,
+// which is a token chosen by the caller, for example, an id of a worker or a request.
+// The first RLockOwner of an owner takes a shared read lock, which is released by its last RUnlockOwner.
+// Nested RLockOwner calls only increase the depth and never wait, so pending writers
+// do not block an owner, which re-enters. New owners still wait for pending writers.
+// Every owner counts as one reader in the shared state, so owners from different processes
+// are accounted correctly, while the depth itself never leaves this instance of the mutex.
+// RLock, TryRLock, RUnlock and TryUpgrade are not affected by the mode.
+// The mode must not be changed while the mutex is read-locked via this instance.
+func (rw *RWMutex) SetRecursiveRead(recursive bool) {
+	rw.rMu.Lock()
+	if recursive && rw.rDepth == nil {
+		rw.rDepth = make(map[uint64]int)
+	}
+	var value int32
+	if recursive {
+		value = 1
+	}
+	atomic.StoreInt32(&rw.recursive, value)
+	rw.rMu.Unlock()
+}
+
+// recursiveRead returns true, if recursive read mode is on.
+func (rw *RWMutex) recursiveRead() bool {
+	return atomic.LoadInt32(&rw.recursive) != 0
+}
+
+// ReaderCount returns the number of readers, which currently hold the mutex, in all processes.
+// Readers waiting for a writer are not counted. It is a diagnostic snapshot of the shared state,
+// which is inherently racy: the value may change before it is returned, so it must not be used for synchronization.
+// It returns an error, if the mutex is closed.
+func (rw *RWMutex) ReaderCount() (int, error) {
+	if len(rw.region.Data()) == 0 {
+		return 0, errors.New("the mutex is closed")
+	}
+	return int(rw.lwm.snapshot().readers()), nil
+}
+
+// WriterLocked returns true, if the mutex is held by a writer in any process.
+// Like ReaderCount, it is an inherently racy diagnostic snapshot.
+// It returns an error, if the mutex is closed.
+func (rw *RWMutex) WriterLocked() (bool, error) {
+	if len(rw.region.Data()) == 0 {
+		return false, errors.New("the mutex is closed")
+	}
+	// a writer, which is counted, holds the lock, when there are no readers,
+	// or it has been woken by the last of them and is about to return from Lock.
+	state := rw.lwm.snapshot()
+	return state.writers() > 0 && state.readers() == 0, nil
+}
+
 // Close closes shared state of the mutex.
 func (rw *RWMutex) Close() error {
 	e1, e2 := closeRWWaiters(rw.wR, rw.wW), rw.region.Close()
@@ -94,7 +290,34 @@ func (rw *RWMutex) Destroy() error {
 	return DestroyRWMutex(rw.name)
 }
 
+// RWMutexExists returns true, if a rw mutex with the given name exists.
+// It returns false and an error, if the mutex can't be opened
+// for any reason other, than its absence, for example, because of the permissions.
+func RWMutexExists(name string) (bool, error) {
+	m, err := NewRWMutex(name, 0, 0666)
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, m.Close()
+}
+
+// MustDestroyRWMutex permanently removes mutex with the given name.
+// Unlike DestroyRWMutex, it returns an error, if the mutex does not exist.
+// In this case os.IsNotExist(errors.Cause(err)) is true.
+func MustDestroyRWMutex(name string) error {
+	m, err := NewRWMutex(name, 0, 0666)
+	if err != nil {
+		return errors.Wrap(err, "failed to open the mutex")
+	}
+	m.Close()
+	return DestroyRWMutex(name)
+}
+
 // DestroyRWMutex permanently removes mutex with the given name.
+// It returns nil, if the mutex does not exist.
 func DestroyRWMutex(name string) error {
 	e1 := shm.DestroyMemoryObject(mutexSharedStateName(name, "rw"))
 	e2 := destroyRWWaiters(name)
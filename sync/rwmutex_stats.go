@@ -0,0 +1,86 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// RWMutexStats contains lock statistics of an RWMutex instance.
+// The values are local to the process and cover only the calls made via this instance.
+type RWMutexStats struct {
+	// Locks is the number of Lock calls.
+	Locks uint64
+	// RLocks is the number of RLock calls.
+	RLocks uint64
+	// TryLocks is the number of TryLock and TryRLock calls.
+	TryLocks uint64
+	// TryLockFailures is the number of TryLock and TryRLock calls, which did not lock the mutex.
+	TryLockFailures uint64
+	// Contended is the number of Lock and RLock calls, which had to wait for the mutex.
+	Contended uint64
+	// WaitTime is the total time, which Lock and RLock calls spent waiting for the mutex.
+	WaitTime time.Duration
+}
+
+// rwMutexStats holds the counters. It is allocated separately to guarantee
+// 64-bit alignment of the fields, which is needed for atomic operations on 32-bit platforms.
+type rwMutexStats struct {
+	locks           uint64
+	rlocks          uint64
+	tryLocks        uint64
+	tryLockFailures uint64
+	contended       uint64
+	waitTime        int64
+}
+
+// SetStatsEnabled turns statistics collection on or off. It is off by default,
+// as measuring the wait time adds a clock read to every contended lock.
+// The counters are kept, when the collection is turned off, and they are not reset, when it is turned on again.
+func (rw *RWMutex) SetStatsEnabled(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+	atomic.StoreInt32(&rw.statsOn, value)
+}
+
+// Stats returns the statistics collected since the creation of the instance.
+func (rw *RWMutex) Stats() RWMutexStats {
+	return RWMutexStats{
+		Locks:           atomic.LoadUint64(&rw.stats.locks),
+		RLocks:          atomic.LoadUint64(&rw.stats.rlocks),
+		TryLocks:        atomic.LoadUint64(&rw.stats.tryLocks),
+		TryLockFailures: atomic.LoadUint64(&rw.stats.tryLockFailures),
+		Contended:       atomic.LoadUint64(&rw.stats.contended),
+		WaitTime:        time.Duration(atomic.LoadInt64(&rw.stats.waitTime)),
+	}
+}
+
+func (rw *RWMutex) statsEnabled() bool {
+	return atomic.LoadInt32(&rw.statsOn) != 0
+}
+
+// lock accounts a Lock or RLock call. If the mutex can't be locked at once,
+// the call is accounted as contended, and the time spent in lock is measured.
+func (s *rwMutexStats) lock(counter *uint64, tryLock func() bool, lock func()) {
+	atomic.AddUint64(counter, 1)
+	if tryLock() {
+		return
+	}
+	start := time.Now()
+	lock()
+	atomic.AddUint64(&s.contended, 1)
+	atomic.AddInt64(&s.waitTime, int64(time.Since(start)))
+}
+
+// tryLock accounts a TryLock or TryRLock call.
+func (s *rwMutexStats) tryLock(tryLock func() bool) bool {
+	atomic.AddUint64(&s.tryLocks, 1)
+	if tryLock() {
+		return true
+	}
+	atomic.AddUint64(&s.tryLockFailures, 1)
+	return false
+}
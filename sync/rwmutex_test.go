@@ -7,7 +7,13 @@ import (
 	"math/rand"
 	"os"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	testutil "github.com/nxgtw/go-ipc/internal/test"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func rwMutexCtor(name string, flag int, perm os.FileMode) (IPCLocker, error) {
@@ -30,6 +36,14 @@ func TestRWMutexOpenMode(t *testing.T) {
 	testLockerOpenMode(t, rwMutexCtor, rwMutexDtor)
 }
 
+func TestRWMutexExists(t *testing.T) {
+	testLockerExists(t, rwMutexCtor, rwMutexDtor, RWMutexExists)
+}
+
+func TestRWMutexMustDestroy(t *testing.T) {
+	testLockerMustDestroy(t, rwMutexCtor, rwMutexDtor, MustDestroyRWMutex)
+}
+
 func TestRWMutexOpenMode2(t *testing.T) {
 	testLockerOpenMode2(t, rwMutexCtor, rwMutexDtor)
 }
@@ -70,6 +84,457 @@ func TestRWMutexPanicsOnDoubleRUnlock(t *testing.T) {
 	testLockerTwiceUnlock(t, rwRMutexCtor, rwMutexDtor)
 }
 
+func TestRWMutexRecursiveRead(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyRWMutex(testLockerName)) {
+		return
+	}
+	m, err := NewRWMutex(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m.Destroy()
+	m.SetRecursiveRead(true)
+	m.RLockOwner(1)
+	m.RLockOwner(1)
+	m.RUnlockOwner(1)
+	m.RUnlockOwner(1)
+	a.Panics(func() {
+		m.RUnlockOwner(1)
+	})
+	a.True(testutil.WaitForFunc(func() {
+		m.Lock()
+		m.Unlock()
+	}, time.Millisecond*200))
+}
+
+func TestRWMutexRecursiveReadWithWriter(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyRWMutex(testLockerName)) {
+		return
+	}
+	m, err := NewRWMutex(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m.Destroy()
+	writer, err := NewRWMutex(testLockerName, 0, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer writer.Close()
+	m.SetRecursiveRead(true)
+	held, nested, release, released := make(chan struct{}), make(chan struct{}), make(chan struct{}), make(chan struct{})
+	go func() {
+		m.RLockOwner(1)
+		close(held)
+		<-nested
+		// re-enter while the writer is waiting.
+		m.RLockOwner(1)
+		m.RUnlockOwner(1)
+		<-release
+		m.RUnlockOwner(1)
+		close(released)
+	}()
+	<-held
+	locked := make(chan struct{})
+	go func() {
+		writer.Lock()
+		close(locked)
+		writer.Unlock()
+	}()
+	// let the writer start waiting.
+	<-time.After(time.Millisecond * 100)
+	nested <- struct{}{}
+	release <- struct{}{}
+	select {
+	case <-released:
+	case <-time.After(time.Millisecond * 200):
+		t.Error("nested read lock waited for the writer")
+		return
+	}
+	select {
+	case <-locked:
+	case <-time.After(time.Millisecond * 500):
+		t.Error("writer failed to lock the mutex")
+	}
+}
+
+func TestRWMutexRecursiveReadPerOwner(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyRWMutex(testLockerName)) {
+		return
+	}
+	m, err := NewRWMutex(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m.Destroy()
+	m.SetRecursiveRead(true)
+	m.RLockOwner(1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// the read lock is held by another owner.
+		a.Panics(func() {
+			m.RUnlockOwner(2)
+		})
+		m.RLockOwner(2)
+		m.RLockOwner(2)
+		m.RUnlockOwner(2)
+		m.RUnlockOwner(2)
+	}()
+	<-done
+	m.RUnlockOwner(1)
+	a.True(testutil.WaitForFunc(func() {
+		m.Lock()
+		m.Unlock()
+	}, time.Millisecond*200))
+}
+
+func TestRWMutexRecursiveReadCrossProcess(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyRWMutex(testLockerName)) {
+		return
+	}
+	m, err := NewRWMutex(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m.Destroy()
+	var stop int32
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		for atomic.LoadInt32(&stop) == 0 {
+			m.Lock()
+			m.Unlock()
+		}
+	}()
+	args := argsForSyncRRLockCommand(testLockerName, 4, 1000, 3)
+	result := testutil.RunTestApp(args, nil)
+	atomic.StoreInt32(&stop, 1)
+	<-writerDone
+	if !a.NoError(result.Err) {
+		t.Logf("test app error. the output is: %s", result.Output)
+	}
+}
+
+func TestRWMutexTryUpgrade(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyRWMutex(testLockerName)) {
+		return
+	}
+	m, err := NewRWMutex(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m.Destroy()
+	m2, err := NewRWMutex(testLockerName, 0, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m2.Close()
+	state := func() lwRWState {
+		return lwRWState(*m.lwm.state)
+	}
+	m.RLock()
+	m2.RLock()
+	// both readers try to upgrade, but none of them can, as there is another reader.
+	results := make(chan bool, 2)
+	for _, rw := range []*RWMutex{m, m2} {
+		go func(rw *RWMutex) {
+			results <- rw.TryUpgrade()
+		}(rw)
+	}
+	a.False(<-results)
+	a.False(<-results)
+	a.Equal(int64(2), state().readers())
+	a.Equal(int64(0), state().writers())
+	// the second reader gives up, so the first one upgrades, while the second one keeps waiting.
+	m2.RUnlock()
+	a.True(m.TryUpgrade())
+	a.Equal(int64(0), state().readers())
+	a.Equal(int64(1), state().writers())
+	a.False(testutil.WaitForFunc(func() {
+		m2.RLock()
+	}, time.Millisecond*100))
+	m.Unlock()
+	<-time.After(time.Millisecond * 100)
+	a.Equal(int64(1), state().readers())
+	m2.RUnlock()
+	// recursive read mode.
+	m.SetRecursiveRead(true)
+	m.RLockOwner(1)
+	m.RLockOwner(1)
+	a.False(m.TryUpgradeOwner(1))
+	m.RUnlockOwner(1)
+	a.True(m.TryUpgradeOwner(1))
+	m.Unlock()
+	a.Equal(int64(0), int64(state()))
+}
+
+func TestRWMutexTryLock(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyRWMutex(testLockerName)) {
+		return
+	}
+	m, err := NewRWMutex(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m.Destroy()
+	state := func() int64 {
+		return *m.lwm.state
+	}
+	locked, unlock, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	go func() {
+		m.Lock()
+		close(locked)
+		<-unlock
+		m.Unlock()
+		close(done)
+	}()
+	<-locked
+	before := state()
+	a.False(m.TryLock())
+	a.False(m.TryRLock())
+	a.Equal(before, state())
+	close(unlock)
+	<-done
+	a.Equal(int64(0), state())
+	// readers.
+	a.True(m.TryRLock())
+	a.True(m.TryRLock())
+	a.False(m.TryLock())
+	m.RUnlock()
+	m.RUnlock()
+	a.True(m.TryLock())
+	a.False(m.TryRLock())
+	m.Unlock()
+	// a waiting writer prevents new readers.
+	m.RLock()
+	written := make(chan struct{})
+	go func() {
+		m.Lock()
+		m.Unlock()
+		close(written)
+	}()
+	<-time.After(time.Millisecond * 100)
+	a.False(m.TryRLock())
+	a.False(m.TryLock())
+	m.RUnlock()
+	select {
+	case <-written:
+	case <-time.After(time.Millisecond * 500):
+		t.Error("writer failed to lock the mutex")
+	}
+	a.Equal(int64(0), state())
+}
+
+func TestRWMutexWriterPreference(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyRWMutex(testLockerName)) {
+		return
+	}
+	m, err := NewRWMutex(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m.Destroy()
+	var events []string
+	var eventsMu sync.Mutex
+	addEvent := func(ev string) {
+		eventsMu.Lock()
+		events = append(events, ev)
+		eventsMu.Unlock()
+	}
+	m.RLock()
+	writerDone := make(chan struct{})
+	go func() {
+		m.Lock()
+		addEvent("writer")
+		m.Unlock()
+		close(writerDone)
+	}()
+	// wait for the writer to become pending.
+	for (lwRWState)(atomic.LoadInt64(m.lwm.state)).writers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.RLock()
+			addEvent("reader")
+			m.RUnlock()
+		}()
+	}
+	<-time.After(time.Millisecond * 100)
+	eventsMu.Lock()
+	a.Empty(events, "new readers must wait for the pending writer")
+	eventsMu.Unlock()
+	start := time.Now()
+	m.RUnlock()
+	select {
+	case <-writerDone:
+	case <-time.After(time.Second):
+		t.Error("writer failed to lock the mutex")
+		return
+	}
+	a.True(time.Since(start) < time.Second)
+	wg.Wait()
+	if a.Len(events, 5) {
+		a.Equal("writer", events[0])
+	}
+}
+
+func TestRWMutexStats(t *testing.T) {
+	const routines, iters = 4, 50
+	a := assert.New(t)
+	if !a.NoError(DestroyRWMutex(testLockerName)) {
+		return
+	}
+	m, err := NewRWMutex(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m.Destroy()
+	// the statistics are disabled by default.
+	m.Lock()
+	m.Unlock()
+	a.Equal(RWMutexStats{}, m.Stats())
+	m.SetStatsEnabled(true)
+	var wg sync.WaitGroup
+	for i := 0; i < routines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iters; j++ {
+				m.Lock()
+				time.Sleep(100 * time.Microsecond)
+				m.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	m.RLock()
+	a.True(m.TryRLock())
+	a.False(m.TryLock())
+	m.RUnlock()
+	m.RUnlock()
+	stats := m.Stats()
+	a.Equal(uint64(routines*iters), stats.Locks)
+	a.Equal(uint64(1), stats.RLocks)
+	a.Equal(uint64(2), stats.TryLocks)
+	a.Equal(uint64(1), stats.TryLockFailures)
+	a.True(stats.Contended > 0)
+	a.True(stats.Contended <= stats.Locks)
+	a.True(stats.WaitTime > 0)
+	m.SetStatsEnabled(false)
+	m.Lock()
+	m.Unlock()
+	a.Equal(stats, m.Stats())
+}
+
+func TestRWMutexSpinCount(t *testing.T) {
+	for _, spins := range []int{0, 1000} {
+		testLockerLock(t, func(name string, flag int, perm os.FileMode) (IPCLocker, error) {
+			m, err := NewRWMutex(name, flag, perm)
+			if err == nil {
+				m.SetSpinCount(spins)
+			}
+			return m, err
+		}, rwMutexDtor)
+		testRWMutexSpinCountReaders(t, spins)
+	}
+}
+
+func testRWMutexSpinCountReaders(t *testing.T, spins int) {
+	const routines, iters = 8, 1000
+	a := assert.New(t)
+	if !a.NoError(DestroyRWMutex(testLockerName)) {
+		return
+	}
+	m, err := NewRWMutex(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m.Destroy()
+	m.SetSpinCount(spins)
+	var wg sync.WaitGroup
+	var value, readers int64
+	for i := 0; i < routines; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iters; j++ {
+				m.Lock()
+				if atomic.LoadInt64(&readers) != 0 {
+					panic("a reader holds the mutex locked by a writer")
+				}
+				value++
+				m.Unlock()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iters; j++ {
+				m.RLock()
+				atomic.AddInt64(&readers, 1)
+				_ = value
+				atomic.AddInt64(&readers, -1)
+				m.RUnlock()
+			}
+		}()
+	}
+	wg.Wait()
+	a.Equal(int64(routines*iters), value)
+}
+
+func TestRWMutexReaderCount(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyRWMutex(testLockerName)) {
+		return
+	}
+	m, err := NewRWMutex(testLockerName, os.O_CREATE|os.O_EXCL, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	defer m.Destroy()
+	// the counter is shared, so use another instance to check it.
+	m2, err := NewRWMutex(testLockerName, 0, 0666)
+	if !a.NoError(err) {
+		return
+	}
+	checkState := func(readers int, writer bool) {
+		count, err := m2.ReaderCount()
+		a.NoError(err)
+		a.Equal(readers, count)
+		locked, err := m2.WriterLocked()
+		a.NoError(err)
+		a.Equal(writer, locked)
+	}
+	checkState(0, false)
+	m.RLock()
+	m.RLock()
+	checkState(2, false)
+	m.RUnlock()
+	checkState(1, false)
+	m.RUnlock()
+	checkState(0, false)
+	m.Lock()
+	checkState(0, true)
+	m.Unlock()
+	checkState(0, false)
+	a.NoError(m2.Close())
+	_, err = m2.ReaderCount()
+	a.Error(err)
+	_, err = m2.WriterLocked()
+	a.Error(err)
+}
+
 func ExampleRWMutex() {
 	const (
 		writers = 4
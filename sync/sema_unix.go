@@ -10,6 +10,7 @@ import (
 
 	"github.com/nxgtw/go-ipc/internal/common"
 	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
 )
 
 const (
@@ -81,6 +82,20 @@ func (s *semaphore) wait() {
 	}
 }
 
+func (s *semaphore) tryWait() bool {
+	b := sembuf{semnum: 0, semop: int16(-1), semflg: common.IpcNoWait}
+	err := common.UninterruptedSyscall(func() error {
+		return semop(s.id, []sembuf{b})
+	})
+	if err == nil {
+		return true
+	}
+	if common.SyscallErrHasCode(err, unix.EAGAIN) {
+		return false
+	}
+	panic(err)
+}
+
 func (s *semaphore) waitTimeout(timeout time.Duration) bool {
 	if timeout < 0 {
 		s.wait()
@@ -45,6 +45,10 @@ func (s *semaphore) wait() {
 	s.waitTimeout(-1)
 }
 
+func (s *semaphore) tryWait() bool {
+	return s.waitTimeout(0)
+}
+
 func (s *semaphore) waitTimeout(timeout time.Duration) bool {
 	waitMillis := uint32(windows.INFINITE)
 	if timeout >= 0 {
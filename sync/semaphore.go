@@ -45,6 +45,12 @@ func (s *Semaphore) Wait() {
 	(*semaphore)(s).wait()
 }
 
+// TryWait decrements the value of semaphore variable by 1, if it is positive.
+// It never blocks and returns true, if the value was decremented.
+func (s *Semaphore) TryWait() bool {
+	return (*semaphore)(s).tryWait()
+}
+
 // Close closes the semaphore.
 func (s *Semaphore) Close() error {
 	return (*semaphore)(s).close()
@@ -203,6 +203,60 @@ func TestTimedSema(t *testing.T) {
 	a.False(s.WaitTimeout(time.Millisecond * 50))
 }
 
+func TestSemaTryWait(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroySemaphore(testSemaName)) {
+		return
+	}
+	s, err := NewSemaphore(testSemaName, os.O_CREATE|os.O_EXCL, 0666, 2)
+	if !a.NoError(err) {
+		return
+	}
+	defer func(s *Semaphore) {
+		a.NoError(s.Close())
+		a.NoError(DestroySemaphore(testSemaName))
+	}(s)
+	a.True(s.TryWait())
+	a.True(s.TryWait())
+	a.False(s.TryWait())
+	s.Signal(1)
+	a.True(s.TryWait())
+}
+
+func TestSemaWaitBlocks(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroySemaphore(testSemaName)) {
+		return
+	}
+	s, err := NewSemaphore(testSemaName, os.O_CREATE|os.O_EXCL, 0666, 2)
+	if !a.NoError(err) {
+		return
+	}
+	defer func(s *Semaphore) {
+		a.NoError(s.Close())
+		a.NoError(DestroySemaphore(testSemaName))
+	}(s)
+	s.Wait()
+	s.Wait()
+	ch := make(chan struct{})
+	go func() {
+		s.Wait()
+		close(ch)
+	}()
+	select {
+	case <-ch:
+		t.Errorf("the third wait has not blocked")
+		return
+	case <-time.After(time.Millisecond * 100):
+	}
+	s.Signal(1)
+	select {
+	case <-ch:
+	case <-time.After(time.Second * 3):
+		t.Errorf("timeout")
+	}
+}
+
 func TestSemaSignalAnotherProcess(t *testing.T) {
 	a := assert.New(t)
 	if !a.NoError(DestroySemaphore(testSemaName)) {
@@ -0,0 +1,91 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"os"
+	"sync/atomic"
+
+	"github.com/nxgtw/go-ipc/internal/allocator"
+	"github.com/nxgtw/go-ipc/internal/helper"
+	"bitbucket.org/avd/go-ipc/mmf"
+	"bitbucket.org/avd/go-ipc/shm"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	sequenceStateSize = 8
+)
+
+// Sequence is a generator of unique increasing numbers, which can be shared between processes.
+// Its state is a single 64-bit word in shared memory, which is modified atomically.
+// Values wrap around to 0 after reaching the maximum value of uint64 without any notice.
+// The state exists as long as the underlying shared memory object exists,
+// so on unix it persists after all the processes have closed the sequence,
+// until DestroySequence is called or the system is rebooted.
+// On windows the state is destroyed when the last instance of the sequence is closed.
+type Sequence struct {
+	name   string
+	region *mmf.MemoryRegion
+	value  *uint64
+}
+
+// NewSequence creates a new sequence, or opens an existing one.
+//	name - object name.
+//	flag - flag is a combination of open flags from 'os' package.
+//	perm - object's permission bits.
+//	start - the first value of the sequence. it is used only if the sequence was created.
+func NewSequence(name string, flag int, perm os.FileMode, start uint64) (*Sequence, error) {
+	if err := ensureOpenFlags(flag); err != nil {
+		return nil, err
+	}
+	region, created, err := helper.CreateWritableRegion(sequenceName(name), flag, perm, sequenceStateSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create shared state")
+	}
+	result := &Sequence{
+		name:   name,
+		region: region,
+		value:  (*uint64)(allocator.ByteSliceData(region.Data())),
+	}
+	if created {
+		atomic.StoreUint64(result.value, start)
+	}
+	return result, nil
+}
+
+// Next returns the next value of the sequence.
+func (s *Sequence) Next() uint64 {
+	return s.NextN(1)
+}
+
+// NextN reserves n consecutive values and returns the first of them.
+func (s *Sequence) NextN(n uint64) uint64 {
+	return atomic.AddUint64(s.value, n) - n
+}
+
+// Close closes the sequence.
+func (s *Sequence) Close() error {
+	return s.region.Close()
+}
+
+// Destroy closes the sequence and removes it permanently.
+func (s *Sequence) Destroy() error {
+	if err := s.Close(); err != nil {
+		return errors.Wrap(err, "failed to close shm region")
+	}
+	return DestroySequence(s.name)
+}
+
+// DestroySequence permanently removes a sequence with the given name.
+func DestroySequence(name string) error {
+	if err := shm.DestroyMemoryObject(sequenceName(name)); err != nil {
+		return errors.Wrap(err, "failed to destroy memory object")
+	}
+	return nil
+}
+
+func sequenceName(baseName string) string {
+	return baseName + ".seq"
+}
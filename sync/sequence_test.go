@@ -0,0 +1,111 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nxgtw/go-ipc/internal/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testSequenceName = "go-ipc.test-seq"
+)
+
+func TestSequence(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroySequence(testSequenceName)) {
+		return
+	}
+	s, err := NewSequence(testSequenceName, os.O_CREATE|os.O_EXCL, 0666, 10)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(s.Destroy())
+	}()
+	a.Equal(uint64(10), s.Next())
+	a.Equal(uint64(11), s.NextN(5))
+	a.Equal(uint64(16), s.Next())
+	s2, err := NewSequence(testSequenceName, 0, 0666, 100)
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(uint64(17), s2.Next())
+	a.NoError(s2.Close())
+	_, err = NewSequence(testSequenceName, os.O_CREATE|os.O_EXCL, 0666, 0)
+	a.Error(err)
+}
+
+func TestSequenceWraparound(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroySequence(testSequenceName)) {
+		return
+	}
+	s, err := NewSequence(testSequenceName, os.O_CREATE|os.O_EXCL, 0666, ^uint64(0))
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(s.Destroy())
+	}()
+	a.Equal(^uint64(0), s.Next())
+	a.Equal(uint64(0), s.Next())
+}
+
+func TestSequenceAnotherProcess(t *testing.T) {
+	const count = 10000
+	a := assert.New(t)
+	if !a.NoError(DestroySequence(testSequenceName)) {
+		return
+	}
+	s, err := NewSequence(testSequenceName, os.O_CREATE|os.O_EXCL, 0666, 0)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(s.Destroy())
+	}()
+	resultChan := testutil.RunTestAppAsync(argsForSequenceNextCommand(testSequenceName, count), nil)
+	seen := make(map[uint64]struct{}, 2*count)
+	var last uint64
+	for i := 0; i < count; i++ {
+		value := s.Next()
+		if i > 0 && !a.True(value > last) {
+			return
+		}
+		last = value
+		seen[value] = struct{}{}
+	}
+	result := <-resultChan
+	if !a.NoError(result.Err) {
+		t.Logf("program output is %q", result.Output)
+		return
+	}
+	lines := strings.Fields(result.Output)
+	if !a.Len(lines, count) {
+		return
+	}
+	last = 0
+	for i, line := range lines {
+		value, err := strconv.ParseUint(line, 10, 64)
+		if !a.NoError(err) {
+			return
+		}
+		if i > 0 && !a.True(value > last) {
+			return
+		}
+		last = value
+		if _, ok := seen[value]; !a.False(ok, "value %d was drawn twice", value) {
+			return
+		}
+		seen[value] = struct{}{}
+	}
+	a.Len(seen, 2*count)
+	a.Equal(uint64(2*count), s.Next())
+}
@@ -22,6 +22,10 @@ const (
 	condProgPath   = "./internal/test/cond/"
 	eventProgPath  = "./internal/test/event/"
 	semaProgPath   = "./internal/test/sema/"
+	seqProgPath    = "./internal/test/sequence/"
+	hbProgPath     = "./internal/test/heartbeat/"
+	brProgPath     = "./internal/test/barrier/"
+	onceProgPath   = "./internal/test/once/"
 	testMemObj     = "go-ipc.sync-test.region"
 )
 
@@ -30,6 +34,10 @@ var (
 	condProgArgs     []string
 	eventProgArgs    []string
 	semaProgArgs     []string
+	seqProgArgs      []string
+	hbProgArgs       []string
+	brProgArgs       []string
+	onceProgArgs     []string
 	defaultMutexType = "m"
 )
 
@@ -70,6 +78,10 @@ func init() {
 	condProgArgs = locate(condProgPath)
 	eventProgArgs = locate(eventProgPath)
 	semaProgArgs = locate(semaProgPath)
+	seqProgArgs = locate(seqProgPath)
+	hbProgArgs = locate(hbProgPath)
+	brProgArgs = locate(brProgPath)
+	onceProgArgs = locate(onceProgPath)
 }
 
 func createMemoryRegionSimple(objMode, regionMode int, size int64, offset int64) (*mmf.MemoryRegion, error) {
@@ -124,6 +136,17 @@ func argsForSyncTestCommand(name, t string, jobs int, shmName string, n int, dat
 	)
 }
 
+func argsForSyncRRLockCommand(name string, jobs, n, depth int) []string {
+	return append(lockerProgArgs,
+		"-object="+name,
+		"-type=rw",
+		"-jobs="+strconv.Itoa(jobs),
+		"rrlock",
+		strconv.Itoa(n),
+		strconv.Itoa(depth),
+	)
+}
+
 // Cond test program
 
 func argsForCondSignalCommand(name string) []string {
@@ -150,6 +173,16 @@ func argsForCondWaitCommand(condName, lockerName, waitEvent string) []string {
 	)
 }
 
+func argsForCondProduceCommand(condName, lockerName, shmName string, value int64) []string {
+	return append(condProgArgs,
+		"produce",
+		condName,
+		lockerName,
+		shmName,
+		strconv.FormatInt(value, 10),
+	)
+}
+
 // Event test program
 
 func argsForEventSetCommand(name string) []string {
@@ -185,6 +218,48 @@ func argsForSemaWaitCommand(name string, timeoutMS int) []string {
 	)
 }
 
+// Sequence test program
+
+func argsForSequenceNextCommand(name string, count int) []string {
+	return append(seqProgArgs,
+		"next",
+		name,
+		strconv.Itoa(count),
+	)
+}
+
+// Heartbeat test program
+
+func argsForHeartbeatBeatCommand(name string, intervalMS, durationMS int) []string {
+	return append(hbProgArgs,
+		"-interval="+strconv.Itoa(intervalMS),
+		"beat",
+		name,
+		strconv.Itoa(durationMS),
+	)
+}
+
+// Barrier test program
+
+func argsForBarrierWaitCommand(name string, parties, n int) []string {
+	return append(brProgArgs,
+		"wait",
+		name,
+		strconv.Itoa(parties),
+		strconv.Itoa(n),
+	)
+}
+
+// Once test program
+
+func argsForOnceDoCommand(name, shmName string) []string {
+	return append(onceProgArgs,
+		"do",
+		name,
+		shmName,
+	)
+}
+
 func startPprof() {
 	go func() {
 		fmt.Println(http.ListenAndServe("localhost:6060", nil))
@@ -66,6 +66,7 @@ func sys_CreateEvent(name string, eventAttrs *windows.SecurityAttributes, manual
 }
 
 func openOrCreateEvent(name string, flag int, initial int) (windows.Handle, error) {
+	name = common.PrefixedName(name)
 	var handle windows.Handle
 	creator := func(create bool) error {
 		var err error
@@ -144,6 +145,7 @@ func sys_OpenSemaphore(name string, desiredAccess uint32, inheritHandle uint32)
 }
 
 func openOrCreateSemaphore(name string, flag int, initial, maximum int) (windows.Handle, error) {
+	name = common.PrefixedName(name)
 	var handle windows.Handle
 	creator := func(create bool) error {
 		var err error
@@ -10,6 +10,10 @@ func gettid() (uint32, error) {
 	return mach_thread_self(), nil
 }
 
+func currentThreadID() uint64 {
+	return uint64(mach_thread_self())
+}
+
 func killThread(port uint32) error {
 	_, _, err := unix.Syscall(unix.SYS___PTHREAD_KILL, uintptr(port), uintptr(unix.SIGUSR2), 0)
 	return err
@@ -22,6 +22,14 @@ func gettid() (int, error) {
 	return int(tid), nil
 }
 
+func currentThreadID() uint64 {
+	tid, err := gettid()
+	if err != nil {
+		panic(err)
+	}
+	return uint64(tid)
+}
+
 func killThread(tid int) error {
 	_, _, err := unix.Syscall(unix.SYS_THR_KILL, uintptr(tid), uintptr(unix.SIGUSR2), 0)
 	return err
@@ -0,0 +1,9 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import "golang.org/x/sys/windows"
+
+func currentThreadID() uint64 {
+	return uint64(windows.GetCurrentThreadId())
+}
@@ -0,0 +1,80 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"time"
+
+	"github.com/nxgtw/go-ipc/internal/common"
+
+	"github.com/pkg/errors"
+)
+
+// Waitable is an object, which can be waited for until it becomes ready.
+// Waiting for a ready object may change its state, for example, a semaphore is decremented,
+// and an event is reset. It is implemented by Event and Semaphore.
+type Waitable interface {
+	// WaitTimeout waits until the object becomes ready, or the timeout elapses.
+	// Returns true, if the object became ready.
+	WaitTimeout(timeout time.Duration) bool
+}
+
+// FdWaitable is a Waitable, which has a file descriptor, which becomes readable, when the object is ready.
+// On Linux WaitAny sleeps on such descriptors with epoll instead of polling the objects.
+type FdWaitable interface {
+	Waitable
+	// Fd returns the file descriptor of the object.
+	Fd() uintptr
+}
+
+var (
+	_ Waitable = (*Event)(nil)
+	_ Waitable = (*Semaphore)(nil)
+)
+
+// WaitAny waits until any of the objects becomes ready, waiting for not longer, than timeout.
+// Passing negative value as a timeout makes the timeout infinite.
+// It returns the index of the object, which became ready. Only this object's state is changed.
+// If several objects are ready, the one with the lowest index is chosen.
+// Objects, which are not FdWaitable, are polled with an increasing interval,
+// so they are not suitable for low-latency notifications.
+// If no object has become ready in time, it returns -1 and a timeout error.
+func WaitAny(objs []Waitable, timeout time.Duration) (int, error) {
+	const maxPollInterval = 50 * time.Millisecond
+	if len(objs) == 0 {
+		return -1, errors.New("no objects to wait for")
+	}
+	var fds []int
+	for _, obj := range objs {
+		if fdObj, ok := obj.(FdWaitable); ok {
+			fds = append(fds, int(fdObj.Fd()))
+		}
+	}
+	w, err := newFdWaiter(fds)
+	if err != nil {
+		return -1, errors.Wrap(err, "failed to wait for file descriptors")
+	}
+	defer w.close()
+	maxInterval := maxPollInterval
+	if len(fds) == len(objs) {
+		// all the objects wake us up, so there is no need to poll them often.
+		maxInterval = time.Second
+	}
+	result := -1
+	ok, err := common.PollTimeoutWait(func() (bool, error) {
+		for i, obj := range objs {
+			if obj.WaitTimeout(0) {
+				result = i
+				return true, nil
+			}
+		}
+		return false, nil
+	}, timeout, maxInterval, w.wait)
+	if err != nil {
+		return -1, err
+	}
+	if !ok {
+		return -1, common.NewTimeoutError("WAITANY")
+	}
+	return result, nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"time"
+
+	"github.com/nxgtw/go-ipc/internal/common"
+)
+
+// fdWaiter sleeps until any of the file descriptors becomes readable.
+type fdWaiter struct {
+	epoll *common.Epoll
+}
+
+func newFdWaiter(fds []int) (*fdWaiter, error) {
+	if len(fds) == 0 {
+		return &fdWaiter{}, nil
+	}
+	epoll, err := common.NewEpoll(fds)
+	if err != nil {
+		return nil, err
+	}
+	return &fdWaiter{epoll: epoll}, nil
+}
+
+func (w *fdWaiter) wait(interval time.Duration) bool {
+	if w.epoll == nil {
+		time.Sleep(interval)
+		return true
+	}
+	if _, err := w.epoll.WaitReadable(interval); err != nil {
+		// fall back to polling.
+		time.Sleep(interval)
+	}
+	return true
+}
+
+func (w *fdWaiter) close() {
+	if w.epoll != nil {
+		w.epoll.Close()
+	}
+}
@@ -0,0 +1,23 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+// +build !linux
+
+package sync
+
+import (
+	"time"
+)
+
+// fdWaiter sleeps for the given interval, as there is no epoll on this platform.
+type fdWaiter struct{}
+
+func newFdWaiter(fds []int) (*fdWaiter, error) {
+	return &fdWaiter{}, nil
+}
+
+func (w *fdWaiter) wait(interval time.Duration) bool {
+	time.Sleep(interval)
+	return true
+}
+
+func (w *fdWaiter) close() {}
@@ -0,0 +1,56 @@
+// Copyright 2016 Aleksandr Demakin. All rights reserved.
+
+package sync
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitAny(t *testing.T) {
+	a := assert.New(t)
+	if !a.NoError(DestroyEvent(testEventName)) {
+		return
+	}
+	ev, err := NewEvent(testEventName, os.O_CREATE|os.O_EXCL, 0666, false)
+	if !a.NoError(err) {
+		return
+	}
+	defer ev.Destroy()
+	if !a.NoError(DestroySemaphore(testSemaName)) {
+		return
+	}
+	s, err := NewSemaphore(testSemaName, os.O_CREATE|os.O_EXCL, 0666, 0)
+	if !a.NoError(err) {
+		return
+	}
+	defer func() {
+		a.NoError(s.Close())
+		a.NoError(DestroySemaphore(testSemaName))
+	}()
+	objs := []Waitable{ev, s}
+	_, err = WaitAny(nil, 0)
+	a.Error(err)
+	idx, err := WaitAny(objs, time.Millisecond*50)
+	a.Error(err)
+	a.Equal(-1, idx)
+	go func() {
+		<-time.After(time.Millisecond * 50)
+		s.Signal(1)
+	}()
+	idx, err = WaitAny(objs, time.Second*2)
+	a.NoError(err)
+	a.Equal(1, idx)
+	// the semaphore has been decremented.
+	a.False(s.WaitTimeout(0))
+	ev.Set()
+	s.Signal(1)
+	idx, err = WaitAny(objs, 0)
+	a.NoError(err)
+	a.Equal(0, idx)
+	// only the first ready object is waited for.
+	a.True(s.WaitTimeout(0))
+}